@@ -72,6 +72,70 @@ func ini_parser_set_input_file(parser *ini_parser_t, file *os.File) {
 	parser.input_file = file
 }
 
+// Set the source encoding.
+func ini_parser_set_encoding(parser *ini_parser_t, encoding ini_encoding_t) {
+	if parser.encoding != ini_ANY_ENCODING {
+		panic("must set the encoding only once")
+	}
+	parser.encoding = encoding
+}
+
+// Set if the scanner should skip to the next line break after an error.
+func ini_parser_set_recover(parser *ini_parser_t, recover bool) {
+	parser.recover = recover
+}
+
+// Set the characters that delimit a key from its value.  A space or a tab
+// makes any run of blanks a delimiter.
+func ini_parser_set_delimiters(parser *ini_parser_t, delimiters []byte) {
+	parser.delimiters = nil
+	parser.blank_delimiter = false
+	for _, c := range delimiters {
+		if c == ' ' || c == '\t' {
+			parser.blank_delimiter = true
+		} else {
+			parser.delimiters = append(parser.delimiters, c)
+		}
+	}
+}
+
+// Set the characters that start a comment, and if and where a comment may
+// follow a key or a value on the same line.
+func ini_parser_set_comments(parser *ini_parser_t, prefixes []byte, inline, need_blank bool) {
+	parser.comments = prefixes
+	parser.no_inline_comments = !inline
+	parser.inline_comment_blank = need_blank
+}
+
+// Set if the comments are collected into the comment list of the parser,
+// as the scanner drops them otherwise.
+func ini_parser_set_keep_comments(parser *ini_parser_t, keep bool) {
+	parser.keep_comments = keep
+}
+
+// Set the characters allowed in plain section keys on top of alphabetical
+// characters, digits, '_', '-' and non-ASCII characters.
+func ini_parser_set_section_chars(parser *ini_parser_t, chars []byte) {
+	parser.section_chars = chars
+}
+
+// Set if dotted keys are kept as plain keys instead of being split into
+// MAP tokens.
+func ini_parser_set_flat_keys(parser *ini_parser_t, flat_keys bool) {
+	parser.flat_keys = flat_keys
+}
+
+// Set if a key without a delimiter and a value is read as a key with the
+// value true.
+func ini_parser_set_boolean_keys(parser *ini_parser_t, boolean_keys bool) {
+	parser.boolean_keys = boolean_keys
+}
+
+// Set the syntax of the input.
+func ini_parser_set_dialect(parser *ini_parser_t, dialect ini_dialect_t) {
+	parser.dialect = dialect
+}
+
 // Create a new emitter object.
 func ini_emitter_initialize(emitter *ini_emitter_t) bool {
 	*emitter = ini_emitter_t{
@@ -118,6 +182,14 @@ func ini_emitter_set_output_file(emitter *ini_emitter_t, file io.Writer) {
 	emitter.output_file = file
 }
 
+// Set the output encoding.
+func ini_emitter_set_encoding(emitter *ini_emitter_t, encoding ini_encoding_t) {
+	if emitter.encoding != ini_ANY_ENCODING {
+		panic("must set the output encoding only once")
+	}
+	emitter.encoding = encoding
+}
+
 // Set if unescaped non-ASCII characters are allowed.
 func ini_emitter_set_unicode(emitter *ini_emitter_t, unicode bool) {
 	emitter.unicode = unicode
@@ -128,6 +200,28 @@ func ini_emitter_set_break(emitter *ini_emitter_t, line_break ini_break_t) {
 	emitter.line_break = line_break
 }
 
+// Set the syntax of the output.
+func ini_emitter_set_dialect(emitter *ini_emitter_t, dialect ini_dialect_t) {
+	emitter.dialect = dialect
+}
+
+// Set the character that starts comments.
+func ini_emitter_set_comment_indicator(emitter *ini_emitter_t, indicator byte) {
+	emitter.indicator = indicator
+}
+
+// Set the character that delimits keys from their values.  A blank writes
+// the value after a space.
+func ini_emitter_set_delimiter(emitter *ini_emitter_t, delimiter byte) {
+	emitter.delimiter = delimiter
+}
+
+// Set if values are written as they are, between the quotes of their
+// style, as the text of the values of a document that is read again.
+func ini_emitter_set_verbatim(emitter *ini_emitter_t, verbatim bool) {
+	emitter.verbatim = verbatim
+}
+
 // Create DOCUMENT-START.
 func ini_document_start_event_initialize(event *ini_event_t) bool {
 	*event = ini_event_t{
@@ -154,6 +248,51 @@ func ini_scalar_event_initialize(event *ini_event_t, value []byte, style ini_sca
 	return true
 }
 
+// Create SECTION-INHERIT.
+func ini_section_inherit_event_initialize(event *ini_event_t, value []byte) bool {
+	*event = ini_event_t{
+		typ:   ini_SECTION_INHERIT_EVENT,
+		value: value,
+		tag:   []byte(ini_STR_TAG),
+	}
+	return true
+}
+
+// Create SECTION-ENTRY.
+func ini_section_entry_event_initialize(event *ini_event_t) bool {
+	*event = ini_event_t{
+		typ: ini_SECTION_ENTRY_EVENT,
+		tag: []byte(ini_SECTION_TAG),
+	}
+	return true
+}
+
+// Create COMMENT.
+func ini_comment_event_initialize(event *ini_event_t, value []byte) bool {
+	*event = ini_event_t{
+		typ:   ini_COMMENT_EVENT,
+		value: value,
+	}
+	return true
+}
+
+// Create MARKER.
+func ini_marker_event_initialize(event *ini_event_t, value []byte) bool {
+	*event = ini_event_t{
+		typ:   ini_MARKER_EVENT,
+		value: value,
+	}
+	return true
+}
+
+// Create MAPPING.
+func ini_mapping_event_initialize(event *ini_event_t) bool {
+	*event = ini_event_t{
+		typ: ini_MAPPING_EVENT,
+	}
+	return true
+}
+
 // Destroy an event object.
 func ini_event_delete(event *ini_event_t) {
 	*event = ini_event_t{}
@@ -0,0 +1,346 @@
+// Command ini reads and edits the keys of INI files, for shell scripts:
+//
+//	ini [-dialect name] get [-all] file section.key
+//	ini [-dialect name] set file section.key value
+//	ini [-dialect name] del file section.key
+//	ini [-dialect name] fmt [-w] [-sort] file...
+//	ini [-dialect name] lint file...
+//	ini [-dialect name] diff [-raw] old new
+//	ini [-dialect name] merge [-w] [-markers] base ours theirs
+//	ini [-dialect name] query file pattern
+//	ini [-dialect name] explain file section.key
+//	ini [-dialect name] flatten [-factorize] [-parent name] file
+//
+// get prints the value of a key, or every value of a key that appears
+// several times with -all, one per line.  set and del edit the file in
+// place, and keep its comments and its layout.  Keys are named as with
+// ini.Get, and sections hold the keys they inherit, as with ini.Unmarshal.
+//
+// fmt prints files in the layout of ini.Format, with their keys sorted
+// with -sort, or rewrites them in place with -w.  lint prints the problems
+// that ini.Lint finds in files, one per line after the name of the file.
+// diff prints the keys whose values ini.Diff finds added, removed or
+// changed, compared as text with -raw.  merge prints the document that
+// ini.Merge3 merges, or rewrites ours with it with -w, and prints the
+// conflicts, which keep our side, or both sides between markers with
+// -markers.  query prints the keys that match a pattern of ini.Query, such
+// as "*.timeout", with their values and the lines and columns of the values.
+// explain prints where ini.Explain finds the value of a key set, and the
+// values that it overrides.  flatten prints the file with the sections that
+// it inherits expanded by ini.Flatten, or with the keys that its sections
+// share moved into a parent section by ini.Factorize with -factorize.
+//
+// The exit status is 0 on success, 1 when the key is not found, lint finds
+// a problem, diff finds a change, merge finds a conflict or query finds no
+// key, and 2 on any other error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-ini"
+)
+
+var dialects = map[string]ini.Dialect{
+	"default":    ini.DialectDefault,
+	"git":        ini.DialectGit,
+	"systemd":    ini.DialectSystemd,
+	"php":        ini.DialectPHP,
+	"mysql":      ini.DialectMySQL,
+	"windows":    ini.DialectWindows,
+	"properties": ini.DialectProperties,
+	"dotenv":     ini.DialectDotenv,
+	"desktop":    ini.DialectDesktop,
+	"python":     ini.DialectPython,
+}
+
+// program runs a command, with the streams that it prints to.
+type program struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// exit is the exit status of a command, which it panics with to stop.
+type exit int
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments, and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) (status int) {
+	defer func() {
+		if v := recover(); v != nil {
+			e, ok := v.(exit)
+			if !ok {
+				panic(v)
+			}
+			status = int(e)
+		}
+	}()
+	c := &program{stdout: stdout, stderr: stderr}
+	c.run(args)
+	return 0
+}
+
+func (c *program) usage() {
+	fmt.Fprintf(c.stderr, "usage: ini [-dialect name] get [-all] file section.key\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] set file section.key value\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] del file section.key\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] fmt [-w] [-sort] file...\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] lint file...\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] diff [-raw] old new\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] merge [-w] [-markers] base ours theirs\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] query file pattern\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] explain file section.key\n")
+	fmt.Fprintf(c.stderr, "       ini [-dialect name] flatten [-factorize] [-parent name] file\n")
+	panic(exit(2))
+}
+
+// flags returns the flags of a command, which print its usage on errors.
+func (c *program) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = c.usage
+	return flags
+}
+
+func (c *program) run(args []string) {
+	var names []string
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	global := c.flags("ini")
+	dialect := global.String("dialect", "default", "the syntax of the file: "+strings.Join(names, ", "))
+	global.Parse(args)
+	d, ok := dialects[*dialect]
+	if !ok {
+		fmt.Fprintf(c.stderr, "ini: unknown dialect %q\n", *dialect)
+		panic(exit(2))
+	}
+	if global.NArg() < 1 {
+		c.usage()
+	}
+	opts := ini.LoadOptions{Dialect: d}
+
+	command, args := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "get":
+		flags := c.flags("get")
+		all := flags.Bool("all", false, "print every value of the key")
+		flags.Parse(args)
+		if flags.NArg() != 2 {
+			c.usage()
+		}
+		in := c.read(flags.Arg(0), &opts)
+		values, err := ini.GetAll(in, flags.Arg(1), opts)
+		c.check(err)
+		if !*all {
+			values = values[len(values)-1:]
+		}
+		for _, value := range values {
+			fmt.Fprintln(c.stdout, value)
+		}
+	case "set":
+		if len(args) != 3 {
+			c.usage()
+		}
+		in := c.read(args[0], &opts)
+		out, err := ini.Set(in, args[1], args[2], opts)
+		c.check(err)
+		c.write(args[0], out)
+	case "del":
+		if len(args) != 2 {
+			c.usage()
+		}
+		in := c.read(args[0], &opts)
+		out, err := ini.Delete(in, args[1], opts)
+		c.check(err)
+		c.write(args[0], out)
+	case "fmt":
+		flags := c.flags("fmt")
+		inPlace := flags.Bool("w", false, "rewrite the files instead of printing them")
+		sortKeys := flags.Bool("sort", false, "sort the keys of every section")
+		flags.Parse(args)
+		if flags.NArg() < 1 {
+			c.usage()
+		}
+		for _, filename := range flags.Args() {
+			opts := ini.FormatOptions{Load: opts, SortKeys: *sortKeys}
+			in := c.read(filename, &opts.Load)
+			out, err := ini.Format(in, opts)
+			c.check(err)
+			if *inPlace {
+				c.write(filename, out)
+			} else {
+				c.stdout.Write(out)
+			}
+		}
+	case "lint":
+		if len(args) < 1 {
+			c.usage()
+		}
+		found := false
+		for _, filename := range args {
+			in := c.read(filename, &opts)
+			problems, err := ini.Lint(in, opts)
+			c.check(err)
+			for _, problem := range problems {
+				fmt.Fprintf(c.stdout, "%s: %s\n", filename, problem)
+				found = true
+			}
+		}
+		if found {
+			panic(exit(1))
+		}
+	case "diff":
+		flags := c.flags("diff")
+		raw := flags.Bool("raw", false, "compare values as text")
+		flags.Parse(args)
+		if flags.NArg() != 2 {
+			c.usage()
+		}
+		opts.RawValues = *raw
+		oldOpts, newOpts := opts, opts
+		a := c.read(flags.Arg(0), &oldOpts)
+		b := c.read(flags.Arg(1), &newOpts)
+		changes, err := ini.Diff(a, b, ini.DiffOptions{Load: opts, OldPath: oldOpts.Path, NewPath: newOpts.Path})
+		c.check(err)
+		for _, change := range changes {
+			fmt.Fprintln(c.stdout, change)
+		}
+		if len(changes) > 0 {
+			panic(exit(1))
+		}
+	case "merge":
+		flags := c.flags("merge")
+		inPlace := flags.Bool("w", false, "rewrite ours instead of printing the merged file")
+		markers := flags.Bool("markers", false, "write both sides of conflicts between markers")
+		flags.Parse(args)
+		if flags.NArg() != 3 {
+			c.usage()
+		}
+		baseOpts, oursOpts, theirsOpts := opts, opts, opts
+		base := c.read(flags.Arg(0), &baseOpts)
+		ours := c.read(flags.Arg(1), &oursOpts)
+		theirs := c.read(flags.Arg(2), &theirsOpts)
+		out, conflicts, err := ini.Merge3(base, ours, theirs, ini.MergeOptions{
+			Load:     opts,
+			Markers:  *markers,
+			BasePath: baseOpts.Path, OursPath: oursOpts.Path, TheirsPath: theirsOpts.Path,
+		})
+		c.check(err)
+		if *inPlace {
+			c.write(flags.Arg(1), out)
+		} else {
+			c.stdout.Write(out)
+		}
+		for _, conflict := range conflicts {
+			fmt.Fprintf(c.stderr, "conflict: %s\n", conflict)
+		}
+		if len(conflicts) > 0 {
+			panic(exit(1))
+		}
+	case "query":
+		if len(args) != 2 {
+			c.usage()
+		}
+		in := c.read(args[0], &opts)
+		matches, err := ini.Query(in, args[1], opts)
+		c.check(err)
+		for _, match := range matches {
+			value := match.Value
+			if value == nil {
+				value = ""
+			}
+			fmt.Fprintf(c.stdout, "%s:%d:%d: %s = %v\n", args[0], match.Line, match.Column, match.Path, value)
+		}
+		if len(matches) == 0 {
+			panic(exit(1))
+		}
+	case "explain":
+		if len(args) != 2 {
+			c.usage()
+		}
+		in := c.read(args[0], &opts)
+		ex, err := ini.Explain(in, args[1], opts)
+		c.check(err)
+		for _, origin := range ex.Values {
+			fmt.Fprintf(c.stdout, "%s = %s\n", ex.Path, origin)
+		}
+		for _, origin := range ex.Overridden {
+			fmt.Fprintf(c.stdout, "  overrides %s\n", origin)
+		}
+	case "flatten":
+		flags := c.flags("flatten")
+		factorize := flags.Bool("factorize", false, "move the keys that sections share into a parent section")
+		parent := flags.String("parent", "common", "the name of the parent section of -factorize")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			c.usage()
+		}
+		in := c.read(flags.Arg(0), &opts)
+		convert := ini.ConvertOptions{Load: opts, Dump: ini.DumpOptions{Dialect: opts.Dialect}, Parent: *parent}
+		var out []byte
+		var err error
+		if *factorize {
+			out, err = ini.FactorizeWithOptions(in, convert)
+		} else {
+			out, err = ini.FlattenWithOptions(in, convert)
+		}
+		c.check(err)
+		c.stdout.Write(out)
+	default:
+		c.usage()
+	}
+}
+
+// read reads a file, which becomes the Path of opts.
+func (c *program) read(filename string, opts *ini.LoadOptions) []byte {
+	in, err := ioutil.ReadFile(filename)
+	c.check(err)
+	opts.Path = filename
+	return in
+}
+
+// write replaces a file, with a file of the same mode renamed over it.
+func (c *program) write(filename string, out []byte) {
+	info, err := os.Stat(filename)
+	c.check(err)
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	c.check(err)
+	_, err = f.Write(out)
+	if err == nil {
+		err = f.Chmod(info.Mode())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		c.check(err)
+	}
+}
+
+// check exits with the status of an error.
+func (c *program) check(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(c.stderr, err)
+	if _, ok := err.(*ini.NotFoundError); ok {
+		panic(exit(1))
+	}
+	panic(exit(2))
+}
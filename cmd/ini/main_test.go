@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type S struct{}
+
+var _ = Suite(&S{})
+
+var runTests = []struct {
+	files   map[string]string
+	args    []string
+	stdout  string
+	status  int
+	written map[string]string
+}{
+	{
+		files:  map[string]string{"a.ini": "[s]\nk = v\n"},
+		args:   []string{"get", "a.ini", "s.k"},
+		stdout: "v\n",
+	}, {
+		files:  map[string]string{"a.ini": "[s]\n\tk = a\n\tk = b\n"},
+		args:   []string{"-dialect", "git", "get", "-all", "a.ini", "s.k"},
+		stdout: "a\nb\n",
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nk = v\n"},
+		args:   []string{"get", "a.ini", "s.nope"},
+		status: 1,
+	}, {
+		files:   map[string]string{"a.ini": "[s]\nk = v ; the key\n"},
+		args:    []string{"set", "a.ini", "s.k", "w"},
+		written: map[string]string{"a.ini": "[s]\nk = w ; the key\n"},
+	}, {
+		files:   map[string]string{"a.ini": "[s]\nk = v\nj = 1\n"},
+		args:    []string{"del", "a.ini", "s.k"},
+		written: map[string]string{"a.ini": "[s]\nj = 1\n"},
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nk = v\n"},
+		args:   []string{"del", "a.ini", "s.nope"},
+		status: 1,
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nz=1\nk  =  v\n"},
+		args:   []string{"fmt", "-sort", "a.ini"},
+		stdout: "[s]\nk = v\nz = 1\n",
+	}, {
+		files:   map[string]string{"a.ini": "[s]\nk  =  v\n"},
+		args:    []string{"fmt", "-w", "a.ini"},
+		written: map[string]string{"a.ini": "[s]\nk = v\n"},
+	}, {
+		files: map[string]string{"a.ini": "[s]\nk = v\n"},
+		args:  []string{"lint", "a.ini"},
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nk = v\nk = w\n"},
+		args:   []string{"lint", "a.ini"},
+		stdout: "a.ini: line 3: key 'k' is already set on line 2\n",
+		status: 1,
+	}, {
+		files: map[string]string{"a.ini": "[s]\nk = v\n", "b.ini": "[s]\nk = v\n"},
+		args:  []string{"diff", "a.ini", "b.ini"},
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nk = v\n", "b.ini": "[s]\nk = w\nj = 1\n"},
+		args:   []string{"diff", "a.ini", "b.ini"},
+		stdout: "~ s.k = 'v' (line 2) -> 'w' (line 2)\n+ s.j = '1' (line 3)\n",
+		status: 1,
+	}, {
+		files: map[string]string{
+			"base.ini":   "[s]\nk = v\nj = 1\n",
+			"ours.ini":   "[s]\nk = w\nj = 1\n",
+			"theirs.ini": "[s]\nk = v\nj = 2\n",
+		},
+		args:   []string{"merge", "base.ini", "ours.ini", "theirs.ini"},
+		stdout: "[s]\nk = w\nj = 2\n",
+	}, {
+		files: map[string]string{
+			"base.ini":   "[s]\nk = v\n",
+			"ours.ini":   "[s]\nk = w\n",
+			"theirs.ini": "[s]\nk = x\n",
+		},
+		args:    []string{"merge", "-w", "base.ini", "ours.ini", "theirs.ini"},
+		status:  1,
+		written: map[string]string{"ours.ini": "[s]\nk = w\n"},
+	}, {
+		files:  map[string]string{"a.ini": "[s]\ntimeout = 5\n[t]\ntimeout = 10\n"},
+		args:   []string{"query", "a.ini", "*.timeout"},
+		stdout: "a.ini:2:11: s.timeout = 5\na.ini:4:11: t.timeout = 10\n",
+	}, {
+		files:  map[string]string{"a.ini": "[s]\nk = v\n"},
+		args:   []string{"query", "a.ini", "*.nope"},
+		status: 1,
+	}, {
+		files:  map[string]string{"a.ini": "[base]\nk = v\n[s : base]\n"},
+		args:   []string{"explain", "a.ini", "s.k"},
+		stdout: "s.k = 'v', inherited from [base] at a.ini:2\n",
+	}, {
+		files:  map[string]string{"a.ini": "[base]\nk = v\n[s : base]\nj = 1\n"},
+		args:   []string{"flatten", "a.ini"},
+		stdout: "[base]\nk = v\n\n[s]\nj = 1\nk = v\n",
+	}, {
+		args:   []string{},
+		status: 2,
+	}, {
+		args:   []string{"nope"},
+		status: 2,
+	}, {
+		args:   []string{"get", "a.ini"},
+		status: 2,
+	}, {
+		args:   []string{"get", "-nope", "a.ini", "s.k"},
+		status: 2,
+	}, {
+		args:   []string{"-dialect", "nope", "get", "a.ini", "s.k"},
+		status: 2,
+	}, {
+		args:   []string{"get", "missing.ini", "s.k"},
+		status: 2,
+	}, {
+		files:  map[string]string{"a.ini": "[s : nope]\n"},
+		args:   []string{"get", "a.ini", "s.k"},
+		status: 2,
+	},
+}
+
+func (s *S) TestRun(c *C) {
+	for _, item := range runTests {
+		dir := c.MkDir()
+		for name, data := range item.files {
+			err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+			c.Assert(err, IsNil)
+		}
+		var args []string
+		for _, arg := range item.args {
+			if strings.HasSuffix(arg, ".ini") {
+				arg = filepath.Join(dir, arg)
+			}
+			args = append(args, arg)
+		}
+		var stdout, stderr bytes.Buffer
+		status := run(args, &stdout, &stderr)
+		comment := Commentf("args: %q, stderr: %s", item.args, stderr.String())
+		c.Assert(status, Equals, item.status, comment)
+		c.Assert(strings.Replace(stdout.String(), dir+string(filepath.Separator), "", -1), Equals, item.stdout, comment)
+		for name, data := range item.written {
+			out, err := ioutil.ReadFile(filepath.Join(dir, name))
+			c.Assert(err, IsNil)
+			c.Assert(string(out), Equals, data, comment)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConvertOptions changes how documents are converted between INI and YAML
+// or TOML, and how they are flattened and factorized.  The zero value
+// converts documents of the default dialect.
+type ConvertOptions struct {
+	// Load reads the INI documents that are converted into YAML or TOML,
+	// flattened or factorized.
+	Load LoadOptions
+
+	// Dump writes the INI documents that YAML or TOML documents are
+	// converted into, and the flattened and factorized documents.
+	Dump DumpOptions
+
+	// Anchors writes a YAML section that inherits another section as the
+	// merge key "<<: *base", with an anchor on the inherited section,
+	// followed by the keys that it adds or changes.  Sections are written
+	// with all their keys otherwise.
+	Anchors bool
+
+	// Parent is the name of the section that Factorize moves the keys
+	// that sections share into, or "common".
+	Parent string
+}
+
+// A ConvertError is returned when a document holds constructs that the
+// format it is converted into cannot express.  Every construct is
+// reported, and nothing is converted.
+type ConvertError struct {
+	Errors []string
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("ini: cannot convert:\n  %s", strings.Join(e.Errors, "\n  "))
+}
+
+// An entry is a key of a converted document.  Its value is a scalar, the
+// []interface{} of a list, or the []*entry of a map.  A section holds the
+// keys that it inherits, and names the section that it inherits them from.
+type entry struct {
+	key      string
+	value    interface{}
+	line     int             // The line of the key, from 1, or 0.
+	comments []string        // The comment lines above the key.
+	comment  string          // The comment after the key, on its line.
+	base     string          // The section that a section inherits.
+	conflict *merge_conflict // The sides of a merged key, written with markers.
+}
+
+// A document is a converted document: the keys of the default section and
+// the sections, and the comments below them.
+type document struct {
+	entries  []*entry
+	comments []string
+}
+
+// problem returns a problem of an entry, with its line.
+func (e *entry) problem(format string, args ...interface{}) string {
+	if e.line == 0 {
+		return fmt.Sprintf(format, args...)
+	}
+	return fmt.Sprintf("line %d: %s", e.line, fmt.Sprintf(format, args...))
+}
+
+// entry_set adds e to entries, in the place of the entry with its key if
+// there is one.
+func entry_set(entries []*entry, e *entry) []*entry {
+	for i := range entries {
+		if entries[i].key == e.key {
+			entries[i] = e
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+// entry_find returns the entry of entries with the key, or nil.
+func entry_find(entries []*entry, key string) *entry {
+	for _, e := range entries {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// entry_copy returns a copy of a value, without the comments of its maps.
+func entry_copy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []*entry:
+		entries := make([]*entry, 0, len(value))
+		for _, e := range value {
+			entries = append(entries, &entry{key: e.key, value: entry_copy(e.value), line: e.line, base: e.base})
+		}
+		return entries
+	case []interface{}:
+		values := make([]interface{}, 0, len(value))
+		for _, v := range value {
+			values = append(values, entry_copy(v))
+		}
+		return values
+	}
+	return value
+}
+
+// entry_equal reports whether two values are the same, regardless of the
+// comments of their maps.
+func entry_equal(a, b interface{}) bool {
+	aEntries, aMap := a.([]*entry)
+	bEntries, bMap := b.([]*entry)
+	if aMap || bMap {
+		if !aMap || !bMap || len(aEntries) != len(bEntries) {
+			return false
+		}
+		for i := range aEntries {
+			if aEntries[i].key != bEntries[i].key || !entry_equal(aEntries[i].value, bEntries[i].value) {
+				return false
+			}
+		}
+		return true
+	}
+	aValues, aList := a.([]interface{})
+	bValues, bList := b.([]interface{})
+	if aList || bList {
+		if !aList || !bList || len(aValues) != len(bValues) {
+			return false
+		}
+		for i := range aValues {
+			if !entry_equal(aValues[i], bValues[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// entry_map returns the keys and values of a map as a MapSlice.
+func entry_map(entries []*entry) MapSlice {
+	slice := make(MapSlice, 0, len(entries))
+	for _, e := range entries {
+		value := e.value
+		if entries, ok := value.([]*entry); ok {
+			value = entry_map(entries)
+		}
+		slice = append(slice, MapItem{Key: e.key, Value: value})
+	}
+	return slice
+}
+
+// section returns the section with the name, or nil.
+func (doc *document) section(name string) *entry {
+	if e := entry_find(doc.entries, name); e != nil {
+		if _, ok := e.value.([]*entry); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// own returns the keys of a section that the section it inherits does not
+// hold with the same value, and whether it inherits a section.
+func (doc *document) own(section *entry) ([]*entry, bool) {
+	base := doc.section(section.base)
+	if section.base == "" || base == nil || base == section {
+		return nil, false
+	}
+	baseEntries := base.value.([]*entry)
+	var entries []*entry
+	for _, e := range section.value.([]*entry) {
+		if inherited := entry_find(baseEntries, e.key); inherited == nil || !entry_equal(inherited.value, e.value) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, true
+}
+
+// ----------------------------------------------------------------------------
+// INI documents
+
+// A converter reads the node tree of an INI document into entries.  The
+// comments go to the first entry below them, or to the entry on their line.
+type converter struct {
+	d        *decoder
+	bases    map[*node]string
+	comments []ini_comment_t
+	next     int // The first comment that no entry holds yet.
+	line     int // The line of the last entry that took comments.
+}
+
+// ini_document reads an INI document, with its comments, into a document.
+func ini_document(in []byte, opts LoadOptions) (doc *document, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	d := newDecoder(opts)
+	p := newParser(in, opts)
+	defer p.destroy()
+	ini_parser_set_keep_comments(&p.parser, true)
+	p.bases = make(map[*node]string)
+	n := p.parse()
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	c := &converter{d: d, bases: p.bases, comments: p.parser.comment_list}
+	doc = &document{}
+	if n != nil {
+		doc.entries = c.entries(n)
+	}
+	if len(d.terrors) > 0 {
+		return nil, &TypeError{d.terrors}
+	}
+	for _, comment := range c.comments[c.next:] {
+		doc.comments = append(doc.comments, string(comment.value))
+	}
+	return doc, nil
+}
+
+// entries reads the keys of a document, a section or a map of dotted keys.
+// The keys of the default section are the keys of the document.
+func (c *converter) entries(n *node) (entries []*entry) {
+	for i := 0; i+1 < len(n.children); i += 2 {
+		keyNode, valueNode := n.children[i], n.children[i+1]
+		if n.kind == documentNode && keyNode.value == DEFAULT_SECTION {
+			for _, e := range c.entries(valueNode) {
+				entries = entry_set(entries, e)
+			}
+			continue
+		}
+		e := &entry{key: keyNode.value, line: keyNode.line + 1}
+		// The keys of a map of dotted keys take the comments around them.
+		if valueNode.kind != mappingNode {
+			c.attach(e, keyNode.line)
+		}
+		switch valueNode.kind {
+		case sectionNode, mappingNode:
+			e.value = c.entries(valueNode)
+			e.base = c.bases[valueNode]
+		case sequenceNode:
+			values := make([]interface{}, 0, len(valueNode.children))
+			for _, child := range valueNode.children {
+				values = append(values, c.value(child))
+			}
+			e.value = values
+		default:
+			e.value = c.value(valueNode)
+		}
+		entries = entry_set(entries, e)
+	}
+	return entries
+}
+
+// attach gives an entry the comments above its line and the comment on its
+// line.  A key copied from an inherited section comes after the entries of
+// later lines, and takes no comments.
+func (c *converter) attach(e *entry, line int) {
+	if line < c.line {
+		return
+	}
+	c.line = line
+	for c.next < len(c.comments) && c.comments[c.next].mark.line < line {
+		e.comments = append(e.comments, string(c.comments[c.next].value))
+		c.next++
+	}
+	if c.next < len(c.comments) && c.comments[c.next].mark.line == line {
+		e.comment = string(c.comments[c.next].value)
+		c.next++
+	}
+}
+
+// value returns a value as Unmarshal decodes it into an interface{}.
+func (c *converter) value(n *node) interface{} {
+	var value interface{}
+	c.d.unmarshal(n, reflect.ValueOf(&value).Elem())
+	return value
+}
+
+// ini_write writes a converted document as an INI document, unless the
+// document had problems or holds constructs that INI cannot express.
+func ini_write(doc *document, opts DumpOptions, problems []string) (out []byte, err error) {
+	opts = opts.preset()
+	if problems = append(problems, doc.check(opts.Dialect)...); len(problems) > 0 {
+		return nil, &ConvertError{problems}
+	}
+	defer handleErr(&err)
+	e := newEncoder(opts)
+	defer e.destroy()
+	e.convert(doc)
+	e.finish()
+	return e.out, nil
+}
+
+// check returns the constructs of a document that INI documents of the
+// dialect cannot express.
+func (doc *document) check(dialect Dialect) (problems []string) {
+	for _, e := range doc.entries {
+		if entries, ok := e.value.([]*entry); ok && !dialect.sectionless() {
+			for _, child := range entries {
+				problems = check_entry(child, dialect, 1, problems)
+			}
+			continue
+		}
+		if dialect.sectioned() {
+			problems = append(problems, e.problem("key '%s' is not in a section", e.key))
+			continue
+		}
+		problems = check_entry(e, dialect, 0, problems)
+	}
+	return problems
+}
+
+// check_entry adds the constructs of an entry of a section, or of a map
+// nested depth times in the default section, that INI documents of the
+// dialect cannot express to problems.
+func check_entry(e *entry, dialect Dialect, depth int, problems []string) []string {
+	if dialect.dotted() && strings.Contains(e.key, ".") {
+		return append(problems, e.problem("key '%s' holds a '.', which this dialect reads as nested keys", e.key))
+	}
+	switch value := e.value.(type) {
+	case []*entry:
+		switch {
+		case dialect == DialectGit && depth > 1, dialect == DialectSystemd, dialect == DialectPython:
+			return append(problems, e.problem("key '%s' holds a map, which the keys of this dialect cannot hold", e.key))
+		case dialect != DialectGit && dialect != DialectDesktop && !dialect.dotted():
+			return append(problems, e.problem("key '%s' holds a map, which this dialect reads back as flat dotted keys", e.key))
+		case dialect == DialectDesktop:
+			for _, child := range value {
+				switch child.value.(type) {
+				case []*entry, []interface{}:
+					return append(problems, e.problem("key '%s' holds a map that is not a map of locales", e.key))
+				}
+			}
+			return problems
+		}
+		for _, child := range value {
+			problems = check_entry(child, dialect, depth+1, problems)
+		}
+	case string:
+		switch dialect.dialect() {
+		case ini_DEFAULT_DIALECT, ini_MYSQL_DIALECT:
+			if value != "" && ini_emitter_select_value_style([]byte(value)) == ini_ANY_SCALAR_STYLE {
+				return append(problems, e.problem("key '%s' holds a line break or both kinds of quotes, which the values of this dialect cannot hold", e.key))
+			}
+		}
+	case []interface{}:
+		if !dialect.multi() && dialect != DialectDesktop {
+			return append(problems, e.problem("key '%s' holds a list, which the keys of this dialect cannot hold", e.key))
+		}
+		for _, v := range value {
+			switch v.(type) {
+			case []*entry, []interface{}:
+				return append(problems, e.problem("key '%s' holds a list of lists or maps", e.key))
+			}
+		}
+	}
+	return problems
+}
+
+// convert marshals a converted document, with its comments.  A section
+// that inherits another section only holds the keys that it adds or
+// changes, in the dialects that have inheritance.
+func (e *encoder) convert(doc *document) {
+	var keys, sections []*entry
+	for _, ent := range doc.entries {
+		if _, ok := ent.value.([]*entry); ok && !e.dialect.sectionless() {
+			sections = append(sections, ent)
+		} else {
+			keys = append(keys, ent)
+		}
+	}
+	if len(keys) > 0 {
+		e.convertSection([]string{DEFAULT_SECTION}, nil, "", keys)
+	}
+	for _, section := range sections {
+		entries, base := section.value.([]*entry), ""
+		if e.dialect == DialectDefault {
+			if own, ok := doc.own(section); ok {
+				entries, base = own, section.base
+			}
+		}
+		e.convertSection([]string{section.key}, section, base, entries)
+	}
+	e.comments(doc.comments, "")
+}
+
+// convertSection marshals the keys of a section.  Nested maps are dotted
+// keys, or subsections in git.
+func (e *encoder) convertSection(path []string, section *entry, base string, entries []*entry) {
+	var keys, subsections []*entry
+	for _, ent := range entries {
+		if _, ok := ent.value.([]*entry); ok && e.dialect == DialectGit {
+			subsections = append(subsections, ent)
+		} else {
+			keys = append(keys, ent)
+		}
+	}
+	if section != nil {
+		e.comments(section.comments, section.comment)
+	}
+	if len(keys) > 0 || len(subsections) == 0 {
+		if base == "" {
+			base = DEFAULT_SECTION
+		}
+		e.key(path)
+		e.must(ini_section_inherit_event_initialize(&e.event, []byte(base)))
+		e.emit()
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+		for _, ent := range keys {
+			e.convertPair([]string{ent.key}, ent)
+		}
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+	}
+	for _, subsection := range subsections {
+		e.convertSection(append(path[:len(path):len(path)], subsection.key), subsection, "", subsection.value.([]*entry))
+	}
+}
+
+// convertPair marshals a key and its value, with its comments.  The
+// comment on the line of the key goes above it.
+func (e *encoder) convertPair(keys []string, ent *entry) {
+	if ent.conflict != nil {
+		e.conflict(keys, ent.conflict)
+		return
+	}
+	e.comments(ent.comments, ent.comment)
+	value := ent.value
+	if entries, ok := value.([]*entry); ok {
+		if e.dialect != DialectDesktop {
+			for _, child := range entries {
+				e.convertPair(append(keys[:len(keys):len(keys)], child.key), child)
+			}
+			return
+		}
+		value = entry_map(entries)
+	}
+	e.pair(keys, reflect.ValueOf(value))
+}
+
+// comments marshals comment lines.
+func (e *encoder) comments(comments []string, comment string) {
+	if comment != "" {
+		comments = append(comments[:len(comments):len(comments)], comment)
+	}
+	for _, comment := range comments {
+		e.must(ini_comment_event_initialize(&e.event, []byte(comment)))
+		e.emit()
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,13 +19,16 @@ const (
 	mappingNode
 	scalarNode
 	commentNode
+	sequenceNode
 )
 
 type node struct {
 	kind         int
 	line, column int
+	file         string // The Path of the document that the node is read from.
 	tag          string
 	value        string
+	style        ini_scalar_style_t
 	children     []*node
 }
 
@@ -31,12 +36,20 @@ type node struct {
 // Parser, produces a node tree out of a ini document.
 
 type parser struct {
-	parser ini_parser_t
-	event  ini_event_t
-	doc    *node
+	parser      ini_parser_t
+	event       ini_event_t
+	doc         *node
+	insensitive bool
+	nested      bool
+	multi       bool
+	reset       bool
+	conflict    KeyConflict
+	opts        LoadOptions
+	depth       int
+	bases       map[*node]string
 }
 
-func newParser(b []byte) *parser {
+func newParser(b []byte, opts LoadOptions) *parser {
 	p := parser{}
 	if !ini_parser_initialize(&p.parser) {
 		panic("failed to initialize INI parser")
@@ -47,6 +60,33 @@ func newParser(b []byte) *parser {
 	}
 
 	ini_parser_set_input_string(&p.parser, b)
+	if opts.Encoding != EncodingAuto {
+		ini_parser_set_encoding(&p.parser, opts.Encoding.encoding())
+	}
+	ini_parser_set_dialect(&p.parser, opts.Dialect.dialect())
+	ini_parser_set_recover(&p.parser, opts.Recover)
+	if opts.KeyValueDelimiters != "" {
+		ini_parser_set_delimiters(&p.parser, []byte(opts.KeyValueDelimiters))
+	}
+	var comments []byte
+	if opts.CommentPrefixes != "" {
+		comments = []byte(opts.CommentPrefixes)
+	}
+	ini_parser_set_comments(&p.parser, comments, !opts.IgnoreInlineComment, opts.SpaceBeforeInlineComment)
+	p.insensitive = opts.Insensitive
+	p.nested = opts.NestedSections
+	p.multi = opts.Dialect.multi()
+	p.reset = opts.Dialect == DialectSystemd
+	p.conflict = opts.KeyConflict
+	p.opts = opts
+	ini_parser_set_flat_keys(&p.parser, opts.FlatKeys)
+	ini_parser_set_boolean_keys(&p.parser, opts.AllowBooleanKeys)
+	if opts.SectionNameChars != "" {
+		ini_parser_set_section_chars(&p.parser, []byte(opts.SectionNameChars))
+	}
+	if opts.NestedSections {
+		ini_parser_set_section_chars(&p.parser, []byte(opts.SectionNameChars+"."))
+	}
 
 	p.skip()
 	if p.event.typ != ini_DOCUMENT_START_EVENT {
@@ -75,15 +115,17 @@ func (p *parser) skip() {
 }
 
 func (p *parser) fail() {
+	// Marks count lines from 0; report them from 1, as p.errors does. A
+	// scanner problem on the first line keeps the plain message.
 	var where string
-	var line int
-	if p.parser.problem_mark.line != 0 {
-		line = p.parser.problem_mark.line
-	} else if p.parser.context_mark.line != 0 {
-		line = p.parser.context_mark.line
-	}
-	if line != 0 {
-		where = "line " + strconv.Itoa(line) + ": "
+	if p.parser.error == ini_SCANNER_ERROR || p.parser.error == ini_PARSER_ERROR {
+		line := p.parser.problem_mark.line
+		if line == 0 {
+			line = p.parser.context_mark.line
+		}
+		if line != 0 || p.parser.error == ini_PARSER_ERROR {
+			where = "line " + strconv.Itoa(line+1) + ": "
+		}
 	}
 	var msg string
 	if len(p.parser.problem) > 0 {
@@ -94,6 +136,24 @@ func (p *parser) fail() {
 	failf("%s%s", where, msg)
 }
 
+// errors returns the problems collected in recovery mode, in line order.
+func (p *parser) errors() []string {
+	problems := p.parser.errors
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].problem_mark.line < problems[j].problem_mark.line
+	})
+	var errors []string
+	for _, problem := range problems {
+		if problem.problem_mark.line < 0 {
+			// The problem of another file, which names its own line.
+			errors = append(errors, problem.problem)
+			continue
+		}
+		errors = append(errors, "line "+strconv.Itoa(problem.problem_mark.line+1)+": "+problem.problem)
+	}
+	return errors
+}
+
 func (p *parser) parse() *node {
 	switch p.event.typ {
 	case ini_DOCUMENT_START_EVENT:
@@ -121,13 +181,29 @@ func (p *parser) node(kind int) *node {
 		kind:   kind,
 		line:   p.event.start_mark.line,
 		column: p.event.start_mark.column,
+		file:   p.opts.Path,
 	}
 }
 
+// match reports whether two section or key names are the same name.
+func (p *parser) match(a, b string) bool {
+	if p.opts.Dialect == DialectMySQL {
+		a, b = fold_dashes(a), fold_dashes(b)
+	}
+	if p.insensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
 func (p *parser) clone_node(n *node) *node {
 	thisNode := p.node(n.kind)
 	thisNode.tag = n.tag
 	thisNode.value = n.value
+	thisNode.style = n.style
+	thisNode.line = n.line
+	thisNode.column = n.column
+	thisNode.file = n.file
 	for _, childNode := range n.children {
 		thisNode.children = append(thisNode.children, p.clone_node(childNode))
 	}
@@ -155,16 +231,17 @@ func (p *parser) merge_node(targetNode *node, sourceNode *node, overwrite bool)
 		for i := 0; i < sourceNodeCount; i += 2 {
 			nodeExist := false
 			for j := 0; j < targetNodeCount; j += 2 {
-				if sourceNode.children[i].kind == scalarNode && targetNode.children[j].kind == scalarNode && sourceNode.children[i].value == targetNode.children[j].value {
+				if sourceNode.children[i].kind == scalarNode && targetNode.children[j].kind == scalarNode && p.match(sourceNode.children[i].value, targetNode.children[j].value) {
 					nodeExist = true
-					if sourceNode.children[i+1].kind == targetNode.children[j+1].kind {
-						if len(sourceNode.children[i+1].children) > 0 && len(targetNode.children[j+1].children) > 0 {
+					if overwrite && p.multi && is_value(sourceNode.children[i+1]) && is_value(targetNode.children[j+1]) {
+						targetNode.children[j+1] = p.append_value(targetNode.children[j+1], p.clone_node(sourceNode.children[i+1]))
+					} else if sourceNode.children[i+1].kind == targetNode.children[j+1].kind && !is_value(sourceNode.children[i+1]) {
+						if len(sourceNode.children[i+1].children) > 0 {
 							p.merge_node(targetNode.children[j+1], p.clone_node(sourceNode.children[i+1]), overwrite)
 						}
-					} else {
-                        if overwrite {
-                            targetNode.children[j + 1] = p.clone_node(sourceNode.children[i + 1])
-                        }
+					} else if overwrite && (sourceNode.children[i+1].kind == targetNode.children[j+1].kind ||
+						p.replace(sourceNode.children[i], sourceNode.children[i+1])) {
+						targetNode.children[j+1] = p.clone_node(sourceNode.children[i+1])
 					}
 					break
 				}
@@ -181,36 +258,229 @@ func (p *parser) merge_node(targetNode *node, sourceNode *node, overwrite bool)
 	return
 }
 
+// replace reports whether a key that is used both for a value and for a map
+// of values takes the later of the two, as decided by the key conflict
+// policy.
+func (p *parser) replace(keyNode *node, valueNode *node) bool {
+	switch p.conflict {
+	case KeyConflictKeep:
+		return false
+	case KeyConflictError:
+		p.problem(keyNode, "key '"+keyNode.value+"' is used both for a value and a map")
+		return false
+	}
+	return true
+}
+
+// problem fails with a problem found at a node, or records it in recovery
+// mode.
+func (p *parser) problem(n *node, problem string) {
+	if !p.parser.recover {
+		failf("line %d: %s", n.line+1, problem)
+	}
+	ini_parser_record_error(&p.parser, problem, ini_mark_t{line: n.line, column: n.column})
+}
+
+// is_value reports whether a node holds the value of a key, or the values
+// of a key that appeared several times.
+func is_value(n *node) bool {
+	return n.kind == scalarNode || n.kind == sequenceNode
+}
+
+// is_reset reports whether a node is an empty value, which resets the
+// values before it in systemd files.
+func is_reset(n *node) bool {
+	return n.kind == scalarNode && n.value == ""
+}
+
+// append_value returns the values of a key that appeared again with other
+// values, when keys may hold several values.  An empty value that resets
+// the values before it stays in front of the values after it, so that the
+// reset still applies when the values are merged into another file.
+func (p *parser) append_value(valueNode *node, otherNode *node) *node {
+	if p.reset {
+		otherNodes := []*node{otherNode}
+		if otherNode.kind == sequenceNode {
+			otherNodes = otherNode.children
+		}
+		for i := len(otherNodes) - 1; i >= 0; i-- {
+			if !is_reset(otherNodes[i]) {
+				continue
+			}
+			if i == len(otherNodes)-1 {
+				return otherNodes[i]
+			}
+			return &node{kind: sequenceNode, line: otherNodes[i].line, column: otherNodes[i].column, children: otherNodes[i:]}
+		}
+	}
+	if valueNode.kind != sequenceNode {
+		valueNode = &node{kind: sequenceNode, line: valueNode.line, column: valueNode.column, children: []*node{valueNode}}
+	}
+	if otherNode.kind == sequenceNode {
+		valueNode.children = append(valueNode.children, otherNode.children...)
+	} else {
+		valueNode.children = append(valueNode.children, otherNode)
+	}
+	return valueNode
+}
+
 func (p *parser) document() *node {
 	n := p.node(documentNode)
 	p.doc = n
 	p.skip()
 	for p.event.typ != ini_DOCUMENT_END_EVENT {
-		keyNode := p.parse()
+		keyNodes := []*node{p.parse()}
+		for p.event.typ == ini_MAPPING_EVENT {
+			p.skip()
+			keyNodes = append(keyNodes, p.parse())
+		}
 		nextNode := p.parse()
 		if nextNode.kind == inheritNode {
 			childNode := p.parse()
-			// inherit
-			sectionExists := false
-			for i := 0; i < len(p.doc.children); i += 2 {
-				if p.doc.children[i].kind == scalarNode && p.doc.children[i].value == nextNode.value {
-					sectionExists = true
-					p.merge_node(childNode, p.clone_node(p.doc.children[i+1]), false)
-					break
+			if p.nested {
+				keyNodes = append(p.split(keyNodes[0]), keyNodes[1:]...)
+			}
+			parentNode, keyNode := p.nest(keyNodes)
+			// repeated section, only merged when names are case-insensitive or nested,
+			// or when keys may hold several values; the keys of case-insensitive
+			// sections keep their first values, as the keys of a section do
+			targetNode := childNode
+			reopen := p.nested || p.multi || p.opts.Dialect == DialectMySQL || p.opts.Dialect == DialectPython
+			if p.insensitive || reopen {
+				if repeatedNode := p.find_child(parentNode, keyNode.value); repeatedNode != nil {
+					targetNode = repeatedNode
+					p.merge_node(targetNode, childNode, reopen)
 				}
 			}
-			if !sectionExists && nextNode.value != DEFAULT_SECTION {
-				failf("inherit section '%s' does not exists", nextNode.value)
+			// inherit, but for Python files, whose sections only fall
+			// through to [DEFAULT] (see python_sections)
+			if p.opts.Dialect != DialectPython {
+				if inheritedNode := p.find_section(nextNode.value); inheritedNode != nil {
+					p.merge_node(targetNode, p.clone_node(inheritedNode), false)
+					if p.bases != nil && nextNode.value != DEFAULT_SECTION {
+						p.bases[targetNode] = nextNode.value
+					}
+				} else if nextNode.value != DEFAULT_SECTION {
+					if !p.parser.recover {
+						failf("inherit section '%s' does not exists", nextNode.value)
+					}
+					ini_parser_record_error(&p.parser, "inherit section '"+nextNode.value+"' does not exists",
+						ini_mark_t{line: nextNode.line, column: nextNode.column})
+				}
+			}
+			if targetNode == childNode {
+				parentNode.children = append(parentNode.children, keyNode, childNode)
+			}
+			switch p.opts.Dialect {
+			case DialectGit:
+				p.include(keyNodes, childNode)
+			case DialectMySQL:
+				p.mysql_include(targetNode)
 			}
-			n.children = append(n.children, keyNode, childNode)
 		} else if nextNode.kind == sectionNode {
-			n.children = append(n.children, keyNode, nextNode)
+			if p.opts.Dialect == DialectPython && len(nextNode.children) > 0 {
+				p.problem(nextNode.children[0], "key '"+nextNode.children[0].value+"' is not in a section")
+			}
+			n.children = append(n.children, keyNodes[0], nextNode)
 		}
 		p.skip()
 	}
+	if p.opts.DefaultFallThrough {
+		p.fall_through(DEFAULT_SECTION)
+	}
+	switch p.opts.Dialect {
+	case DialectSystemd:
+		p.drop_ins()
+	case DialectMySQL:
+		p.mysql_groups()
+	case DialectPython:
+		p.python_sections()
+	}
 	return n
 }
 
+// find_child returns the value of the named child of a section or the
+// document, or nil.
+func (p *parser) find_child(parentNode *node, name string) *node {
+	for i := 0; i < len(parentNode.children); i += 2 {
+		if parentNode.children[i].kind == scalarNode && p.match(parentNode.children[i].value, name) {
+			return parentNode.children[i+1]
+		}
+	}
+	return nil
+}
+
+// fall_through adds the keys of the sections called name to every other
+// section, and to the sections nested in it, that lacks them.  The keys of
+// the first of these sections win.
+func (p *parser) fall_through(name string) {
+	var defaultNodes []*node
+	for i := 0; i < len(p.doc.children); i += 2 {
+		if p.doc.children[i+1].kind == sectionNode && p.match(p.doc.children[i].value, name) {
+			defaultNodes = append(defaultNodes, p.doc.children[i+1])
+		}
+	}
+	if len(defaultNodes) == 0 {
+		return
+	}
+	var fall func(parentNode *node)
+	fall = func(parentNode *node) {
+		for i := 0; i < len(parentNode.children); i += 2 {
+			childNode := parentNode.children[i+1]
+			if childNode.kind != sectionNode || parentNode == p.doc && p.match(parentNode.children[i].value, name) {
+				continue
+			}
+			for _, defaultNode := range defaultNodes {
+				p.merge_node(childNode, p.clone_node(defaultNode), false)
+			}
+			fall(childNode)
+		}
+	}
+	fall(p.doc)
+}
+
+// find_section returns the named section, or nil.  Dotted names address
+// nested sections when sections are nested.
+func (p *parser) find_section(name string) *node {
+	if !p.nested {
+		return p.find_child(p.doc, name)
+	}
+	thisNode := p.doc
+	for _, key := range strings.Split(name, ".") {
+		if thisNode = p.find_child(thisNode, key); thisNode == nil || thisNode.kind != sectionNode {
+			return nil
+		}
+	}
+	return thisNode
+}
+
+// split splits a dotted section key into the keys of nested sections.
+func (p *parser) split(keyNode *node) []*node {
+	var keyNodes []*node
+	for _, key := range strings.Split(keyNode.value, ".") {
+		splitNode := *keyNode
+		splitNode.value = key
+		keyNodes = append(keyNodes, &splitNode)
+	}
+	return keyNodes
+}
+
+// nest returns the section that holds the last of the keys of nested
+// sections, along with that key.  Missing sections on the way are created
+// empty.
+func (p *parser) nest(keyNodes []*node) (*node, *node) {
+	parentNode := p.doc
+	for _, keyNode := range keyNodes[:len(keyNodes)-1] {
+		childNode := p.find_child(parentNode, keyNode.value)
+		if childNode == nil || childNode.kind != sectionNode {
+			childNode = &node{kind: sectionNode, line: keyNode.line, column: keyNode.column}
+			parentNode.children = append(parentNode.children, keyNode, childNode)
+		}
+		parentNode = childNode
+	}
+	return parentNode, keyNodes[len(keyNodes)-1]
+}
+
 func (p *parser) section() *node {
 	thisNode := p.node(sectionNode)
 
@@ -224,30 +494,57 @@ func (p *parser) section() *node {
 		}
 		if currentNodeKey.kind == scalarNode {
 			currentNodeValue := p.parse()
+			switch p.opts.Dialect {
+			case DialectPHP:
+				p.php_value(currentNodeValue)
+				if p.php_array(parentNode, currentNodeKey, currentNodeValue) {
+					continue
+				}
+			case DialectMySQL:
+				p.mysql_key(currentNodeKey)
+			case DialectDotenv:
+				p.dotenv_value(parentNode, currentNodeValue)
+			case DialectDesktop:
+				if p.desktop_locale(parentNode, currentNodeKey, currentNodeValue) {
+					continue
+				}
+			case DialectPython:
+				p.python_value(currentNodeKey, currentNodeValue)
+			}
+			if p.multi && p.append_child(parentNode, currentNodeKey, currentNodeValue) {
+				continue
+			}
 			swapChildNodes := make([]*node, 0)
+			kept := false
 			for i := 0; i < len(parentNode.children); i += 2 {
-				if parentNode.children[i].value == currentNodeKey.value {
+				if p.match(parentNode.children[i].value, currentNodeKey.value) {
 					if parentNode.children[i+1].kind == currentNodeValue.kind {
 						swapChildNodes = append(swapChildNodes, parentNode.children[i], parentNode.children[i+1])
+					} else if !p.replace(currentNodeKey, currentNodeValue) {
+						swapChildNodes = append(swapChildNodes, parentNode.children[i], parentNode.children[i+1])
+						kept = true
 					}
 				} else {
 					swapChildNodes = append(swapChildNodes, parentNode.children[i], parentNode.children[i+1])
 				}
 			}
 			parentNode.children = swapChildNodes
+			if kept {
+				continue
+			}
 
 			nodeExist := false
 			for i := 0; i < len(parentNode.children); i += 2 {
 				// condition:
 				// 1. current node type
 				// 2. current node value
-				if currentNodeKey.kind == scalarNode && parentNode.children[i].kind == scalarNode && currentNodeKey.value == parentNode.children[i].value {
+				if currentNodeKey.kind == scalarNode && parentNode.children[i].kind == scalarNode && p.match(currentNodeKey.value, parentNode.children[i].value) {
 					nodeExist = true
 					// if current node value type is different, overwrite it
 					if parentNode.children[i+1].kind != currentNodeValue.kind {
 						parentNode.children[i+1] = p.clone_node(currentNodeValue)
-					} else {
-						p.merge_node(parentNode.children[i+1], p.clone_node(currentNodeValue), true)
+					} else if !p.repeated(parentNode.children[i+1], currentNodeValue) {
+						p.merge_node(parentNode.children[i+1], currentNodeValue, true)
 					}
 					break
 				}
@@ -261,6 +558,38 @@ func (p *parser) section() *node {
 	return thisNode
 }
 
+// repeated reports whether a dotted key repeats a key that a map already
+// holds a value for, so that the first value is kept, as for the keys of a
+// section.  Keys that hold several values are not repeated.
+func (p *parser) repeated(targetNode *node, sourceNode *node) bool {
+	if p.multi {
+		return false
+	}
+	for len(sourceNode.children) == 2 {
+		targetNode = p.find_child(targetNode, sourceNode.children[0].value)
+		sourceNode = sourceNode.children[1]
+		if targetNode == nil || targetNode.kind != sourceNode.kind {
+			return false
+		}
+		if is_value(sourceNode) {
+			return true
+		}
+	}
+	return false
+}
+
+// append_child appends a value to the values of a key of a section, and
+// reports whether the section held the key.
+func (p *parser) append_child(parentNode *node, keyNode *node, valueNode *node) bool {
+	for i := 0; i < len(parentNode.children); i += 2 {
+		if p.match(parentNode.children[i].value, keyNode.value) && is_value(parentNode.children[i+1]) && is_value(valueNode) {
+			parentNode.children[i+1] = p.append_value(parentNode.children[i+1], valueNode)
+			return true
+		}
+	}
+	return false
+}
+
 func (p *parser) mapping() *node {
 	thisNode := p.node(mappingNode)
 	// until next ini_SECTION_START_EVENT
@@ -278,7 +607,7 @@ func (p *parser) mapping() *node {
 			// condition:
 			// 1. current node type
 			// 2. current node value
-			if currentNodeKey.kind == parentNode.children[i].kind && currentNodeKey.value == parentNode.children[i].value {
+			if currentNodeKey.kind == parentNode.children[i].kind && p.match(currentNodeKey.value, parentNode.children[i].value) {
 				nodeExist = true
 				break
 			}
@@ -288,8 +617,8 @@ func (p *parser) mapping() *node {
 				// if node type is different, overwrite it
 				if parentNode.children[i+1].kind != currentNodeValue.kind {
 					parentNode.children[i+1] = p.clone_node(currentNodeValue)
-				} else {
-					p.merge_node(parentNode.children[i+1], p.clone_node(currentNodeValue), true)
+				} else if !p.repeated(parentNode.children[i+1], currentNodeValue) {
+					p.merge_node(parentNode.children[i+1], currentNodeValue, true)
 				}
 				parentNode = parentNode.children[i+1]
 			}
@@ -321,6 +650,7 @@ func (p *parser) scalar() *node {
 	thisNode := p.node(scalarNode)
 	thisNode.value = string(p.event.value)
 	thisNode.tag = string(p.event.tag)
+	thisNode.style = p.event.scalar_style()
 	p.skip()
 	return thisNode
 }
@@ -329,23 +659,71 @@ func (p *parser) scalar() *node {
 // Decoder, unmarshals a node into a provided value.
 
 type decoder struct {
-	doc     *node
-	mapType reflect.Type
-	terrors []string
+	doc         *node
+	mapType     reflect.Type
+	terrors     []string
+	insensitive bool
+	dashes      bool
+	reset       bool
+	desktop     bool
+	locales     []string
+	python      bool
+	resolve     func(tag string, in string) (string, interface{})
 }
 
 var (
 	mapItemType    = reflect.TypeOf(MapItem{})
 	durationType   = reflect.TypeOf(time.Duration(0))
+	localeType     = reflect.TypeOf(LocaleString(""))
 	defaultMapType = reflect.TypeOf(map[interface{}]interface{}{})
 	ifaceType      = defaultMapType.Elem()
 )
 
-func newDecoder() *decoder {
+func newDecoder(opts LoadOptions) *decoder {
 	d := &decoder{mapType: defaultMapType}
+	d.insensitive = opts.Insensitive || opts.Dialect.multi() || opts.Dialect == DialectMySQL || opts.Dialect == DialectPython
+	d.python = opts.Dialect == DialectPython
+	d.dashes = opts.Dialect == DialectMySQL
+	d.reset = opts.Dialect == DialectSystemd
+	if opts.Dialect == DialectDesktop {
+		d.desktop = true
+		d.locales = desktop_locales(opts.Locale)
+	}
+	switch {
+	case opts.RawValues:
+		d.resolve = resolveRaw
+	case opts.Dialect == DialectPHP:
+		d.resolve = resolvePHP
+	case opts.Dialect == DialectPython:
+		d.resolve = resolvePython
+	default:
+		d.resolve = resolve
+	}
 	return d
 }
 
+// field returns the struct field that the key name decodes into.  In MySQL
+// files, the dashes and underscores of names are ignored, so that the key
+// skip-name-resolve decodes into the field SkipNameResolve.
+func (d *decoder) field(sinfo *structInfo, name string) (info fieldInfo, ok bool) {
+	if info, ok = sinfo.FieldsMap[name]; ok || !d.insensitive {
+		return info, ok
+	}
+	if d.dashes {
+		name = strip_dashes(name)
+	}
+	for _, info = range sinfo.FieldsList {
+		key := info.Key
+		if d.dashes {
+			key = strip_dashes(key)
+		}
+		if strings.EqualFold(key, name) {
+			return info, true
+		}
+	}
+	return fieldInfo{}, false
+}
+
 func (d *decoder) terror(n *node, tag string, out reflect.Value) {
 	if n.tag != "" {
 		tag = n.tag
@@ -424,15 +802,62 @@ func (d *decoder) unmarshal(n *node, out reflect.Value) (good bool) {
 	case sectionNode:
 		good = d.mapping(n, out)
 	case mappingNode:
+		if n.tag == ini_LOCALE_TAG && out.Kind() != reflect.Map && out.Kind() != reflect.Interface {
+			return d.locale(n, out)
+		}
 		good = d.mapping(n, out)
 	case scalarNode:
-		good = d.scalar(n, out)
+		if d.desktop {
+			return d.desktopScalar(n, out)
+		}
+		if out.Kind() == reflect.Slice && out.Type().Elem() != mapItemType && out.Type().Elem().Kind() != reflect.Uint8 {
+			good = d.sequence(&node{kind: sequenceNode, line: n.line, column: n.column, children: []*node{n}}, out)
+		} else {
+			good = d.scalar(n, out)
+		}
+	case sequenceNode:
+		good = d.sequence(n, out)
 	default:
 		panic("internal error: unknown node kind: " + strconv.Itoa(n.kind))
 	}
 	return good
 }
 
+// locale decodes the values of a localized key of a desktop entry file.  A
+// LocaleString is the value of the best matching locale, and anything else
+// the unlocalized value.
+func (d *decoder) locale(n *node, out reflect.Value) (good bool) {
+	locales := []string{""}
+	if out.Type() == localeType {
+		locales = d.locales
+	}
+	for _, locale := range locales {
+		for i := 0; i < len(n.children); i += 2 {
+			if n.children[i].value == locale {
+				return d.unmarshal(n.children[i+1], out)
+			}
+		}
+	}
+	return true
+}
+
+// desktopScalar decodes a value of a desktop entry file, without its escape
+// sequences.  A slice is the list of the values separated by ';'.
+func (d *decoder) desktopScalar(n *node, out reflect.Value) (good bool) {
+	if out.Kind() == reflect.Slice && out.Type().Elem().Kind() != reflect.Uint8 {
+		list := &node{kind: sequenceNode, line: n.line, column: n.column}
+		for _, value := range desktop_split(n.value) {
+			element := *n
+			element.value = value
+			list.children = append(list.children, &element)
+		}
+		return d.sequence(list, out)
+	}
+	unescaped := *n
+	unescaped.value = desktop_unescape(n.value)
+	return d.scalar(&unescaped, out)
+}
+
 func (d *decoder) document(n *node, out reflect.Value) (good bool) {
 	if len(n.children) > 0 {
 		d.doc = n
@@ -454,7 +879,7 @@ func (d *decoder) document(n *node, out reflect.Value) (good bool) {
 						if !d.unmarshal(n.children[i+1].children[j], k) {
 							continue
 						}
-						if info, ok := sinfo.FieldsMap[k.String()]; ok {
+						if info, ok := d.field(sinfo, k.String()); ok {
 							var field reflect.Value
 							if info.Inline == nil {
 								field = out.Field(info.Num)
@@ -465,7 +890,7 @@ func (d *decoder) document(n *node, out reflect.Value) (good bool) {
 						}
 					}
 				} else {
-					if info, ok := sinfo.FieldsMap[k.String()]; ok {
+					if info, ok := d.field(sinfo, k.String()); ok {
 						var field reflect.Value
 						if info.Inline == nil {
 							field = out.Field(info.Num)
@@ -650,6 +1075,50 @@ func (d *decoder) mapping(n *node, out reflect.Value) (good bool) {
 	return true
 }
 
+// sequence decodes the values of a key that appeared several times into a
+// slice.  Any other value takes the last of them.  The empty value in front
+// of the values of a systemd key that was reset is dropped.
+func (d *decoder) sequence(n *node, out reflect.Value) (good bool) {
+	children := n.children
+	if d.reset && len(children) > 0 && is_reset(children[0]) {
+		children = children[1:]
+		if len(children) == 1 && out.Kind() == reflect.Interface {
+			return d.unmarshal(children[0], out)
+		}
+	}
+	switch out.Kind() {
+	case reflect.Slice:
+		if out.Type().Elem() == mapItemType {
+			break
+		}
+		et := out.Type().Elem()
+		l := len(children)
+		slice := reflect.MakeSlice(out.Type(), 0, l)
+		for i := 0; i < l; i++ {
+			e := reflect.New(et).Elem()
+			if d.unmarshal(children[i], e) {
+				slice = reflect.Append(slice, e)
+			}
+		}
+		out.Set(slice)
+		return true
+	case reflect.Interface:
+		var slice []interface{}
+		for i := 0; i < len(children); i++ {
+			var e interface{}
+			if d.unmarshal(children[i], reflect.ValueOf(&e).Elem()) {
+				slice = append(slice, e)
+			}
+		}
+		out.Set(reflect.ValueOf(slice))
+		return true
+	}
+	if len(children) == 0 {
+		return d.unmarshal(n.children[len(n.children)-1], out)
+	}
+	return d.unmarshal(children[len(children)-1], out)
+}
+
 func (d *decoder) mappingSlice(n *node, out reflect.Value) (good bool) {
 	outt := out.Type()
 	if outt.Elem() != mapItemType {
@@ -688,7 +1157,7 @@ func (d *decoder) mappingStruct(n *node, out reflect.Value) (good bool) {
 		if !d.unmarshal(n.children[i], name) {
 			continue
 		}
-		if info, ok := sinfo.FieldsMap[name.String()]; ok {
+		if info, ok := d.field(sinfo, name.String()); ok {
 			var field reflect.Value
 			if info.Inline == nil {
 				field = out.Field(info.Num)
@@ -705,7 +1174,7 @@ func (d *decoder) scalar(n *node, out reflect.Value) (good bool) {
 	var tag string
 	var resolved interface{}
 
-	tag, resolved = resolve(n.tag, n.value)
+	tag, resolved = d.resolve(n.tag, n.value)
 	if tag == ini_BINARY_TAG {
 		data, err := base64.StdEncoding.DecodeString(resolved.(string))
 		if err != nil {
@@ -824,6 +1293,12 @@ func (d *decoder) scalar(n *node, out reflect.Value) (good bool) {
 		case bool:
 			out.SetBool(resolved)
 			good = true
+		case int:
+			// Python's getboolean reads 1 and 0 as booleans too.
+			if d.python && (resolved == 0 || resolved == 1) {
+				out.SetBool(resolved == 1)
+				good = true
+			}
 		}
 	case reflect.Float32, reflect.Float64:
 		switch resolved := resolved.(type) {
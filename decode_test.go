@@ -1,10 +1,16 @@
 package ini_test
 
 import (
+	"encoding/binary"
 	"errors"
 	. "gopkg.in/check.v1"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"unicode/utf16"
 
 	"go-ini"
 )
@@ -535,6 +541,10 @@ var unmarshalErrorTests = []struct {
 		"hello= world\n[section_2:section_1]\nhello_2= world\n[section_1]\nhello_1= world",
 		"ini: inherit section 'section_1' does not exists",
 	},
+	{
+		"[section 1]\nhello= world",
+		"ini: found blank character inside the section key",
+	},
 }
 
 func (s *S) TestUnmarshalErrors(c *C) {
@@ -545,6 +555,690 @@ func (s *S) TestUnmarshalErrors(c *C) {
 	}
 }
 
+var unmarshalInsensitiveTests = []struct {
+	data  string
+	value interface{}
+}{
+	{
+		"Hello= world\n[Section]\nhello= world_1",
+		map[string]interface{}{
+			"Hello":   "world",
+			"Section": map[interface{}]interface{}{"hello": "world_1"},
+		},
+	}, {
+		"[Section]\nhello= world\n[SECTION]\nHELLO= world_1\nhello_1= world_1",
+		map[string]interface{}{
+			"Section": map[interface{}]interface{}{"hello": "world", "hello_1": "world_1"},
+		},
+	}, {
+		"[Section]\nhello= world\nHELLO= world_1",
+		map[string]interface{}{
+			"Section": map[interface{}]interface{}{"hello": "world"},
+		},
+	}, {
+		"[Section_1]\nhello= world\n[section_2:SECTION_1]\nhello_2= world",
+		map[string]interface{}{
+			"Section_1": map[interface{}]interface{}{"hello": "world"},
+			"section_2": map[interface{}]interface{}{"hello": "world", "hello_2": "world"},
+		},
+	}, {
+		"MaxConns= 10\n[Section]\nHELLO= world",
+		&struct {
+			MaxConns int
+			Section  struct {
+				Hello string
+			}
+		}{10, struct{ Hello string }{"world"}},
+	}, {
+		"NAME= world",
+		&struct {
+			Name string `ini:"name"`
+		}{"world"},
+	},
+}
+
+func (s *S) TestInsensitiveUnmarshal(c *C) {
+	for _, item := range unmarshalInsensitiveTests {
+		typ := reflect.ValueOf(item.value).Type()
+		var value interface{}
+		if typ.Kind() == reflect.Map {
+			value = reflect.MakeMap(typ).Interface()
+		} else {
+			value = reflect.New(typ.Elem()).Interface()
+		}
+		err := ini.InsensitiveUnmarshal([]byte(item.data), value)
+		c.Assert(err, IsNil)
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+var unmarshalDelimiterTests = []struct {
+	delimiters string
+	data       string
+	value      map[string]interface{}
+}{
+	{
+		":",
+		"hello: world\nurl: http://host:80/",
+		map[string]interface{}{"hello": "world", "url": "http://host:80/"},
+	}, {
+		"=:",
+		"hello= world\n[section_1]\nhello_1: a=b\n[section_2:section_1]\nhello_2 : world",
+		map[string]interface{}{
+			"hello": "world",
+			"section_1": map[interface{}]interface{}{
+				"hello":   "world",
+				"hello_1": "a=b",
+			},
+			"section_2": map[interface{}]interface{}{
+				"hello":   "world",
+				"hello_1": "a=b",
+				"hello_2": "world",
+			},
+		},
+	}, {
+		" ",
+		"hello world\nhello_1\t  two words",
+		map[string]interface{}{"hello": "world", "hello_1": "two words"},
+	}, {
+		" =",
+		"hello world\nhello_1 = world_1",
+		map[string]interface{}{"hello": "world", "hello_1": "world_1"},
+	},
+}
+
+func (s *S) TestUnmarshalDelimiters(c *C) {
+	for _, item := range unmarshalDelimiterTests {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{KeyValueDelimiters: item.delimiters})
+		c.Assert(err, IsNil)
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestUnmarshalDefaultDelimiter(c *C) {
+	// An explicit "=" reads keys as the empty default does, and lets values
+	// hold a '='.
+	data := "hello = world\n[section]\nhello_1 = world_1\n"
+	var empty, explicit map[string]interface{}
+	c.Assert(ini.UnmarshalWithOptions([]byte(data), &empty, ini.LoadOptions{}), IsNil)
+	c.Assert(ini.UnmarshalWithOptions([]byte(data), &explicit, ini.LoadOptions{KeyValueDelimiters: "="}), IsNil)
+	c.Assert(explicit, DeepEquals, empty)
+
+	data = "[section]\nhello = a=b\n"
+	err := ini.UnmarshalWithOptions([]byte(data), &empty, ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: line 2: did not find expected <key> or <section-start>")
+	explicit = nil
+	c.Assert(ini.UnmarshalWithOptions([]byte(data), &explicit, ini.LoadOptions{KeyValueDelimiters: "="}), IsNil)
+	c.Assert(explicit, DeepEquals, map[string]interface{}{"section": map[interface{}]interface{}{"hello": "a=b"}})
+}
+
+var unmarshalWithOptionsTests = []struct {
+	options ini.LoadOptions
+	data    string
+	value   map[string]interface{}
+}{
+	{
+		ini.LoadOptions{IgnoreInlineComment: true},
+		"# comment\nurl= http://host/#frag\npassword= a;b",
+		map[string]interface{}{"url": "http://host/#frag", "password": "a;b"},
+	}, {
+		ini.LoadOptions{SpaceBeforeInlineComment: true},
+		"url= http://host/#frag ; comment\npassword= a;b\t# comment\nempty= # comment",
+		map[string]interface{}{"url": "http://host/#frag", "password": "a;b", "empty": nil},
+	}, {
+		ini.LoadOptions{CommentPrefixes: ";"},
+		"; comment\nurl= http://host/#frag ;comment",
+		map[string]interface{}{"url": "http://host/#frag"},
+	}, {
+		ini.LoadOptions{CommentPrefixes: "!", IgnoreInlineComment: true},
+		"! comment\npassword= a!b",
+		map[string]interface{}{"password": "a!b"},
+	}, {
+		ini.LoadOptions{},
+		"[\"database primary\"]\nhello= world\n[ section_1 ]\nhello= world\n[section_2:\"database primary\"]",
+		map[string]interface{}{
+			"database primary": map[interface{}]interface{}{"hello": "world"},
+			"section_1":        map[interface{}]interface{}{"hello": "world"},
+			"section_2":        map[interface{}]interface{}{"hello": "world"},
+		},
+	}, {
+		ini.LoadOptions{SectionNameChars: " .\""},
+		"[remote \"origin\"]\nhello= world\n[server.eu-west]\nhello= world\n[世界]\nhello= world",
+		map[string]interface{}{
+			"remote \"origin\"": map[interface{}]interface{}{"hello": "world"},
+			"server.eu-west":    map[interface{}]interface{}{"hello": "world"},
+			"世界":                map[interface{}]interface{}{"hello": "world"},
+		},
+	}, {
+		ini.LoadOptions{},
+		"[base]\nhello= world\n[remote \"Origin\"]\nurl= a\n[remote \"fork\" : base]\nurl= b",
+		map[string]interface{}{
+			"base": map[interface{}]interface{}{"hello": "world"},
+			"remote": map[interface{}]interface{}{
+				"Origin": map[interface{}]interface{}{"url": "a"},
+				"fork":   map[interface{}]interface{}{"hello": "world", "url": "b"},
+			},
+		},
+	}, {
+		ini.LoadOptions{Dialect: ini.DialectPython},
+		"[remote \"origin\"]\nurl= a",
+		map[string]interface{}{
+			"remote \"origin\"": map[interface{}]interface{}{"url": "a"},
+		},
+	}, {
+		ini.LoadOptions{NestedSections: true},
+		"[a.b]\nhello= world\n[a]\nhello_1= world\n[c:a.b]\nhello_2= world",
+		map[string]interface{}{
+			"a": map[interface{}]interface{}{
+				"b":       map[interface{}]interface{}{"hello": "world"},
+				"hello_1": "world",
+			},
+			"c": map[interface{}]interface{}{"hello": "world", "hello_2": "world"},
+		},
+	}, {
+		ini.LoadOptions{FlatKeys: true},
+		"spring.datasource.url= jdbc\nspring.datasource= primary",
+		map[string]interface{}{"spring.datasource.url": "jdbc", "spring.datasource": "primary"},
+	}, {
+		ini.LoadOptions{},
+		"a= 1\na.b= 2\nc.d= 3\nc= 4",
+		map[string]interface{}{"a": map[interface{}]interface{}{"b": 2}, "c": 4},
+	}, {
+		ini.LoadOptions{KeyConflict: ini.KeyConflictKeep},
+		"a= 1\na.b= 2\nc.d= 3\nc= 4\ne.f= 5\ne.f.g= 6",
+		map[string]interface{}{
+			"a": 1,
+			"c": map[interface{}]interface{}{"d": 3},
+			"e": map[interface{}]interface{}{"f": 5},
+		},
+	}, {
+		ini.LoadOptions{AllowBooleanKeys: true},
+		"skip-networking\nport= 3306\nquick # comment",
+		map[string]interface{}{"skip-networking": true, "port": 3306, "quick": true},
+	},
+}
+
+func (s *S) TestUnmarshalWithOptions(c *C) {
+	for _, item := range unmarshalWithOptionsTests {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, item.options)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestUnmarshalDefaultFallThrough(c *C) {
+	data := "[server]\nport= 8080\n[client]\ntimeout= 5\n[default]\ntimeout= 30\nretries= 3\n"
+
+	value := map[string]interface{}{}
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(value["server"], DeepEquals, map[interface{}]interface{}{"port": 8080})
+
+	value = map[string]interface{}{}
+	err = ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{DefaultFallThrough: true})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"server":  map[interface{}]interface{}{"port": 8080, "timeout": 30, "retries": 3},
+		"client":  map[interface{}]interface{}{"timeout": 5, "retries": 3},
+		"timeout": 30,
+		"retries": 3,
+	})
+
+	var config struct {
+		Server struct {
+			Port    int
+			Timeout int
+		}
+	}
+	err = ini.UnmarshalWithOptions([]byte(data), &config, ini.LoadOptions{DefaultFallThrough: true})
+	c.Assert(err, IsNil)
+	c.Assert(config.Server.Port, Equals, 8080)
+	c.Assert(config.Server.Timeout, Equals, 30)
+}
+
+var unmarshalRecoverTests = []struct {
+	data  string
+	value interface{}
+	error string
+}{
+	{
+		"a= 1\nbad line\nb= 2",
+		map[string]interface{}{"a": 1, "b": 2},
+		"ini: parse errors:\n  line 2: did not find expected <value> or <map>",
+	}, {
+		"[section!]\na= 1\n[section_1]\nb= 2 = 3\nc= 3",
+		map[string]interface{}{"a": 1, "section_1": map[interface{}]interface{}{"a": 1, "c": 3}},
+		"ini: parse errors:\n  line 1: .*\n  line 4: did not find expected <line-break>",
+	}, {
+		"[section_2:section_1]\nhello_2= world\n[section_1]\nhello_1= world",
+		map[string]interface{}{
+			"section_2": map[interface{}]interface{}{"hello_2": "world"},
+			"section_1": map[interface{}]interface{}{"hello_1": "world"},
+		},
+		"ini: parse errors:\n  line 1: inherit section 'section_1' does not exists",
+	},
+}
+
+func (s *S) TestUnmarshalKeyConflictError(c *C) {
+	value := map[string]interface{}{}
+	err := ini.UnmarshalWithOptions([]byte("a= 1\na.b= 2"), &value, ini.LoadOptions{KeyConflict: ini.KeyConflictError})
+	c.Assert(err, ErrorMatches, "ini: line 2: key 'a' is used both for a value and a map")
+
+	value = map[string]interface{}{}
+	err = ini.UnmarshalWithOptions([]byte("a= 1\na.b= 2\nc.d= 3\nc.d.e= 4\nf= 5"), &value,
+		ini.LoadOptions{KeyConflict: ini.KeyConflictError, Recover: true})
+	c.Assert(err, ErrorMatches, "ini: parse errors:\n  line 2: key 'a' .*\n  line 4: key 'd' .*")
+	c.Assert(value, DeepEquals, map[string]interface{}{"a": 1, "c": map[interface{}]interface{}{"d": 3}, "f": 5})
+}
+
+func (s *S) TestUnmarshalRecover(c *C) {
+	for _, item := range unmarshalRecoverTests {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Recover: true})
+		c.Assert(err, ErrorMatches, item.error)
+		c.Assert(err, FitsTypeOf, &ini.ParseError{})
+		c.Assert(value, DeepEquals, item.value)
+	}
+}
+
+var unmarshalGitTests = []struct {
+	data  string
+	value map[string]interface{}
+}{
+	{
+		"[Core]\n\tIgnoreCase = true\n\tbare\n[remote \"Origin\"]\n\turl = https://host/repo.git\n",
+		map[string]interface{}{
+			"core":   map[interface{}]interface{}{"ignorecase": true, "bare": true},
+			"remote": map[interface{}]interface{}{"Origin": map[interface{}]interface{}{"url": "https://host/repo.git"}},
+		},
+	}, {
+		"[remote \"origin\"]\n\tfetch = +refs/heads/*\n\tfetch = +refs/tags/*\n[remote \"origin\"]\n\tfetch = +refs/notes/*\n",
+		map[string]interface{}{
+			"remote": map[interface{}]interface{}{"origin": map[interface{}]interface{}{
+				"fetch": []interface{}{"+refs/heads/*", "+refs/tags/*", "+refs/notes/*"},
+			}},
+		},
+	}, {
+		"[Branch.Main]\n\tremote = origin\n[branch \"a \\\"b\\\" c.d\"]\n\tremote = fork\n",
+		map[string]interface{}{
+			"branch": map[interface{}]interface{}{
+				"main":        map[interface{}]interface{}{"remote": "origin"},
+				"a \"b\" c.d": map[interface{}]interface{}{"remote": "fork"},
+			},
+		},
+	}, {
+		"[alias]\n\tst = status  -s ; comment\n\tlg = log \\\n--oneline\n\tq = \"a ; b\"  \"c\"\n\te = tab\\tand\\\\ \\\"quote\\\"\n\tempty =\n",
+		map[string]interface{}{
+			"alias": map[interface{}]interface{}{
+				"st":    "status  -s",
+				"lg":    "log --oneline",
+				"q":     "a ; b  c",
+				"e":     "tab\tand\\ \"quote\"",
+				"empty": nil,
+			},
+		},
+	},
+}
+
+func (s *S) TestUnmarshalGit(c *C) {
+	for _, item := range unmarshalGitTests {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectGit})
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestUnmarshalGitStruct(c *C) {
+	type remote struct {
+		URL   string
+		Fetch []string
+	}
+	var value struct {
+		Core struct {
+			IgnoreCase bool
+			Editor     string
+		}
+		Remote map[string]remote
+	}
+	data := "[core]\n\tignoreCase\n\teditor = vim\n\teditor = emacs\n" +
+		"[remote \"origin\"]\n\turl = u\n\tfetch = f\n[remote \"fork\"]\n\tfetch = f1\n\tfetch = f2\n"
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectGit})
+	c.Assert(err, IsNil)
+	c.Assert(value.Core.IgnoreCase, Equals, true)
+	c.Assert(value.Core.Editor, Equals, "emacs")
+	c.Assert(value.Remote, DeepEquals, map[string]remote{
+		"origin": {URL: "u", Fetch: []string{"f"}},
+		"fork":   {Fetch: []string{"f1", "f2"}},
+	})
+}
+
+func (s *S) TestUnmarshalGitInclude(c *C) {
+	files := map[string]string{
+		"/home/me/.gitconfig": "[user]\n\tname = A\n\temail = a@home\n" +
+			"[include]\n\tpath = .gitconfig.local\n\tpath = missing\n" +
+			"[includeIf \"gitdir:/work/\"]\n\tpath = /etc/work.inc\n" +
+			"[includeIf \"onbranch:feature/\"]\n\tpath = /etc/feature.inc\n" +
+			"[core]\n\teditor = vim\n",
+		"/home/me/.gitconfig.local": "[user]\n\tname = B\n[core]\n\teditor = emacs\n\tpager = less\n",
+		"/etc/work.inc":             "[user]\n\temail = a@work\n",
+		"/etc/feature.inc":          "[user]\n\tsigningkey = F\n",
+	}
+	readFile := func(filename string) ([]byte, error) {
+		if data, ok := files[filename]; ok {
+			return []byte(data), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	opts := ini.LoadOptions{Dialect: ini.DialectGit, Path: "/home/me/.gitconfig", ReadFile: readFile}
+	value := map[string]interface{}{}
+	err := ini.UnmarshalWithOptions([]byte(files[opts.Path]), &value, opts)
+	c.Assert(err, IsNil)
+	c.Assert(value["user"], DeepEquals, map[interface{}]interface{}{"name": []interface{}{"A", "B"}, "email": "a@home"})
+	c.Assert(value["core"], DeepEquals, map[interface{}]interface{}{"editor": []interface{}{"emacs", "vim"}, "pager": "less"})
+
+	var config struct {
+		User struct {
+			Name, Email, SigningKey string
+		}
+	}
+	opts.GitDir = "/work/project/.git"
+	opts.GitBranch = "feature/x"
+	err = ini.UnmarshalWithOptions([]byte(files[opts.Path]), &config, opts)
+	c.Assert(err, IsNil)
+	c.Assert(config.User.Name, Equals, "B")
+	c.Assert(config.User.Email, Equals, "a@work")
+	c.Assert(config.User.SigningKey, Equals, "F")
+
+	err = ini.UnmarshalWithOptions([]byte("[include]\n\tpath = local\n"), &value, ini.LoadOptions{Dialect: ini.DialectGit})
+	c.Assert(err, ErrorMatches, "ini: line 2: relative config includes must come from files")
+
+	files["/loop"] = "[include]\n\tpath = /loop\n"
+	err = ini.UnmarshalWithOptions([]byte(files["/loop"]), &value, ini.LoadOptions{Dialect: ini.DialectGit, ReadFile: readFile})
+	c.Assert(err, ErrorMatches, "(?s)ini: line 2: /loop: .*exceeded maximum include depth.*")
+}
+
+var unmarshalGitErrorTests = []struct {
+	data, error string
+}{
+	{"[core]\n\teditor = \"vim\n", "ini: line 2: found unexpected end of line"},
+	{"[core]\n\teditor = \\q\n", "ini: line 2: found unknown escape character"},
+	{"[core]\n\t1editor = vim\n", "ini: line 2: found character\\(1\\) that cannot start for any key"},
+	{"[remote \"origin\" x]\n", "ini: did not find expected ']'"},
+	{"[core]\n\teditor vim\n", "ini: line 2: did not find expected <value> or <map>"},
+}
+
+func (s *S) TestUnmarshalGitErrors(c *C) {
+	for _, item := range unmarshalGitErrorTests {
+		var value interface{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectGit})
+		c.Assert(err, ErrorMatches, item.error, Commentf("data: %q", item.data))
+	}
+}
+
+var unmarshalSystemdTests = []struct {
+	data  string
+	value map[string]interface{}
+}{
+	{
+		"# comment\n[Unit]\nDescription=My \"svc\" ; not a comment\nAfter=a.target b.target\n",
+		map[string]interface{}{
+			"Unit": map[interface{}]interface{}{"Description": "My \"svc\" ; not a comment", "After": "a.target b.target"},
+		},
+	}, {
+		"[Service]\nExecStartPre=-/bin/a\nExecStartPre=@/bin/b arg\nExecStart=+/bin/c\nEnvironment=A=1 B=2\n",
+		map[string]interface{}{
+			"Service": map[interface{}]interface{}{
+				"ExecStartPre": []interface{}{"-/bin/a", "@/bin/b arg"},
+				"ExecStart":    "+/bin/c",
+				"Environment":  "A=1 B=2",
+			},
+		},
+	}, {
+		"[Service]\nExecStart=/bin/a \\\n  --flag \\\n# skipped\n  --other\n",
+		map[string]interface{}{
+			"Service": map[interface{}]interface{}{"ExecStart": "/bin/a    --flag    --other"},
+		},
+	}, {
+		"[Service]\nExecReload=/bin/a\nExecReload=\nExecReload=/bin/b\nExecReload=/bin/c\nExecStop=/bin/d\nExecStop=\n",
+		map[string]interface{}{
+			"Service": map[interface{}]interface{}{
+				"ExecReload": []interface{}{"/bin/b", "/bin/c"},
+				"ExecStop":   nil,
+			},
+		},
+	},
+}
+
+func (s *S) TestUnmarshalSystemd(c *C) {
+	for _, item := range unmarshalSystemdTests {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectSystemd})
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestUnmarshalSystemdDropIns(c *C) {
+	dir := c.MkDir()
+	filename := filepath.Join(dir, "foo.service")
+	files := map[string]string{
+		filename: "[Unit]\nDescription=Foo\n[Service]\nExecStartPre=/bin/a\nExecStart=/bin/foo\nRestart=no\n",
+		filepath.Join(filename+".d", "20-reset.conf"): "[Service]\nExecStart=\nExecStart=/bin/foo --new\n",
+		filepath.Join(filename+".d", "10-more.conf"):  "[Service]\nExecStartPre=/bin/b\nRestart=always\n[Install]\nWantedBy=multi-user.target\n",
+		filepath.Join(filename+".d", "ignored.txt"):   "[Service]\nRestart=never\n",
+	}
+	c.Assert(os.Mkdir(filename+".d", 0755), IsNil)
+	for name, data := range files {
+		c.Assert(ioutil.WriteFile(name, []byte(data), 0644), IsNil)
+	}
+
+	var unit struct {
+		Unit struct {
+			Description string
+		}
+		Service struct {
+			ExecStartPre []string
+			ExecStart    []string
+			Restart      string
+		}
+		Install map[string]string
+	}
+	err := ini.UnmarshalFile(filename, &unit, ini.LoadOptions{Dialect: ini.DialectSystemd})
+	c.Assert(err, IsNil)
+	c.Assert(unit.Unit.Description, Equals, "Foo")
+	c.Assert(unit.Service.ExecStartPre, DeepEquals, []string{"/bin/a", "/bin/b"})
+	c.Assert(unit.Service.ExecStart, DeepEquals, []string{"/bin/foo --new"})
+	c.Assert(unit.Service.Restart, Equals, "always")
+	c.Assert(unit.Install, DeepEquals, map[string]string{"WantedBy": "multi-user.target"})
+
+	// Without a path, there are no drop-ins.
+	value := map[string]interface{}{}
+	err = ini.UnmarshalWithOptions([]byte(files[filename]), &value, ini.LoadOptions{Dialect: ini.DialectSystemd})
+	c.Assert(err, IsNil)
+	c.Assert(value["Install"], IsNil)
+
+	bad := filepath.Join(filename+".d", "30-bad.conf")
+	c.Assert(ioutil.WriteFile(bad, []byte("[Service]\nExecStart\n"), 0644), IsNil)
+	err = ini.UnmarshalFile(filename, &value, ini.LoadOptions{Dialect: ini.DialectSystemd})
+	c.Assert(err, ErrorMatches, "ini: "+regexp.QuoteMeta(bad)+": line 2: did not find expected <value> or <map>")
+}
+
+var unmarshalPHPTests = []struct {
+	data  string
+	raw   bool
+	value map[string]interface{}
+}{
+	{
+		"; comment\nname = app ; comment\ndebug = On\nverbose = NONE\ncache = Null\nport = 8080\nratio = 1.5\nquoted = \"yes\"\n",
+		false,
+		map[string]interface{}{"name": "app", "debug": true, "verbose": false, "cache": nil, "port": 8080, "ratio": 1.5, "quoted": "yes"},
+	}, {
+		"sq = '${INI_TEST_HOME}'\ndq = \"${INI_TEST_HOME}/a\"\nplain = ${INI_TEST_HOME}/b\nconst = APP_DIR\nquoted = \"APP_DIR\"\n",
+		false,
+		map[string]interface{}{"sq": "${INI_TEST_HOME}", "dq": "/home/me/a", "plain": "/home/me/b", "const": "/app", "quoted": "APP_DIR"},
+	}, {
+		"hosts[] = a\nhosts[] = b\nopts[x] = 1\nopts[y] = 2\nmix[] = p\nmix[k] = q\nmix[] = r\n",
+		false,
+		map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+			"opts":  map[interface{}]interface{}{"x": 1, "y": 2},
+			"mix":   map[interface{}]interface{}{0: "p", "k": "q", 1: "r"},
+		},
+	}, {
+		"debug = On\nport = 8080\nempty =\nplain = ${INI_TEST_HOME}\nconst = APP_DIR\nopts[x] = 1\n",
+		true,
+		map[string]interface{}{
+			"debug": "On", "port": "8080", "empty": "", "plain": "${INI_TEST_HOME}", "const": "APP_DIR",
+			"opts": map[interface{}]interface{}{"x": "1"},
+		},
+	},
+}
+
+func (s *S) TestUnmarshalPHP(c *C) {
+	os.Setenv("INI_TEST_HOME", "/home/me")
+	defer os.Unsetenv("INI_TEST_HOME")
+	for _, item := range unmarshalPHPTests {
+		value := map[string]interface{}{}
+		opts := ini.LoadOptions{Dialect: ini.DialectPHP, Constants: map[string]string{"APP_DIR": "/app"}, RawValues: item.raw}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, opts)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(value, DeepEquals, item.value, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestUnmarshalPHPStruct(c *C) {
+	var value struct {
+		DB struct {
+			Hosts   []string
+			Options map[string]int `ini:"opts"`
+			Debug   bool
+			Port    int
+		}
+	}
+	data := "[db]\nhosts[] = a\nhosts[] = b\nopts[x] = 1\ndebug = yes\nport = 5432\n"
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectPHP})
+	c.Assert(err, IsNil)
+	c.Assert(value.DB.Hosts, DeepEquals, []string{"a", "b"})
+	c.Assert(value.DB.Options, DeepEquals, map[string]int{"x": 1})
+	c.Assert(value.DB.Debug, Equals, true)
+	c.Assert(value.DB.Port, Equals, 5432)
+
+	err = ini.UnmarshalWithOptions([]byte("[db]\nport = \"5432\"\n"), &value, ini.LoadOptions{Dialect: ini.DialectPHP})
+	c.Assert(err, ErrorMatches, "(?s).*cannot unmarshal str `5432` into int.*")
+}
+
+func (s *S) TestUnmarshalMySQL(c *C) {
+	dir := c.MkDir()
+	filename := filepath.Join(dir, "my.cnf")
+	files := map[string]string{
+		filename: "[client]\nport=3306\n" +
+			"[mysqld]\nskip-name-resolve\nmax_connections = 100\nloose-group_replication_start_on_boot = off\nsql_mode = \"STRICT\" # comment\n" +
+			"!includedir conf.d\n" +
+			"[mysqld-8.0]\nskip_name_resolve = OFF\n[mysqld-5.7]\nquery_cache_size = 0\n" +
+			"[mysqld]\nbind-address = 0.0.0.0\n!include " + filepath.Join(dir, "extra.cnf") + "\n",
+		filepath.Join(dir, "conf.d", "b.cnf"): "[mysqld]\nmax_connections = 500\n",
+		filepath.Join(dir, "conf.d", "a.cnf"): "[mysqld]\nmax-connections = 300\nport = 3307\n",
+		filepath.Join(dir, "conf.d", "c.txt"): "[mysqld]\nport = 1\n",
+		filepath.Join(dir, "extra.cnf"):       "[client]\nuser = root\n",
+	}
+	c.Assert(os.Mkdir(filepath.Join(dir, "conf.d"), 0755), IsNil)
+	for name, data := range files {
+		c.Assert(ioutil.WriteFile(name, []byte(data), 0644), IsNil)
+	}
+
+	value := map[string]interface{}{}
+	err := ini.UnmarshalFile(filename, &value, ini.LoadOptions{Dialect: ini.DialectMySQL})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"client": map[interface{}]interface{}{"port": 3306, "user": "root"},
+		"mysqld": map[interface{}]interface{}{
+			"skip-name-resolve":               true,
+			"max_connections":                 500,
+			"group_replication_start_on_boot": false,
+			"sql_mode":                        "STRICT",
+			"port":                            3307,
+			"bind-address":                    "0.0.0.0",
+		},
+		"mysqld-8.0": map[interface{}]interface{}{"skip_name_resolve": false},
+		"mysqld-5.7": map[interface{}]interface{}{"query_cache_size": 0},
+	})
+
+	var config struct {
+		Mysqld struct {
+			SkipNameResolve bool
+			MaxConnections  int
+			QueryCacheSize  *int
+		}
+	}
+	err = ini.UnmarshalFile(filename, &config, ini.LoadOptions{Dialect: ini.DialectMySQL, MySQLVersion: "8.0"})
+	c.Assert(err, IsNil)
+	c.Assert(config.Mysqld.SkipNameResolve, Equals, false)
+	c.Assert(config.Mysqld.MaxConnections, Equals, 500)
+	c.Assert(config.Mysqld.QueryCacheSize, IsNil)
+}
+
+var unmarshalMySQLErrorTests = []struct {
+	data, error string
+}{
+	{"[mysqld]\n!include\n", "ini: line 2: did not find expected file name"},
+	{"[mysqld]\n!include /nonexistent/my.cnf\n", "ini: line 2: open /nonexistent/my.cnf: .*"},
+	{"[mysqld]\n!include relative.cnf\n", "ini: line 2: open relative.cnf: .*"},
+}
+
+func (s *S) TestUnmarshalMySQLErrors(c *C) {
+	for _, item := range unmarshalMySQLErrorTests {
+		var value interface{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectMySQL})
+		c.Assert(err, ErrorMatches, item.error, Commentf("data: %q", item.data))
+	}
+}
+
+// utf16Bytes encodes s in UTF-16, optionally big endian and with a byte
+// order mark.
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	var data []byte
+	if bom {
+		data = append(data, 0, 0)
+		order.PutUint16(data, 0xFEFF)
+	}
+	for _, c := range utf16.Encode([]rune(s)) {
+		data = append(data, 0, 0)
+		order.PutUint16(data[len(data)-2:], c)
+	}
+	return data
+}
+
+func (s *S) TestUnmarshalWindows(c *C) {
+	data := "; comment\r\n[Settings]\r\nName=Caf\u00e9 \U0001F600 ; not a comment\r\nPath=C:\\app\r\n[SETTINGS]\r\nmax.size=1\r\n"
+	inputs := [][]byte{
+		[]byte(data),
+		append([]byte("\xef\xbb\xbf"), data...),
+		utf16Bytes(data, false, true),
+		utf16Bytes(data, true, true),
+		utf16Bytes(data, false, false),
+		utf16Bytes(data, true, false),
+	}
+	for i, in := range inputs {
+		value := map[string]interface{}{}
+		err := ini.UnmarshalWithOptions(in, &value, ini.LoadOptions{Dialect: ini.DialectWindows})
+		c.Assert(err, IsNil, Commentf("input %d", i))
+		c.Assert(value, DeepEquals, map[string]interface{}{
+			"Settings": map[interface{}]interface{}{"Name": "Caf\u00e9 \U0001F600 ; not a comment", "Path": "C:\\app", "max.size": 1},
+		}, Commentf("input %d", i))
+	}
+
+	var value interface{}
+	err := ini.Unmarshal([]byte{0xff, 0xfe, 'a', 0, '=', 0, 'b'}, &value)
+	c.Assert(err, ErrorMatches, "ini: incomplete UTF-16 character")
+}
+
 var unmarshalerTests = []struct {
 	data  string
 	value interface{}
@@ -570,6 +1264,275 @@ func (o *unmarshalerType) UnmarshalINI(unmarshal func(v interface{}) error) erro
 	return nil
 }
 
+func (s *S) TestUnmarshalProperties(c *C) {
+	data := "# comment\n! comment\n  server.host = example.com  \nserver.port:8080\nname Caf\xe9\n" +
+		"debug\nkey\\ with\\=escapes=a\\tb\\u00e9\\\n    continued\nemoji=\\ud83d\\ude00\n"
+	var value map[string]interface{}
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectProperties})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"server.host":      "example.com  ",
+		"server.port":      8080,
+		"name":             "Caf\u00e9",
+		"debug":            nil,
+		"key with=escapes": "a\tb\u00e9continued",
+		"emoji":            "\U0001F600",
+	})
+
+	var config struct {
+		Host string `ini:"server.host"`
+		Port int    `ini:"server.port"`
+	}
+	err = ini.UnmarshalWithOptions([]byte(data), &config, ini.LoadOptions{Dialect: ini.DialectProperties})
+	c.Assert(err, IsNil)
+	c.Assert(config.Host, Equals, "example.com  ")
+	c.Assert(config.Port, Equals, 8080)
+
+	err = ini.UnmarshalWithOptions([]byte("a=b\nc=\\u00zz\n"), &value, ini.LoadOptions{Dialect: ini.DialectProperties})
+	c.Assert(err, ErrorMatches, "ini: line 2: did not find expected hexdecimal number")
+}
+
+func (s *S) TestUnmarshalDotenv(c *C) {
+	os.Setenv("INI_TEST_HOME", "/home/ini")
+	defer os.Unsetenv("INI_TEST_HOME")
+	data := "# comment\nexport PORT=8080\nNAME = two words # comment\nCOLOR=#fff\n" +
+		"LITERAL='${PORT}\\n'\nQUOTED=\"a\\tb ${PORT} \\${PORT}\" # comment\n" +
+		"MULTI=\"first\nsecond\"\nPATH=${INI_TEST_HOME}/bin:${UNSET_INI_TEST}\nEMPTY=\n"
+	var value map[string]interface{}
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectDotenv})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"PORT":    8080,
+		"NAME":    "two words",
+		"COLOR":   "#fff",
+		"LITERAL": "${PORT}\\n",
+		"QUOTED":  "a\tb 8080 ${PORT}",
+		"MULTI":   "first\nsecond",
+		"PATH":    "/home/ini/bin:",
+		"EMPTY":   nil,
+	})
+
+	type config struct {
+		Name string `ini:"NAME"`
+		Port int    `ini:"PORT"`
+	}
+	var fromINI, fromEnv config
+	c.Assert(ini.Unmarshal([]byte("NAME = two words\nPORT = 8080\n"), &fromINI), IsNil)
+	c.Assert(ini.UnmarshalWithOptions([]byte(data), &fromEnv, ini.LoadOptions{Dialect: ini.DialectDotenv}), IsNil)
+	c.Assert(fromEnv, DeepEquals, fromINI)
+}
+
+var unmarshalDotenvErrorTests = []struct {
+	data, error string
+}{
+	{"A=1\nB\n", "ini: line 2: did not find expected '='"},
+	{"A=1\nB='x' y\n", "ini: line 2: did not find expected comment or line break"},
+	{"A=1\nB=\"x\n", "ini: line 3: found unexpected end of stream"},
+}
+
+func (s *S) TestUnmarshalDotenvErrors(c *C) {
+	for _, item := range unmarshalDotenvErrorTests {
+		var value map[string]interface{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectDotenv})
+		c.Assert(err, ErrorMatches, item.error, Commentf("data: %q", item.data))
+	}
+}
+
+var desktopEntry = `# comment
+[Desktop Entry]
+Type=Application
+Name=Browser
+Name[de]=Netzbetrachter
+Name[de_DE@euro]=Euro-Netzbetrachter
+Comment=Browse the web\sand\nmore
+Exec="/opt/browser/bin/browser" %u
+Categories=Network;WebBrowser;Semi\;colon;
+Terminal=false
+
+[Desktop Action new-window]
+Name=New Window
+Name[fr]=Nouvelle fenêtre
+`
+
+func (s *S) TestUnmarshalDesktop(c *C) {
+	var value map[string]interface{}
+	err := ini.UnmarshalWithOptions([]byte(desktopEntry), &value, ini.LoadOptions{Dialect: ini.DialectDesktop})
+	c.Assert(err, IsNil)
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"Desktop Entry": map[interface{}]interface{}{
+			"Type":       "Application",
+			"Name":       map[interface{}]interface{}{"": "Browser", "de": "Netzbetrachter", "de_DE@euro": "Euro-Netzbetrachter"},
+			"Comment":    "Browse the web and\nmore",
+			"Exec":       "\"/opt/browser/bin/browser\" %u",
+			"Categories": "Network;WebBrowser;Semi;colon;",
+			"Terminal":   false,
+		},
+		"Desktop Action new-window": map[interface{}]interface{}{
+			"Name": map[interface{}]interface{}{"": "New Window", "fr": "Nouvelle fenêtre"},
+		},
+	})
+}
+
+func (s *S) TestUnmarshalDesktopLocales(c *C) {
+	type entry struct {
+		Name       ini.LocaleString `ini:"Name"`
+		Categories []string         `ini:"Categories"`
+		Terminal   bool             `ini:"Terminal"`
+	}
+	type action struct {
+		Name     ini.LocaleString `ini:"Name"`
+		Fallback string           `ini:"Name[fr]"`
+	}
+	var tests = []struct {
+		locale, name, action string
+	}{
+		{"de_DE.UTF-8@euro", "Euro-Netzbetrachter", "New Window"},
+		{"de_AT.UTF-8", "Netzbetrachter", "New Window"},
+		{"fr_FR", "Browser", "Nouvelle fenêtre"},
+		{"C", "Browser", "New Window"},
+	}
+	for _, test := range tests {
+		var value struct {
+			Entry  entry  `ini:"Desktop Entry"`
+			Action action `ini:"Desktop Action new-window"`
+		}
+		err := ini.UnmarshalWithOptions([]byte(desktopEntry), &value, ini.LoadOptions{Dialect: ini.DialectDesktop, Locale: test.locale})
+		c.Assert(err, IsNil)
+		c.Assert(string(value.Entry.Name), Equals, test.name, Commentf("locale: %s", test.locale))
+		c.Assert(string(value.Action.Name), Equals, test.action, Commentf("locale: %s", test.locale))
+		c.Assert(value.Entry.Categories, DeepEquals, []string{"Network", "WebBrowser", "Semi;colon"})
+		c.Assert(value.Entry.Terminal, Equals, false)
+	}
+
+	var plain struct {
+		Entry struct {
+			Name string `ini:"Name"`
+		} `ini:"Desktop Entry"`
+	}
+	err := ini.UnmarshalWithOptions([]byte(desktopEntry), &plain, ini.LoadOptions{Dialect: ini.DialectDesktop, Locale: "de"})
+	c.Assert(err, IsNil)
+	c.Assert(plain.Entry.Name, Equals, "Browser")
+}
+
+var pythonConfig = `[DEFAULT]
+ServerAliveInterval = 45
+Compression = yes
+home: /home/%(user)s
+user = bob
+
+[bitbucket.org]
+User = hg
+path = %(home)s/repo
+; comment
+motd =
+    first line
+    # comment
+
+    second line, 100%%
+empty =
+
+[topsecret.server.com:22]
+Port: 50022
+ForwardX11 = no
+`
+
+func (s *S) TestUnmarshalPython(c *C) {
+	var value map[string]interface{}
+	err := ini.UnmarshalWithOptions([]byte(pythonConfig), &value, ini.LoadOptions{Dialect: ini.DialectPython})
+	c.Assert(err, IsNil)
+	defaults := map[interface{}]interface{}{
+		"serveraliveinterval": 45,
+		"compression":         true,
+		"home":                "/home/bob",
+		"user":                "bob",
+	}
+	c.Assert(value, DeepEquals, map[string]interface{}{
+		"DEFAULT": defaults,
+		"bitbucket.org": map[interface{}]interface{}{
+			"serveraliveinterval": 45,
+			"compression":         true,
+			"home":                "/home/hg",
+			"user":                "hg",
+			"path":                "/home/hg/repo",
+			"motd":                "\nfirst line\n\nsecond line, 100%",
+			"empty":               "",
+		},
+		"topsecret.server.com:22": map[interface{}]interface{}{
+			"serveraliveinterval": 45,
+			"compression":         true,
+			"home":                "/home/bob",
+			"user":                "bob",
+			"port":                50022,
+			"forwardx11":          false,
+		},
+	})
+
+	var config struct {
+		Server struct {
+			Interval    int    `ini:"ServerAliveInterval"`
+			Compression bool   `ini:"Compression"`
+			Forward     bool   `ini:"ForwardX11"`
+			Port        string `ini:"Port"`
+		} `ini:"topsecret.server.com:22"`
+	}
+	err = ini.UnmarshalWithOptions([]byte(pythonConfig), &config, ini.LoadOptions{Dialect: ini.DialectPython})
+	c.Assert(err, IsNil)
+	c.Assert(config.Server.Interval, Equals, 45)
+	c.Assert(config.Server.Compression, Equals, true)
+	c.Assert(config.Server.Forward, Equals, false)
+	c.Assert(config.Server.Port, Equals, "50022")
+
+	value = nil
+	err = ini.UnmarshalWithOptions([]byte(pythonConfig), &value, ini.LoadOptions{Dialect: ini.DialectPython, Interpolation: ini.InterpolationNone})
+	c.Assert(err, IsNil)
+	c.Assert(value["bitbucket.org"].(map[interface{}]interface{})["path"], Equals, "%(home)s/repo")
+}
+
+func (s *S) TestUnmarshalPythonBooleans(c *C) {
+	var value struct {
+		S map[string]bool `ini:"s"`
+	}
+	data := "[s]\na = 1\nb = YES\nc = True\nd = on\ne = 0\nf = No\ng = FALSE\nh = off\n"
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectPython})
+	c.Assert(err, IsNil)
+	c.Assert(value.S, DeepEquals, map[string]bool{
+		"a": true, "b": true, "c": true, "d": true,
+		"e": false, "f": false, "g": false, "h": false,
+	})
+
+	err = ini.UnmarshalWithOptions([]byte("[s]\na = 2\n"), &value, ini.LoadOptions{Dialect: ini.DialectPython})
+	c.Assert(err, ErrorMatches, "ini: unmarshal errors:\n  line 2: cannot unmarshal int `2` into bool")
+}
+
+func (s *S) TestUnmarshalPythonExtended(c *C) {
+	data := "[DEFAULT]\nroot = /opt\n[common]\nbase = ${root}/common\n[app]\nDir = ${common:base}/app\nlog = ${dir}/log, $$5\n"
+	var value map[string]map[string]string
+	err := ini.UnmarshalWithOptions([]byte(data), &value, ini.LoadOptions{Dialect: ini.DialectPython, Interpolation: ini.InterpolationExtended})
+	c.Assert(err, IsNil)
+	c.Assert(value["app"], DeepEquals, map[string]string{
+		"root": "/opt",
+		"dir":  "/opt/common/app",
+		"log":  "/opt/common/app/log, $5",
+	})
+}
+
+var unmarshalPythonErrorTests = []struct {
+	data, error string
+}{
+	{"a = 1\n", "ini: line 1: key 'a' is not in a section"},
+	{"[s]\na = %(b)s\n", "ini: line 2: option 'a' in section 's' refers to the unknown option 'b'"},
+	{"[s]\na = %(b)s\nb = %(a)s\n", "ini: line 2: option 'a' in section 's' refers to itself through too many references"},
+	{"[s]\na = 50%\n", "ini: line 2: '%' must be followed by '%' or '\\(' in option 'a' in section 's'"},
+}
+
+func (s *S) TestUnmarshalPythonErrors(c *C) {
+	for _, item := range unmarshalPythonErrorTests {
+		var value map[string]interface{}
+		err := ini.UnmarshalWithOptions([]byte(item.data), &value, ini.LoadOptions{Dialect: ini.DialectPython})
+		c.Assert(err, ErrorMatches, item.error, Commentf("data: %q", item.data))
+	}
+}
+
 func (s *S) TestUnmarshalerWholeDocument(c *C) {
 	obj := &unmarshalerType{}
 	err := ini.Unmarshal([]byte(unmarshalerTests[0].data), obj)
@@ -0,0 +1,1030 @@
+package ini
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Dialect is a preset for the syntax of a family of INI files.
+type Dialect int
+
+const (
+	// DialectDefault is the syntax described in the package documentation.
+	DialectDefault Dialect = iota
+
+	// DialectGit is the syntax of git-config files, such as ~/.gitconfig
+	// and .git/config:
+	//
+	//   - a [section "subsection"] header is a subsection nested in a
+	//     section; section names are case-insensitive, and subsection
+	//     names are case-sensitive;
+	//   - keys are case-insensitive, and never split at dots;
+	//   - a key may appear several times, and its values decode into a
+	//     slice, or the last value wins;
+	//   - a key without a value is true;
+	//   - values may hold double quotes, escape sequences and line
+	//     continuations;
+	//   - [include] and [includeIf "condition"] sections include the files
+	//     of their path keys.
+	//
+	// Section and key names are lowercased.
+	DialectGit
+
+	// DialectSystemd is the syntax of systemd unit files, such as
+	// foo.service and foo.timer:
+	//
+	//   - keys are delimited by '=' and never split at dots;
+	//   - a key may appear several times, and its values decode into a
+	//     slice, or the last value wins;
+	//   - an empty value, as in "ExecStart=", resets the values before it;
+	//   - values are read verbatim, quotes and prefixes such as '-', '@'
+	//     and '+' included, and a backslash at the end of a line continues
+	//     the value on the next line;
+	//   - comments only stand on lines of their own.
+	//
+	// The drop-in files foo.service.d/*.conf next to the Path of the
+	// document are merged into it, in the lexical order of their names.
+	DialectSystemd
+
+	// DialectPHP is the syntax that PHP's parse_ini_file reads in its
+	// INI_SCANNER_TYPED mode:
+	//
+	//   - comments start with ';';
+	//   - keys are never split at dots, and a key[] key appends its value
+	//     to the array of the key, as key[index] sets an element of it;
+	//   - unquoted values are typed: true, on and yes are true; false,
+	//     off, no and none are false; null is null, regardless of case;
+	//     numbers are numbers, and anything else is a string;
+	//   - quoted values are strings;
+	//   - ${NAME} in unquoted and double-quoted values is replaced with
+	//     the environment variable NAME, and an unquoted value that names
+	//     one of the Constants of LoadOptions is replaced with it.
+	//
+	// RawValues turns the typing and the replacements off, as the
+	// INI_SCANNER_RAW mode does.
+	DialectPHP
+
+	// DialectMySQL is the syntax of MySQL option files, such as my.cnf:
+	//
+	//   - a key without a value, such as skip-name-resolve, is true;
+	//   - '-' and '_' are the same character in names, and a loose- prefix
+	//     is dropped from keys;
+	//   - keys are never split at dots;
+	//   - repeated groups are merged;
+	//   - !include file and !includedir directory directives include a
+	//     file, or the .cnf files of a directory;
+	//   - [name-version] groups, such as [mysqld-8.0], apply to the
+	//     MySQLVersion of LoadOptions.
+	DialectMySQL
+
+	// DialectWindows is the syntax of the files that Windows tools read and
+	// write with GetPrivateProfileString and WritePrivateProfileString:
+	//
+	//   - section and key names are case-insensitive;
+	//   - comments start with ';' and only stand on lines of their own;
+	//   - keys are never split at dots.
+	//
+	// Files are written with CR LF line breaks.  UTF-16 files, which these
+	// tools often write, are read like any file with a byte order mark.
+	DialectWindows
+
+	// DialectProperties is the syntax of Java properties files, such as
+	// application.properties:
+	//
+	//   - there are no sections, and every key is in the default one;
+	//   - a key ends at '=', ':' or a blank, and a key without a value
+	//     holds an empty value;
+	//   - comments start with '#' or '!' and only stand on lines of their
+	//     own;
+	//   - keys are never split at dots;
+	//   - keys and values may hold escape sequences, such as '\=' and
+	//     '\u00e9', and a backslash at the end of a line continues them on
+	//     the next line.
+	//
+	// Files are read and written in ISO-8859-1, unless an Encoding is set.
+	DialectProperties
+
+	// DialectDotenv is the syntax of .env files:
+	//
+	//   - there are no sections, and every key is in the default one;
+	//   - a line may start with the export keyword, which is dropped;
+	//   - keys are never split at dots;
+	//   - comments start with '#', and follow a blank inside a line;
+	//   - single-quoted values are literal; double-quoted values may hold
+	//     the escape sequences '\n', '\r', '\t', '\"', '\\' and '\$';
+	//     both may span several lines;
+	//   - ${NAME} in unquoted and double-quoted values is replaced with the
+	//     value of the key NAME above it, or else with the environment
+	//     variable NAME.
+	DialectDotenv
+
+	// DialectDesktop is the syntax of freedesktop.org desktop entry files,
+	// such as firefox.desktop:
+	//
+	//   - group names may hold spaces, as in [Desktop Entry];
+	//   - keys are delimited by '=' and never split at dots;
+	//   - comments start with '#' and only stand on lines of their own;
+	//   - values are read verbatim, but for the escape sequences '\s',
+	//     '\n', '\t', '\r' and '\\';
+	//   - a value decodes into a slice as a list of values separated by
+	//     ';', where '\;' escapes a ';';
+	//   - the localized values of a key, such as Name[de], are grouped
+	//     with it into a map by locale, where the unlocalized value has
+	//     the empty locale.  The map decodes into a string as the
+	//     unlocalized value, and into a LocaleString as the value of the
+	//     best match for the Locale of LoadOptions.
+	DialectDesktop
+
+	// DialectPython is the syntax of the files that Python's configparser
+	// reads:
+	//
+	//   - section names may hold any character but brackets;
+	//   - keys are delimited by '=' or ':', are lowercased, and are never
+	//     split at dots;
+	//   - comments start with '#' or ';' and only stand on lines of their
+	//     own;
+	//   - values are read verbatim, and continue on the lines after them
+	//     that are indented deeper than their key;
+	//   - the keys of the [DEFAULT] section fall through to every other
+	//     section that lacks them;
+	//   - values refer to other values as the Interpolation of LoadOptions
+	//     sets, %(name)s by default;
+	//   - 1, yes, true and on decode into a bool as true, and 0, no, false
+	//     and off as false, regardless of case.
+	//
+	// Every key must be in a section.
+	DialectPython
+)
+
+// An Interpolation is the syntax of the references to other values in the
+// values of a Python configparser file.
+type Interpolation int
+
+const (
+	// InterpolationBasic replaces %(name)s with the value of the key name
+	// of the section or of [DEFAULT], and %% with %, as configparser's
+	// BasicInterpolation does.
+	InterpolationBasic Interpolation = iota
+
+	// InterpolationExtended replaces ${name} with the value of the key
+	// name of the section or of [DEFAULT], ${section:name} with the value
+	// of the key name of another section, and $$ with $, as configparser's
+	// ExtendedInterpolation does.
+	InterpolationExtended
+
+	// InterpolationNone reads values as they are.
+	InterpolationNone
+)
+
+// PYTHON_DEFAULT_SECTION is the section whose keys fall through to every
+// other section of a Python configparser file.
+const PYTHON_DEFAULT_SECTION = "DEFAULT"
+
+// A LocaleString is a localized value of a desktop entry file, such as the
+// Name of an application.  It decodes into the value of the locale that
+// best matches the Locale of LoadOptions, or else into the unlocalized
+// value.
+type LocaleString string
+
+// dialect returns the scanner and emitter dialect of d.
+func (d Dialect) dialect() ini_dialect_t {
+	switch d {
+	case DialectGit:
+		return ini_GIT_DIALECT
+	case DialectSystemd:
+		return ini_SYSTEMD_DIALECT
+	case DialectMySQL:
+		return ini_MYSQL_DIALECT
+	case DialectProperties:
+		return ini_PROPERTIES_DIALECT
+	case DialectDotenv:
+		return ini_DOTENV_DIALECT
+	case DialectDesktop:
+		return ini_DESKTOP_DIALECT
+	case DialectPython:
+		return ini_PYTHON_DIALECT
+	}
+	return ini_DEFAULT_DIALECT
+}
+
+// preset returns opts, with the options that its dialect implies turned on.
+func (opts LoadOptions) preset() LoadOptions {
+	switch opts.Dialect {
+	case DialectGit:
+		opts.FlatKeys = true
+		opts.AllowBooleanKeys = true
+	case DialectSystemd:
+		opts.FlatKeys = true
+		opts.KeyValueDelimiters = "="
+		opts.IgnoreInlineComment = true
+	case DialectPHP:
+		opts.FlatKeys = true
+		opts.CommentPrefixes = ";"
+	case DialectMySQL:
+		opts.FlatKeys = true
+		opts.AllowBooleanKeys = true
+		opts.SectionNameChars += "."
+	case DialectWindows:
+		opts.Insensitive = true
+		opts.CommentPrefixes = ";"
+		opts.IgnoreInlineComment = true
+		opts.FlatKeys = true
+	case DialectProperties:
+		opts.FlatKeys = true
+		opts.CommentPrefixes = "#!"
+		opts.IgnoreInlineComment = true
+		if opts.Encoding == EncodingAuto {
+			opts.Encoding = EncodingLatin1
+		}
+	case DialectDotenv:
+		opts.FlatKeys = true
+		opts.KeyValueDelimiters = "="
+		opts.CommentPrefixes = "#"
+		opts.SpaceBeforeInlineComment = true
+	case DialectDesktop:
+		opts.FlatKeys = true
+		opts.KeyValueDelimiters = "="
+		opts.CommentPrefixes = "#"
+		opts.IgnoreInlineComment = true
+		opts.SectionNameChars += " .@+,/"
+	case DialectPython:
+		opts.FlatKeys = true
+		opts.KeyValueDelimiters = "=:"
+		opts.CommentPrefixes = "#;"
+		opts.IgnoreInlineComment = true
+	}
+	return opts
+}
+
+// preset returns opts, with the options that its dialect implies turned on.
+func (opts DumpOptions) preset() DumpOptions {
+	switch opts.Dialect {
+	case DialectWindows:
+		opts.CRLF = true
+	case DialectProperties:
+		if opts.Encoding == EncodingAuto {
+			opts.Encoding = EncodingLatin1
+		}
+	}
+	return opts
+}
+
+// multi reports whether the keys of d may appear several times, to hold
+// several values.
+func (d Dialect) multi() bool {
+	return d == DialectGit || d == DialectSystemd
+}
+
+// dotted reports whether d reads dotted keys as nested maps.
+func (d Dialect) dotted() bool {
+	return !LoadOptions{Dialect: d}.preset().FlatKeys
+}
+
+// sectioned reports whether every key of d must be in a section.
+func (d Dialect) sectioned() bool {
+	return d == DialectGit || d == DialectSystemd || d == DialectDesktop || d == DialectPython
+}
+
+// sectionless reports whether d has no sections, so that every key is in
+// the default one.
+func (d Dialect) sectionless() bool {
+	return d == DialectProperties || d == DialectDotenv
+}
+
+// ----------------------------------------------------------------------------
+// Git includes
+
+// The maximum depth of nested git includes.
+const maxIncludeDepth = 10
+
+// include reads the files that an [include] section, or an [includeIf
+// "condition"] section whose condition holds, includes by its path keys.
+// The included documents are merged into the document at the place of the
+// section, so that later keys override them.
+func (p *parser) include(keyNodes []*node, thisNode *node) {
+	switch {
+	case len(keyNodes) == 1 && keyNodes[0].value == "include":
+	case len(keyNodes) == 2 && keyNodes[0].value == "includeif" && p.include_condition(keyNodes[1]):
+	default:
+		return
+	}
+	pathNode := p.find_child(thisNode, "path")
+	if pathNode == nil {
+		return
+	}
+	pathNodes := []*node{pathNode}
+	if pathNode.kind == sequenceNode {
+		pathNodes = pathNode.children
+	}
+	for _, pathNode := range pathNodes {
+		p.include_file(pathNode)
+	}
+}
+
+// include_file reads the file of a path key, and merges it into the
+// document.  Missing files are ignored, as git does.
+func (p *parser) include_file(pathNode *node) {
+	filename := pathNode.value
+	if filename == "" {
+		return
+	}
+	if strings.HasPrefix(filename, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			filename = filepath.Join(home, filename[2:])
+		}
+	} else if !filepath.IsAbs(filename) {
+		if p.opts.Path == "" {
+			p.problem(pathNode, "relative config includes must come from files")
+			return
+		}
+		filename = filepath.Join(filepath.Dir(p.opts.Path), filename)
+	}
+	if p.depth >= maxIncludeDepth {
+		p.problem(pathNode, "exceeded maximum include depth while including '"+filename+"'")
+		return
+	}
+	doc, problems, err := p.compose_file(filename)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		p.problem(pathNode, strings.TrimPrefix(err.Error(), "ini: "))
+		return
+	}
+	for _, problem := range problems {
+		p.problem(pathNode, problem)
+	}
+	p.merge_document(doc)
+}
+
+// compose_file reads a file and produces its node tree.  Its problems are
+// prefixed with its name.
+func (p *parser) compose_file(filename string) (doc *node, problems []string, err error) {
+	readFile := p.opts.ReadFile
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+	in, err := readFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := p.opts
+	opts.Path = filename
+	err = func() (err error) {
+		defer handleErr(&err)
+		q := newParser(in, opts)
+		defer q.destroy()
+		q.depth = p.depth + 1
+		doc = q.parse()
+		for _, problem := range q.errors() {
+			problems = append(problems, filename+": "+problem)
+		}
+		return nil
+	}()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ini: %s: %s", filename, strings.TrimPrefix(err.Error(), "ini: "))
+	}
+	return doc, problems, nil
+}
+
+// merge_document merges the sections of another document into the
+// document, so that its keys override the keys of the document.
+func (p *parser) merge_document(doc *node) {
+	if doc == nil {
+		return
+	}
+	for i := 0; i < len(doc.children); i += 2 {
+		if targetNode := p.find_child(p.doc, doc.children[i].value); targetNode != nil && targetNode.kind == doc.children[i+1].kind {
+			p.merge_node(targetNode, doc.children[i+1], true)
+		} else {
+			p.doc.children = append(p.doc.children, doc.children[i], doc.children[i+1])
+		}
+	}
+}
+
+// include_condition reports whether the condition of an [includeIf]
+// section holds.  The gitdir:, gitdir/i: and onbranch: conditions are
+// supported; the other ones never hold.
+func (p *parser) include_condition(conditionNode *node) bool {
+	condition := conditionNode.value
+	switch {
+	case strings.HasPrefix(condition, "gitdir:"):
+		return p.opts.GitDir != "" && match_path(p.gitdir_pattern(conditionNode, condition[len("gitdir:"):]), p.opts.GitDir, false)
+	case strings.HasPrefix(condition, "gitdir/i:"):
+		return p.opts.GitDir != "" && match_path(p.gitdir_pattern(conditionNode, condition[len("gitdir/i:"):]), p.opts.GitDir, true)
+	case strings.HasPrefix(condition, "onbranch:"):
+		pattern := condition[len("onbranch:"):]
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return p.opts.GitBranch != "" && match_path(pattern, p.opts.GitBranch, false)
+	}
+	return false
+}
+
+// gitdir_pattern expands a gitdir: pattern as git does: "~/" is the home
+// directory, "./" is the directory of the document, a relative pattern
+// matches at any depth, and a trailing '/' matches everything below.
+func (p *parser) gitdir_pattern(conditionNode *node, pattern string) string {
+	if strings.HasPrefix(pattern, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.ToSlash(home) + pattern[1:]
+		}
+	} else if strings.HasPrefix(pattern, "./") {
+		if p.opts.Path == "" {
+			p.problem(conditionNode, "relative config include conditionals must come from files")
+			return pattern
+		}
+		pattern = filepath.ToSlash(filepath.Dir(p.opts.Path)) + pattern[1:]
+	} else if !strings.HasPrefix(pattern, "/") && !filepath.IsAbs(pattern) {
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return pattern
+}
+
+// match_path reports whether a slash-separated name matches a pattern,
+// where "**" matches any number of path elements, and the other elements
+// are matched as by path.Match.
+func match_path(pattern, name string, fold bool) bool {
+	name = strings.TrimSuffix(filepath.ToSlash(name), "/")
+	if fold {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	return match_elements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func match_elements(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if match_elements(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ----------------------------------------------------------------------------
+// Systemd drop-ins
+
+// drop_ins merges the drop-in files foo.service.d/*.conf of a unit file
+// foo.service into the document, in the lexical order of their names.
+func (p *parser) drop_ins() {
+	if p.opts.Path == "" || p.depth > 0 {
+		return
+	}
+	filenames, err := filepath.Glob(filepath.Join(p.opts.Path+".d", "*.conf"))
+	if err != nil {
+		failf("%s", err)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		doc, problems, err := p.compose_file(filename)
+		if err != nil {
+			fail(err)
+		}
+		for _, problem := range problems {
+			ini_parser_record_error(&p.parser, problem, ini_mark_t{line: -1})
+		}
+		p.merge_document(doc)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// PHP arrays and values
+
+// php_value replaces the ${NAME} variables and the constant of a value of a
+// PHP file, and marks quoted values as strings.
+func (p *parser) php_value(valueNode *node) {
+	if valueNode.kind != scalarNode {
+		return
+	}
+	if valueNode.style != ini_PLAIN_SCALAR_STYLE {
+		valueNode.tag = ini_STR_TAG
+	}
+	if p.opts.RawValues {
+		return
+	}
+	if value, ok := p.opts.Constants[valueNode.value]; ok && valueNode.style == ini_PLAIN_SCALAR_STYLE {
+		valueNode.value = value
+		return
+	}
+	if valueNode.style != ini_SINGLE_QUOTED_SCALAR_STYLE {
+		valueNode.value = php_variable.ReplaceAllStringFunc(valueNode.value, func(variable string) string {
+			return os.Getenv(variable[2 : len(variable)-1])
+		})
+	}
+}
+
+var php_variable = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// php_array sets the element of the array of a key[] or key[index] key of
+// a PHP file, and reports whether the key was one.  The elements of key[]
+// keys make a sequence; a key[index] key makes the array a map, where the
+// elements of key[] keys are indexed by the next unused integer.
+func (p *parser) php_array(parentNode *node, keyNode *node, valueNode *node) bool {
+	i := strings.IndexByte(keyNode.value, '[')
+	if i <= 0 || !strings.HasSuffix(keyNode.value, "]") {
+		return false
+	}
+	index := keyNode.value[i+1 : len(keyNode.value)-1]
+	nameNode := *keyNode
+	nameNode.value = keyNode.value[:i]
+
+	arrayNode := p.find_child(parentNode, nameNode.value)
+	if arrayNode == nil || arrayNode.kind != sequenceNode && arrayNode.kind != mappingNode {
+		arrayNode = &node{kind: sequenceNode, line: keyNode.line, column: keyNode.column}
+		p.set_child(parentNode, &nameNode, arrayNode)
+	}
+	if index == "" && arrayNode.kind == sequenceNode {
+		arrayNode.children = append(arrayNode.children, valueNode)
+		return true
+	}
+	if arrayNode.kind == sequenceNode {
+		// An index turns the sequence into a map.
+		elementNodes := arrayNode.children
+		arrayNode.kind = mappingNode
+		arrayNode.children = nil
+		for j, elementNode := range elementNodes {
+			arrayNode.children = append(arrayNode.children, &node{kind: scalarNode, line: elementNode.line, column: elementNode.column, value: strconv.Itoa(j)}, elementNode)
+		}
+	}
+	if index == "" {
+		next := 0
+		for j := 0; j < len(arrayNode.children); j += 2 {
+			if n, err := strconv.Atoi(arrayNode.children[j].value); err == nil && n >= next {
+				next = n + 1
+			}
+		}
+		index = strconv.Itoa(next)
+	}
+	indexNode := *keyNode
+	indexNode.value = index
+	p.set_child(arrayNode, &indexNode, valueNode)
+	return true
+}
+
+// set_child sets the value of a key of a section or a map, replacing the
+// value the key held.
+func (p *parser) set_child(parentNode *node, keyNode *node, valueNode *node) {
+	for i := 0; i < len(parentNode.children); i += 2 {
+		if p.match(parentNode.children[i].value, keyNode.value) {
+			parentNode.children[i+1] = valueNode
+			return
+		}
+	}
+	parentNode.children = append(parentNode.children, keyNode, valueNode)
+}
+
+// ----------------------------------------------------------------------------
+// MySQL option files
+
+// fold_dashes returns a name with its dashes replaced by underscores.
+func fold_dashes(name string) string {
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// strip_dashes returns a name without its dashes and underscores.
+func strip_dashes(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(name)
+}
+
+// mysql_key drops the loose- prefix of a key.
+func (p *parser) mysql_key(keyNode *node) {
+	if strings.HasPrefix(fold_dashes(keyNode.value), "loose_") {
+		keyNode.value = keyNode.value[len("loose_"):]
+	}
+}
+
+// mysql_include reads the files that the !include and !includedir
+// directives of a group include, and merges them into the document.
+func (p *parser) mysql_include(sectionNode *node) {
+	var directiveNodes []*node
+	children := sectionNode.children[:0]
+	for i := 0; i < len(sectionNode.children); i += 2 {
+		switch sectionNode.children[i].value {
+		case "!include", "!includedir":
+			directiveNodes = append(directiveNodes, sectionNode.children[i], sectionNode.children[i+1])
+		default:
+			children = append(children, sectionNode.children[i], sectionNode.children[i+1])
+		}
+	}
+	sectionNode.children = children
+
+	for i := 0; i < len(directiveNodes); i += 2 {
+		pathNode := directiveNodes[i+1]
+		filename := pathNode.value
+		if !filepath.IsAbs(filename) && p.opts.Path != "" {
+			filename = filepath.Join(filepath.Dir(p.opts.Path), filename)
+		}
+		filenames := []string{filename}
+		if directiveNodes[i].value == "!includedir" {
+			var err error
+			filenames, err = filepath.Glob(filepath.Join(filename, "*.cnf"))
+			if err != nil {
+				p.problem(pathNode, err.Error())
+				continue
+			}
+			sort.Strings(filenames)
+		}
+		for _, filename := range filenames {
+			if p.depth >= maxIncludeDepth {
+				p.problem(pathNode, "exceeded maximum include depth while including '"+filename+"'")
+				break
+			}
+			doc, problems, err := p.compose_file(filename)
+			if err != nil {
+				p.problem(pathNode, strings.TrimPrefix(err.Error(), "ini: "))
+				continue
+			}
+			for _, problem := range problems {
+				p.problem(pathNode, problem)
+			}
+			p.merge_document(doc)
+		}
+	}
+}
+
+// mysql_groups merges the [name-version] groups of the MySQLVersion of the
+// options into the [name] groups, and drops the groups of other versions.
+func (p *parser) mysql_groups() {
+	if p.opts.MySQLVersion == "" || p.depth > 0 {
+		return
+	}
+	var versionNodes []*node
+	children := p.doc.children[:0]
+	for i := 0; i < len(p.doc.children); i += 2 {
+		name := p.doc.children[i].value
+		if j := strings.LastIndexByte(name, '-'); j > 0 && j+1 < len(name) && is_digit([]byte(name), j+1) {
+			if name[j+1:] == p.opts.MySQLVersion {
+				nameNode := *p.doc.children[i]
+				nameNode.value = name[:j]
+				versionNodes = append(versionNodes, &nameNode, p.doc.children[i+1])
+			}
+			continue
+		}
+		children = append(children, p.doc.children[i], p.doc.children[i+1])
+	}
+	p.doc.children = children
+	p.merge_document(&node{kind: documentNode, children: versionNodes})
+}
+
+// ----------------------------------------------------------------------------
+// Dotenv values
+
+// dotenv_value replaces the escape sequences and the ${NAME} variables of a
+// value of a dotenv file, and marks quoted values as strings.  A variable
+// is the value of the key NAME of the section, or else the environment
+// variable NAME.
+func (p *parser) dotenv_value(sectionNode *node, valueNode *node) {
+	if valueNode.kind != scalarNode {
+		return
+	}
+	if valueNode.style == ini_SINGLE_QUOTED_SCALAR_STYLE {
+		valueNode.tag = ini_STR_TAG
+		return
+	}
+	escapes := valueNode.style == ini_DOUBLE_QUOTED_SCALAR_STYLE
+	if escapes {
+		valueNode.tag = ini_STR_TAG
+	}
+	lookup := func(name string) string {
+		for i := len(sectionNode.children) - 2; i >= 0; i -= 2 {
+			keyNode, valueNode := sectionNode.children[i], sectionNode.children[i+1]
+			if keyNode.value == name && valueNode.kind == scalarNode {
+				return valueNode.value
+			}
+		}
+		return os.Getenv(name)
+	}
+	valueNode.value = dotenv_expand(valueNode.value, escapes, lookup)
+}
+
+// dotenv_expand replaces the ${NAME} variables of value with their lookup,
+// and its escape sequences too if escapes is set.  An unknown escape
+// sequence is kept as it is.
+func dotenv_expand(value string, escapes bool, lookup func(name string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if escapes && c == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(value[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		if c == '$' && strings.HasPrefix(value[i+1:], "{") {
+			if end := strings.IndexByte(value[i+2:], '}'); end >= 0 {
+				b.WriteString(lookup(value[i+2 : i+2+end]))
+				i += 2 + end
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// ----------------------------------------------------------------------------
+// Desktop entry locales and lists
+
+var desktop_locale_key = regexp.MustCompile(`^(.+)\[([^\[\]]+)\]$`)
+
+// desktop_locale groups the value of a key of a desktop entry file with the
+// values of the same key in other locales, and reports whether it did.  A
+// Name[de] key sets the "de" element of the map of Name, and a Name key
+// sets its "" element once Name is a map.
+func (p *parser) desktop_locale(parentNode *node, keyNode *node, valueNode *node) bool {
+	nameNode, localeNode := *keyNode, *keyNode
+	localeNode.value, localeNode.tag = "", ini_STR_TAG
+	if m := desktop_locale_key.FindStringSubmatch(keyNode.value); m != nil {
+		nameNode.value, localeNode.value = m[1], m[2]
+	}
+	groupNode := p.find_child(parentNode, nameNode.value)
+	if groupNode == nil || groupNode.tag != ini_LOCALE_TAG {
+		if localeNode.value == "" {
+			return false
+		}
+		// A localized value turns the key into a map.
+		unlocalizedNode := groupNode
+		groupNode = &node{kind: mappingNode, tag: ini_LOCALE_TAG, line: keyNode.line, column: keyNode.column}
+		if unlocalizedNode != nil {
+			groupNode.children = append(groupNode.children,
+				&node{kind: scalarNode, tag: ini_STR_TAG, line: unlocalizedNode.line, column: unlocalizedNode.column}, unlocalizedNode)
+		}
+		p.set_child(parentNode, &nameNode, groupNode)
+	}
+	p.set_child(groupNode, &localeNode, valueNode)
+	return true
+}
+
+// desktop_locales returns the locales that locale matches, from the best
+// to the worst match: lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER,
+// lang and the empty locale.  The encoding of locale is ignored.
+func desktop_locales(locale string) []string {
+	if locale == "" {
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if locale = os.Getenv(name); locale != "" {
+				break
+			}
+		}
+	}
+	var modifier string
+	if i := strings.IndexByte(locale, '@'); i >= 0 {
+		locale, modifier = locale[:i], locale[i:]
+	}
+	if i := strings.IndexByte(locale, '.'); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return []string{""}
+	}
+	var locales []string
+	lang := locale
+	if i := strings.IndexByte(locale, '_'); i >= 0 {
+		lang = locale[:i]
+		if modifier != "" {
+			locales = append(locales, locale+modifier)
+		}
+		locales = append(locales, locale)
+	}
+	if modifier != "" {
+		locales = append(locales, lang+modifier)
+	}
+	return append(locales, lang, "")
+}
+
+// desktop_unescape replaces the escape sequences of a value of a desktop
+// entry file.  An unknown escape sequence is kept as it is.
+func desktop_unescape(value string) string {
+	if strings.IndexByte(value, '\\') < 0 {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\', ';':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// desktop_split splits a list value of a desktop entry file at the ';'
+// characters that are not escaped.  The ';' after the last element is
+// optional, and the elements keep their escape sequences.
+func desktop_split(value string) []string {
+	var elements []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ';':
+			elements = append(elements, value[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(value) {
+		elements = append(elements, value[start:])
+	}
+	return elements
+}
+
+// desktop_escape escapes a value of a desktop entry file, and its ';'
+// characters too if it is an element of a list.
+func desktop_escape(value string, element bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == ' ' && i == 0:
+			b.WriteString(`\s`)
+		case c == ';' && element:
+			b.WriteString(`\;`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ----------------------------------------------------------------------------
+// Python defaults and interpolation
+
+// python_value lowercases the key of a value of a Python file, and marks
+// an empty value as a string, as configparser reads it.
+func (p *parser) python_value(keyNode *node, valueNode *node) {
+	keyNode.value = strings.ToLower(keyNode.value)
+	if valueNode.kind == scalarNode && valueNode.value == "" {
+		valueNode.tag = ini_STR_TAG
+	}
+}
+
+// python_sections adds the keys of the [DEFAULT] section of a Python file
+// to every other section that lacks them, and then replaces the references
+// to other values in the values of every section.
+func (p *parser) python_sections() {
+	var sectionNodes []*node
+	var defaultNode *node
+	for i := 0; i < len(p.doc.children); i += 2 {
+		if p.doc.children[i+1].kind != sectionNode {
+			continue
+		}
+		if p.doc.children[i].value == PYTHON_DEFAULT_SECTION {
+			defaultNode = p.doc.children[i+1]
+		}
+		sectionNodes = append(sectionNodes, p.doc.children[i], p.doc.children[i+1])
+	}
+	if defaultNode != nil {
+		for i := 0; i < len(sectionNodes); i += 2 {
+			sectionNode := sectionNodes[i+1]
+			if sectionNode == defaultNode {
+				continue
+			}
+			for j := 0; j < len(defaultNode.children); j += 2 {
+				if p.find_child(sectionNode, defaultNode.children[j].value) == nil {
+					sectionNode.children = append(sectionNode.children,
+						p.clone_node(defaultNode.children[j]), p.clone_node(defaultNode.children[j+1]))
+				}
+			}
+		}
+	}
+	if p.opts.Interpolation == InterpolationNone {
+		return
+	}
+
+	// Every value refers to the values as they were read.
+	values := make(map[string]map[string]string)
+	for i := 0; i < len(sectionNodes); i += 2 {
+		options := make(map[string]string)
+		for j := 0; j < len(sectionNodes[i+1].children); j += 2 {
+			if valueNode := sectionNodes[i+1].children[j+1]; valueNode.kind == scalarNode {
+				options[sectionNodes[i+1].children[j].value] = valueNode.value
+			}
+		}
+		values[sectionNodes[i].value] = options
+	}
+	for i := 0; i < len(sectionNodes); i += 2 {
+		section := sectionNodes[i].value
+		for j := 0; j < len(sectionNodes[i+1].children); j += 2 {
+			keyNode, valueNode := sectionNodes[i+1].children[j], sectionNodes[i+1].children[j+1]
+			if valueNode.kind != scalarNode {
+				continue
+			}
+			value, problem := p.python_interpolate(values, section, keyNode.value, valueNode.value, 1)
+			if problem != "" {
+				p.problem(valueNode, problem)
+				continue
+			}
+			valueNode.value = value
+		}
+	}
+}
+
+// maxInterpolationDepth is the depth of references after which a value of
+// a Python file is taken for referring to itself, as configparser's
+// MAX_INTERPOLATION_DEPTH.
+const maxInterpolationDepth = 10
+
+// python_interpolate replaces the references to other values in the value
+// of the key option of section, and returns the value, or the problem with
+// it.
+func (p *parser) python_interpolate(values map[string]map[string]string, section, option, value string, depth int) (string, string) {
+	if depth > maxInterpolationDepth {
+		return "", fmt.Sprintf("option '%s' in section '%s' refers to itself through too many references", option, section)
+	}
+	indicator, open, close := byte('%'), byte('('), ")s"
+	if p.opts.Interpolation == InterpolationExtended {
+		indicator, open, close = '$', '{', "}"
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != indicator {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == indicator {
+			b.WriteByte(indicator)
+			i++
+			continue
+		}
+		end := strings.Index(value[i+1:], close)
+		if i+1 == len(value) || value[i+1] != open || end < 0 {
+			return "", fmt.Sprintf("'%c' must be followed by '%c' or '%c' in option '%s' in section '%s'", indicator, indicator, open, option, section)
+		}
+		reference := value[i+2 : i+1+end]
+		i += end + len(close)
+		refSection, refOption := section, reference
+		if p.opts.Interpolation == InterpolationExtended {
+			if k := strings.IndexByte(reference, ':'); k >= 0 {
+				refSection, refOption = reference[:k], reference[k+1:]
+			}
+		}
+		refOption = strings.ToLower(refOption)
+		refValue, ok := values[refSection][refOption]
+		if !ok {
+			return "", fmt.Sprintf("option '%s' in section '%s' refers to the unknown option '%s'", option, section, reference)
+		}
+		refValue, problem := p.python_interpolate(values, refSection, refOption, refValue, depth+1)
+		if problem != "" {
+			return "", problem
+		}
+		b.WriteString(refValue)
+	}
+	return b.String(), ""
+}
@@ -0,0 +1,258 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// Added is a key that only the second document holds.
+	Added ChangeKind = iota
+
+	// Removed is a key that only the first document holds.
+	Removed
+
+	// Changed is a key that the documents hold with different values.
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A Change is a key whose value differs between two documents.  The values
+// are decoded as Unmarshal decodes them into an interface{}, and the value
+// of a key that appears several times, in git and systemd files, is the
+// []interface{} of its values.
+type Change struct {
+	Kind ChangeKind
+
+	// Section is the name of the section of the key, or "" for the default
+	// section.  Key is the name of the key, with the names of the
+	// subsections and the dotted keys above it, as in "origin.url".
+	Section, Key string
+
+	// OldValue and OldLine are the value and the line, from 1, of the key in
+	// the first document, and NewValue and NewLine in the second one.  The
+	// line is 0 where the document lacks the key.
+	OldValue, NewValue interface{}
+	OldLine, NewLine   int
+}
+
+// Path returns the path of the key, as Get names it.
+func (c Change) Path() string {
+	if c.Section == "" {
+		return c.Key
+	}
+	return c.Section + "." + c.Key
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s = %s (line %d)", c.Path(), diff_format(c.NewValue), c.NewLine)
+	case Removed:
+		return fmt.Sprintf("- %s = %s (line %d)", c.Path(), diff_format(c.OldValue), c.OldLine)
+	}
+	return fmt.Sprintf("~ %s = %s (line %d) -> %s (line %d)",
+		c.Path(), diff_format(c.OldValue), c.OldLine, diff_format(c.NewValue), c.NewLine)
+}
+
+// diff_format formats a value of a Change.
+func diff_format(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "''"
+	case []interface{}:
+		values := make([]string, len(value))
+		for i, v := range value {
+			values[i] = diff_format(v)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	return fmt.Sprintf("'%v'", value)
+}
+
+// DiffOptions changes how Diff compares documents.  The zero value compares
+// documents of the default dialect by their decoded values.
+type DiffOptions struct {
+	// Load reads both documents.
+	Load LoadOptions
+
+	// OldPath and NewPath are the paths of the first and of the second
+	// document, which take the place of the Path of Load, so that the
+	// files that each document includes are found from its own path.
+	OldPath, NewPath string
+}
+
+// Diff compares the effective values of two INI documents: the keys of
+// every section, with the keys that it inherits and the keys of the
+// sections that are merged into it, as Unmarshal reads them.  The layout,
+// the comments and the order of the keys do not matter.
+//
+// Values are compared as Unmarshal decodes them into an interface{}, so
+// that on and true, or 8080 and +8080, are the same value.  With the
+// RawValues option of Load, values are compared as the text that they are.
+//
+// The changes follow the order of the first document, and the keys that
+// only the second document holds come after the keys of their section, or
+// at the end for the sections that only the second document holds.
+func Diff(a, b []byte, opts DiffOptions) (changes []Change, err error) {
+	load := opts.Load
+	load.Path = opts.OldPath
+	oldSections, err := diff_document(a, load)
+	if err != nil {
+		return nil, err
+	}
+	load.Path = opts.NewPath
+	newSections, err := diff_document(b, load)
+	if err != nil {
+		return nil, err
+	}
+	for _, oldSection := range oldSections {
+		newSection := diff_find(newSections, oldSection.id)
+		if newSection == nil {
+			newSection = &diff_section{}
+		}
+		for _, oldKey := range oldSection.keys {
+			c := Change{Kind: Removed, Section: oldSection.name, Key: oldKey.name, OldValue: oldKey.value, OldLine: oldKey.line}
+			if newKey := diff_find_key(newSection.keys, oldKey.id); newKey != nil {
+				if reflect.DeepEqual(oldKey.value, newKey.value) {
+					continue
+				}
+				c.Kind, c.NewValue, c.NewLine = Changed, newKey.value, newKey.line
+			}
+			changes = append(changes, c)
+		}
+		changes = diff_added(changes, oldSection, newSection)
+	}
+	for _, newSection := range newSections {
+		if diff_find(oldSections, newSection.id) == nil {
+			changes = diff_added(changes, &diff_section{}, newSection)
+		}
+	}
+	return changes, nil
+}
+
+// diff_added adds the keys of a section of the second document that the
+// section of the first document lacks.
+func diff_added(changes []Change, oldSection, newSection *diff_section) []Change {
+	for _, newKey := range newSection.keys {
+		if diff_find_key(oldSection.keys, newKey.id) == nil {
+			changes = append(changes, Change{Kind: Added, Section: newSection.name, Key: newKey.name, NewValue: newKey.value, NewLine: newKey.line})
+		}
+	}
+	return changes
+}
+
+// A diff_section is a section of a compared document, with its keys.  The
+// id of a section or a key is its name as names are matched, regardless of
+// case in case-insensitive documents.
+type diff_section struct {
+	name, id string
+	keys     []*diff_key
+}
+
+// A diff_key is a key of a compared document and its decoded value.
+type diff_key struct {
+	name, id string
+	value    interface{}
+	line     int
+}
+
+// diff_find returns the section with the id, or nil.
+func diff_find(sections []*diff_section, id string) *diff_section {
+	for _, section := range sections {
+		if section.id == id {
+			return section
+		}
+	}
+	return nil
+}
+
+// diff_find_key returns the key with the id, or nil.
+func diff_find_key(keys []*diff_key, id string) *diff_key {
+	for _, key := range keys {
+		if key.id == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// diff_document reads the sections of a document and their keys, with the
+// keys that they inherit.  A section that appears several times, and that
+// the dialect does not merge, is read from its last header, in the place
+// of its first one.
+func diff_document(in []byte, opts LoadOptions) (sections []*diff_section, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	d := newDecoder(opts)
+	p := newParser(in, opts)
+	defer p.destroy()
+	doc := p.parse()
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(doc.children); i += 2 {
+		name := doc.children[i].value
+		if name == DEFAULT_SECTION {
+			name = ""
+		}
+		keys := p.diff_keys(d, nil, "", doc.children[i+1])
+		if section := diff_find(sections, p.diff_id(name)); section != nil {
+			// The last of the sections with the same name is the one that
+			// Unmarshal reads.
+			section.name, section.keys = name, keys
+			continue
+		}
+		sections = append(sections, &diff_section{name: name, id: p.diff_id(name), keys: keys})
+	}
+	if len(d.terrors) > 0 {
+		return nil, &TypeError{d.terrors}
+	}
+	return sections, nil
+}
+
+// diff_keys adds the keys of a section, a subsection or a map of dotted
+// keys, with the names of the maps above them joined by dots.
+func (p *parser) diff_keys(d *decoder, keys []*diff_key, prefix string, n *node) []*diff_key {
+	for i := 0; i+1 < len(n.children); i += 2 {
+		keyNode, valueNode := n.children[i], n.children[i+1]
+		name := prefix + keyNode.value
+		if valueNode.kind == sectionNode || valueNode.kind == mappingNode {
+			keys = p.diff_keys(d, keys, name+".", valueNode)
+			continue
+		}
+		var value interface{}
+		d.unmarshal(valueNode, reflect.ValueOf(&value).Elem())
+		keys = append(keys, &diff_key{name: name, id: p.diff_id(name), value: value, line: keyNode.line + 1})
+	}
+	return keys
+}
+
+// diff_id returns the name that a section or a key is matched by.
+func (p *parser) diff_id(name string) string {
+	if p.opts.Dialect == DialectMySQL {
+		name = fold_dashes(name)
+	}
+	if p.insensitive {
+		name = strings.ToLower(name)
+	}
+	return name
+}
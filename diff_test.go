@@ -0,0 +1,122 @@
+package ini_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var diffOld = `name = app
+
+[base]
+host = localhost
+port = 8080
+debug = on
+
+[prod : base]
+host = example.com
+
+[old]
+x = 1
+`
+
+var diffNew = `name = app
+[base]
+port = +8080
+debug = true
+host = localhost
+timeout = 30
+[prod : base]
+host = prod.example.com
+[new]
+y = 2
+`
+
+var diffTests = []struct {
+	a, b    string
+	opts    ini.DiffOptions
+	changes []string
+}{
+	{diffOld, diffOld, ini.DiffOptions{}, nil},
+	{"[s]\nx = 1\n[s]\nx = 2\n", "[s]\nx = 2\n", ini.DiffOptions{}, nil},
+	{"[s]\nx = 1\n[t]\n[s]\nx = 2\n", "[s]\nx = 1\n", ini.DiffOptions{}, []string{"~ s.x = '2' (line 5) -> '1' (line 2)"}},
+	{
+		diffOld, diffNew, ini.DiffOptions{},
+		[]string{
+			"+ base.timeout = '30' (line 6)",
+			"~ prod.host = 'example.com' (line 9) -> 'prod.example.com' (line 8)",
+			"+ prod.timeout = '30' (line 6)",
+			"- old.x = '1' (line 12)",
+			"- old.name = 'app' (line 1)",
+			"+ new.y = '2' (line 10)",
+			"+ new.name = 'app' (line 1)",
+		},
+	}, {
+		"[s]\nport = 8080\nflag = on\n", "[s]\nflag = true\nport = +8080\n", ini.DiffOptions{Load: ini.LoadOptions{RawValues: true}},
+		[]string{
+			"~ s.port = '8080' (line 2) -> '+8080' (line 3)",
+			"~ s.flag = 'on' (line 3) -> 'true' (line 2)",
+		},
+	}, {
+		"[S]\nKey = a\n", "[s]\nkey = b\n", ini.DiffOptions{Load: ini.LoadOptions{Insensitive: true}},
+		[]string{"~ S.Key = 'a' (line 2) -> 'b' (line 2)"},
+	}, {
+		"[remote \"origin\"]\n\turl = a\n\tfetch = x\n", "[remote \"origin\"]\n\turl = a\n\tfetch = x\n\tfetch = z\n",
+		ini.DiffOptions{Load: ini.LoadOptions{Dialect: ini.DialectGit}},
+		[]string{"~ remote.origin.fetch = 'x' (line 3) -> ['x', 'z'] (line 3)"},
+	},
+}
+
+func (s *S) TestDiff(c *C) {
+	for _, item := range diffTests {
+		changes, err := ini.Diff([]byte(item.a), []byte(item.b), item.opts)
+		c.Assert(err, IsNil)
+		var lines []string
+		for _, change := range changes {
+			lines = append(lines, change.String())
+		}
+		c.Assert(lines, DeepEquals, item.changes, Commentf("a: %q b: %q", item.a, item.b))
+	}
+}
+
+func (s *S) TestDiffChange(c *C) {
+	changes, err := ini.Diff([]byte("a = 1\n[s]\nk = v\n"), []byte("a = 2\n"), ini.DiffOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(changes, DeepEquals, []ini.Change{
+		{Kind: ini.Changed, Key: "a", OldValue: 1, NewValue: 2, OldLine: 1, NewLine: 1},
+		{Kind: ini.Removed, Section: "s", Key: "k", OldValue: "v", OldLine: 3},
+		{Kind: ini.Removed, Section: "s", Key: "a", OldValue: 1, OldLine: 1},
+	})
+	c.Assert(changes[0].Path(), Equals, "a")
+	c.Assert(changes[1].Path(), Equals, "s.k")
+	c.Assert(changes[1].Kind.String(), Equals, "removed")
+}
+
+func (s *S) TestDiffPaths(c *C) {
+	dir := c.MkDir()
+	oldFile := filepath.Join(dir, "old", "x.service")
+	newFile := filepath.Join(dir, "new", "x.service")
+	files := map[string]string{
+		oldFile:                               "[Service]\nUser=a\n",
+		newFile:                               "[Service]\nUser=a\n",
+		filepath.Join(newFile+".d", "o.conf"): "[Service]\nUser=b\n",
+	}
+	for name, data := range files {
+		c.Assert(os.MkdirAll(filepath.Dir(name), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(name, []byte(data), 0644), IsNil)
+	}
+	opts := ini.DiffOptions{Load: ini.LoadOptions{Dialect: ini.DialectSystemd}, OldPath: oldFile, NewPath: newFile}
+	changes, err := ini.Diff([]byte(files[oldFile]), []byte(files[newFile]), opts)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].String(), Equals, "~ Service.User = 'a' (line 2) -> ['a', 'b'] (line 2)")
+}
+
+func (s *S) TestDiffErrors(c *C) {
+	_, err := ini.Diff([]byte("a = 1\n"), []byte("[a] x\n"), ini.DiffOptions{})
+	c.Assert(err, ErrorMatches, "ini: must have a line break before the first section key")
+}
@@ -0,0 +1,560 @@
+package ini
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A NotFoundError is returned by Get, GetAll and Delete when a document has
+// no key at the path.
+type NotFoundError struct {
+	Path string
+}
+
+func (e *NotFoundError) Error() string {
+	return "ini: key '" + e.Path + "' not found"
+}
+
+// Get returns the value of the key at path in an INI document, as it is
+// written, without its quotes and escapes.  Values that Unmarshal reads as
+// null or as booleans, such as "null" or "yes", are returned as they are
+// written.  The path is the name of the section and the name of the key
+// joined by a dot, such as "server.port", or the name of the key alone for
+// a key of the default section.  Subsections, nested sections and dotted
+// keys add their names to the path, as in "remote.origin.url".
+//
+// Sections hold the keys they inherit, from [child:parent] headers and from
+// the default section, as they do with Unmarshal.  A section that appears
+// several times, and that the dialect does not merge, is read from its last
+// header, as Unmarshal reads it.  Of the values of a key that appears
+// several times, in git and systemd files, Get returns the last one.
+func Get(in []byte, path string, opts LoadOptions) (string, error) {
+	values, err := GetAll(in, path, opts)
+	if err != nil {
+		return "", err
+	}
+	return values[len(values)-1], nil
+}
+
+// GetAll is like Get, but returns every value of a key that appears several
+// times.
+func GetAll(in []byte, path string, opts LoadOptions) (values []string, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	p := newParser(in, opts)
+	defer p.destroy()
+	var n *node
+	if doc := p.parse(); doc != nil {
+		n = p.resolve(doc, path)
+	}
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	if n == nil {
+		return nil, &NotFoundError{path}
+	}
+	if !is_value(n) {
+		failf("'%s' is a section or a map of keys, not a key", path)
+	}
+	valueNodes := []*node{n}
+	if n.kind == sequenceNode {
+		valueNodes = n.children
+	}
+	for _, valueNode := range valueNodes {
+		values = append(values, valueNode.value)
+	}
+	return values, nil
+}
+
+// resolve returns the value of the key at path in the document, in its
+// section or else in the default section, or nil.
+func (p *parser) resolve(doc *node, path string) *node {
+	n := p.lookup(doc, path)
+	if n == nil || !is_value(n) {
+		if defaultNode := p.find_last(doc, DEFAULT_SECTION); defaultNode != nil {
+			if found := p.lookup(defaultNode, path); found != nil {
+				n = found
+			}
+		}
+	}
+	return n
+}
+
+// lookup returns the value at a dotted path in the document, a section or
+// a map of dotted keys, or nil.  A name that holds dots itself, such as the
+// name of a key that is never split at dots, is matched whole.  Of the
+// sections with the same name, only the last is read, as the decoder reads
+// them.
+func (p *parser) lookup(n *node, path string) *node {
+	if found := p.find_last(n, path); found != nil {
+		return found
+	}
+	var seen []string
+	for i := len(n.children) - 2; i >= 0; i -= 2 {
+		name, valueNode := n.children[i].value, n.children[i+1]
+		if p.seen(seen, name) {
+			continue
+		}
+		seen = append(seen, name)
+		if valueNode.kind != sectionNode && valueNode.kind != mappingNode {
+			continue
+		}
+		if len(path) > len(name) && path[len(name)] == '.' && p.match(name, path[:len(name)]) {
+			if found := p.lookup(valueNode, path[len(name)+1:]); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// find_last returns the value of the last child of a section or the
+// document with the name, or nil.
+func (p *parser) find_last(parentNode *node, name string) *node {
+	for i := len(parentNode.children) - 2; i >= 0; i -= 2 {
+		if parentNode.children[i].kind == scalarNode && p.match(parentNode.children[i].value, name) {
+			return parentNode.children[i+1]
+		}
+	}
+	return nil
+}
+
+// seen reports whether a name is one of the names.
+func (p *parser) seen(names []string, name string) bool {
+	for _, other := range names {
+		if p.match(other, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set sets the value of the key at path, named as with Get, and returns the
+// edited document.  Only the value changes, so that the layout and the
+// comments of the document are kept.  A key that the section lacks, even
+// when it inherits it, is added below the last key of the section, and a
+// section that the document lacks is added at its end.
+//
+// The line of the key that Get reads gets the value, and the other lines
+// of the key, which Get does not read, are removed.  A key that appears
+// several times, in git and systemd files, keeps only its last line.
+func Set(in []byte, path, value string, opts LoadOptions) (out []byte, err error) {
+	defer handleErr(&err)
+	e := newEditor(in, opts)
+	entries := e.find(path)
+	effective := e.effective(path)
+	if len(effective) > 0 {
+		e.replace(effective[len(effective)-1], value)
+	} else {
+		e.add(path, value)
+	}
+	for _, ent := range entries {
+		if len(effective) == 0 || ent != effective[len(effective)-1] {
+			e.remove(ent)
+		}
+	}
+	return e.apply(), nil
+}
+
+// Delete removes the lines of the key at path, named as with Get, and
+// returns the edited document.  Every line of a key that appears several
+// times is removed.  A section still holds the keys it inherits after
+// they are deleted from it, and it is an error to delete a key that a
+// section only inherits.
+func Delete(in []byte, path string, opts LoadOptions) (out []byte, err error) {
+	defer handleErr(&err)
+	e := newEditor(in, opts)
+	if len(e.effective(path)) == 0 {
+		if _, err := GetAll(in, path, opts); err == nil {
+			failf("key '%s' is inherited, so it cannot be deleted from its section", path)
+		}
+		return nil, &NotFoundError{path}
+	}
+	for _, ent := range e.find(path) {
+		e.remove(ent)
+	}
+	return e.apply(), nil
+}
+
+// ----------------------------------------------------------------------------
+// Editor, edits a document in place.
+
+// editor edits the text of a document, at the places that the events of
+// the parser mark.
+type editor struct {
+	p        *parser
+	in       []byte
+	opts     LoadOptions
+	crlf     bool
+	bom      int   // the length of the byte order mark
+	offsets  []int // the byte offset of each character
+	sections []edit_section
+	edits    []edit
+}
+
+// edit_section is a section of a document being edited.  The names of the
+// default section are empty, unless it has a header.
+type edit_section struct {
+	names   []string
+	start   int // the start of the name in the header, or of the first key
+	end     int // the end of the header, or of the value of the last key
+	entries []edit_entry
+}
+
+// edit_entry is a key of a document being edited.
+type edit_entry struct {
+	path         string // the names of the section and of the key
+	key, key_end int
+	start, end   int // the value
+	line, column int // the mark of the value, as the nodes hold it
+}
+
+// edit replaces the text between two offsets.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+// newEditor reads the sections and keys of a document.  Only documents in
+// UTF-8 and ISO-8859-1 may be edited, whose characters can be found back.
+func newEditor(in []byte, opts LoadOptions) *editor {
+	opts = opts.preset()
+	e := &editor{in: in, opts: opts, crlf: bytes.Contains(in, []byte("\r\n"))}
+	if opts.Encoding == EncodingLatin1 {
+		for i := range in {
+			e.offsets = append(e.offsets, i)
+		}
+	} else {
+		if opts.Encoding != EncodingAuto && opts.Encoding != EncodingUTF8 || bytes.IndexByte(in, 0) >= 0 || !utf8.Valid(in) {
+			failf("cannot edit a document that is not in UTF-8")
+		}
+		if bytes.HasPrefix(in, []byte("\xef\xbb\xbf")) {
+			e.bom = 3
+		}
+		for i := e.bom; i < len(in); {
+			e.offsets = append(e.offsets, i)
+			_, size := utf8.DecodeRune(in[i:])
+			i += size
+		}
+	}
+	e.offsets = append(e.offsets, len(in))
+
+	e.p = newParser(in, opts)
+	defer e.p.destroy()
+	p := e.p
+	p.skip()
+	for p.event.typ != ini_DOCUMENT_END_EVENT {
+		s := edit_section{start: e.offset(p.event.start_mark)}
+		names, _ := e.names()
+		if p.event.typ == ini_SECTION_INHERIT_EVENT {
+			s.names = names
+			s.end = e.offset(p.event.end_mark)
+			p.skip()
+		} else {
+			s.end = s.start
+		}
+		if p.event.typ != ini_SECTION_ENTRY_EVENT {
+			failf("expected the end of a section header, got %s", p.event.event_type())
+		}
+		if s.names != nil {
+			s.end = e.offset(p.event.end_mark)
+		}
+		p.skip()
+		for p.event.typ != ini_SECTION_ENTRY_EVENT {
+			ent := edit_entry{key: e.offset(p.event.start_mark)}
+			var keys []string
+			keys, ent.key_end = e.names()
+			ent.path = strings.Join(append(s.names[:len(s.names):len(s.names)], keys...), ".")
+			if p.event.typ != ini_SCALAR_EVENT {
+				failf("expected the value of key '%s', got %s", ent.path, p.event.event_type())
+			}
+			ent.start = e.offset(p.event.start_mark)
+			ent.line, ent.column = p.event.start_mark.line, p.event.start_mark.column
+			ent.end = e.offset(p.event.end_mark)
+			for ent.end > ent.start && (in[ent.end-1] == ' ' || in[ent.end-1] == '\t') {
+				ent.end--
+			}
+			s.entries = append(s.entries, ent)
+			s.end = ent.end
+			p.skip()
+		}
+		e.sections = append(e.sections, s)
+		p.skip()
+	}
+	return e
+}
+
+// names reads the name of a section or a key, with the names of its
+// subsections or its dotted keys, with the offset of the end of the last
+// name, and stops at the event after them.
+func (e *editor) names() (names []string, end int) {
+	p := e.p
+	for {
+		if p.event.typ != ini_SCALAR_EVENT {
+			failf("expected a name, got %s", p.event.event_type())
+		}
+		names = append(names, string(p.event.value))
+		end = e.offset(p.event.end_mark)
+		p.skip()
+		if p.event.typ != ini_MAPPING_EVENT {
+			return names, end
+		}
+		p.skip()
+	}
+}
+
+// offset returns the byte offset of a mark.
+func (e *editor) offset(mark ini_mark_t) int {
+	if mark.index >= len(e.offsets) {
+		return len(e.in)
+	}
+	return e.offsets[mark.index]
+}
+
+// line_start returns the offset of the start of the line of an offset.
+func (e *editor) line_start(offset int) int {
+	for offset > e.bom && e.in[offset-1] != '\n' && e.in[offset-1] != '\r' {
+		offset--
+	}
+	return offset
+}
+
+// line_end returns the offset of the start of the line after an offset.
+func (e *editor) line_end(offset int) int {
+	for offset < len(e.in) && e.in[offset] != '\n' && e.in[offset] != '\r' {
+		offset++
+	}
+	if offset < len(e.in) && e.in[offset] == '\r' {
+		offset++
+	}
+	if offset < len(e.in) && e.in[offset] == '\n' {
+		offset++
+	}
+	return offset
+}
+
+// find returns the keys at a path, in the order of the document.
+func (e *editor) find(path string) []edit_entry {
+	var entries []edit_entry
+	for _, s := range e.sections {
+		for _, ent := range s.entries {
+			if e.p.match(ent.path, path) {
+				entries = append(entries, ent)
+			}
+		}
+	}
+	return entries
+}
+
+// effective returns the keys at a path whose values Get reads, in the order
+// of the document.  Values that the section inherits, or that are read from
+// other files, are not keys of the path in the document, and are left out.
+func (e *editor) effective(path string) []edit_entry {
+	p := newParser(e.in, e.opts)
+	defer p.destroy()
+	var n *node
+	if doc := p.parse(); doc != nil {
+		n = p.resolve(doc, path)
+	}
+	if len(p.parser.errors) > 0 {
+		fail(&ParseError{p.errors()})
+	}
+	if n == nil || !is_value(n) {
+		return nil
+	}
+	valueNodes := []*node{n}
+	if n.kind == sequenceNode {
+		valueNodes = n.children
+	}
+	var entries []edit_entry
+	for _, ent := range e.find(path) {
+		for _, valueNode := range valueNodes {
+			if valueNode.file == e.opts.Path && valueNode.line == ent.line && valueNode.column == ent.column {
+				entries = append(entries, ent)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// replace replaces the value of a key.
+func (e *editor) replace(ent edit_entry, value string) {
+	var names []string
+	if !e.opts.Dialect.sectionless() {
+		names = []string{"section"}
+	}
+	r := e.render(names, "key", value)
+	rendered := r.sections[len(r.sections)-1].entries[0]
+	text := r.in[rendered.start:rendered.end]
+	if ent.start == ent.key_end {
+		// A key without a value, such as a boolean key of a git file.
+		text = r.in[rendered.key_end:rendered.end]
+	} else if ent.start == ent.end && ent.start > 0 && e.in[ent.start-1] != ' ' && e.in[ent.start-1] != '\t' {
+		// An empty value right after the delimiter.
+		delimiter := r.in[rendered.key_end:rendered.start]
+		text = append(delimiter[len(bytes.TrimRight(delimiter, " \t")):len(delimiter):len(delimiter)], text...)
+	}
+	e.edits = append(e.edits, edit{ent.start, ent.end, text})
+}
+
+// remove removes the lines of a key.
+func (e *editor) remove(ent edit_entry) {
+	e.edits = append(e.edits, edit{e.line_start(ent.key), e.line_end(ent.end), nil})
+}
+
+// add adds a key that the document lacks to the last section whose names
+// start the path, the longest names first, or else to a new section.
+func (e *editor) add(path, value string) {
+	var target *edit_section
+	var key, targetName string
+	for i := range e.sections {
+		s := &e.sections[i]
+		name := strings.Join(s.names, ".")
+		var rest string
+		switch {
+		case s.names == nil:
+			if strings.Contains(path, ".") && !e.opts.Dialect.sectionless() {
+				continue
+			}
+			rest = path
+		case len(path) > len(name) && path[len(name)] == '.' && e.p.match(name, path[:len(name)]):
+			rest = path[len(name)+1:]
+		default:
+			continue
+		}
+		if target == nil || len(name) >= len(targetName) {
+			target, key, targetName = s, rest, name
+		}
+	}
+	if target == nil {
+		e.add_section(path, value)
+		return
+	}
+
+	var names []string
+	if !e.opts.Dialect.sectionless() {
+		names = []string{"section"}
+	}
+	r := e.render(names, key, value)
+	rendered := r.sections[len(r.sections)-1].entries[0]
+	line := r.in[r.line_start(rendered.key):r.line_end(rendered.end)]
+	if n := len(target.entries); n > 0 {
+		// Indent the key as the key above it.
+		last := target.entries[n-1]
+		indent := e.in[e.line_start(last.key):last.key]
+		if len(bytes.Trim(indent, " \t")) == 0 {
+			line = append(append([]byte{}, indent...), bytes.TrimLeft(line, " \t")...)
+		}
+	}
+	at := e.line_end(target.end)
+	e.insert(at, line)
+}
+
+// add_section adds a key in a section that the document lacks.  A key of
+// the default section goes above the first section, and any other at the
+// end of the document.
+func (e *editor) add_section(path, value string) {
+	var names []string
+	key := path
+	if i := strings.IndexByte(path, '.'); i >= 0 && !e.opts.Dialect.sectionless() {
+		j := strings.LastIndexByte(path, '.')
+		switch {
+		case e.opts.Dialect == DialectGit && i < j:
+			names, key = []string{path[:i], path[i+1 : j]}, path[j+1:]
+		case e.opts.NestedSections:
+			names, key = strings.Split(path[:j], "."), path[j+1:]
+		default:
+			names, key = []string{path[:i]}, path[i+1:]
+		}
+	} else if e.opts.Dialect.sectioned() {
+		failf("key '%s' is not in a section", path)
+	}
+	text := e.render(names, key, value).in
+	if names == nil && len(e.sections) > 0 {
+		e.insert(e.line_start(e.sections[0].start), append(text, '\n'))
+		return
+	}
+	end := len(e.in)
+	switch {
+	case end == e.bom:
+	case e.in[end-1] != '\n' && e.in[end-1] != '\r':
+		text = append([]byte("\n\n"), text...)
+	case !bytes.HasSuffix(e.in, []byte("\n\n")) && !bytes.HasSuffix(e.in, []byte("\r\n\r\n")):
+		text = append([]byte("\n"), text...)
+	}
+	e.insert(end, text)
+}
+
+// insert inserts lines at an offset, with the line breaks of the document.
+// A line break ends the line before them when the document lacks it.
+func (e *editor) insert(at int, text []byte) {
+	if at == len(e.in) && at > e.bom && e.in[at-1] != '\n' && e.in[at-1] != '\r' && text[0] != '\n' {
+		text = append([]byte("\n"), text...)
+	}
+	text = bytes.Replace(text, []byte("\r\n"), []byte("\n"), -1)
+	if e.crlf {
+		text = bytes.Replace(text, []byte("\n"), []byte("\r\n"), -1)
+	}
+	e.edits = append(e.edits, edit{at, at, text})
+}
+
+// render marshals a key and its value in the syntax of the document, in the
+// section with the given names, or in the default section when there are
+// none, and reads the result back.  A value that would read as null or as
+// a boolean is quoted, when the dialect reads a quoted value as a string.
+func (e *editor) render(names []string, key, value string) *editor {
+	var doc interface{} = MapSlice{{Key: key, Value: value}}
+	if len(names) > 0 && e.opts.Dialect != DialectGit {
+		names = []string{strings.Join(names, ".")}
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		doc = MapSlice{{Key: names[i], Value: doc}}
+	}
+	out, err := MarshalWithOptions(doc, DumpOptions{Dialect: e.opts.Dialect, Encoding: e.opts.Encoding})
+	if err != nil {
+		fail(err)
+	}
+	r := newEditor(out, e.opts)
+	path := strings.Join(append(names[:len(names):len(names)], key), ".")
+	switch e.read(out, path).(type) {
+	case nil, bool:
+		rendered := r.sections[len(r.sections)-1].entries[0]
+		quoted := append(append(append([]byte{}, out[:rendered.start]...), '"'), value...)
+		quoted = append(append(quoted, '"'), out[rendered.end:]...)
+		if v, ok := e.read(quoted, path).(string); ok && v == value {
+			return newEditor(quoted, e.opts)
+		}
+	}
+	return r
+}
+
+// read returns the value at a path of a document, as Unmarshal decodes it
+// into an interface{}, or nil.
+func (e *editor) read(in []byte, path string) (value interface{}) {
+	p := newParser(in, e.opts)
+	defer p.destroy()
+	if doc := p.parse(); doc != nil {
+		if n := p.resolve(doc, path); n != nil && len(p.parser.errors) == 0 {
+			newDecoder(e.opts).unmarshal(n, reflect.ValueOf(&value).Elem())
+		}
+	}
+	return value
+}
+
+// apply returns the document with its edits, and checks that it can still
+// be read.
+func (e *editor) apply() []byte {
+	sort.SliceStable(e.edits, func(i, j int) bool {
+		return e.edits[i].start > e.edits[j].start
+	})
+	out := append([]byte{}, e.in...)
+	for _, ed := range e.edits {
+		out = append(out[:ed.start], append(append([]byte{}, ed.text...), out[ed.end:]...)...)
+	}
+	newEditor(out, e.opts)
+	return out
+}
@@ -0,0 +1,214 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var editDocument = `; the service
+name = app
+
+[base]
+host = localhost ; the host
+  port=8080
+
+# production
+[prod : base]
+host = "example.com"
+empty =
+`
+
+var getTests = []struct {
+	data   string
+	path   string
+	opts   ini.LoadOptions
+	values []string
+}{
+	{editDocument, "name", ini.LoadOptions{}, []string{"app"}},
+	{editDocument, "base.host", ini.LoadOptions{}, []string{"localhost"}},
+	{editDocument, "base.port", ini.LoadOptions{}, []string{"8080"}},
+	{editDocument, "prod.host", ini.LoadOptions{}, []string{"example.com"}},
+	{editDocument, "prod.port", ini.LoadOptions{}, []string{"8080"}},
+	{editDocument, "prod.name", ini.LoadOptions{}, []string{"app"}},
+	{editDocument, "prod.empty", ini.LoadOptions{}, []string{""}},
+	{editDocument, "PROD.Host", ini.LoadOptions{Insensitive: true}, []string{"example.com"}},
+	{"[a]\ndb.user = root\n", "a.db.user", ini.LoadOptions{}, []string{"root"}},
+	{"[a]\ndb.user = root\n", "a.db.user", ini.LoadOptions{FlatKeys: true}, []string{"root"}},
+	{
+		"[remote \"origin\"]\n\turl = u\n\tfetch = a\n\tfetch = b\n[core]\n\tbare\n",
+		"remote.origin.fetch",
+		ini.LoadOptions{Dialect: ini.DialectGit},
+		[]string{"a", "b"},
+	},
+	{"[core]\n\tbare\n", "core.bare", ini.LoadOptions{Dialect: ini.DialectGit}, []string{"true"}},
+	{"spring.datasource.url=jdbc\n", "spring.datasource.url", ini.LoadOptions{Dialect: ini.DialectProperties}, []string{"jdbc"}},
+	{"[s]\nx = 1\n[s]\nx = 2\n", "s.x", ini.LoadOptions{}, []string{"2"}},
+	{"[s]\nx = 1\nx = 2\n", "s.x", ini.LoadOptions{}, []string{"1"}},
+	{"k = null\nb = yes\n", "k", ini.LoadOptions{}, []string{"null"}},
+	{"k = null\nb = yes\n", "b", ini.LoadOptions{}, []string{"yes"}},
+	{"[s]\nx = 1\n[s]\nx = 2\n", "s.x", ini.LoadOptions{Dialect: ini.DialectGit}, []string{"1", "2"}},
+}
+
+func (s *S) TestGet(c *C) {
+	for _, item := range getTests {
+		values, err := ini.GetAll([]byte(item.data), item.path, item.opts)
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(values, DeepEquals, item.values, Commentf("path: %s", item.path))
+		value, err := ini.Get([]byte(item.data), item.path, item.opts)
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(value, Equals, item.values[len(item.values)-1], Commentf("path: %s", item.path))
+	}
+}
+
+func (s *S) TestGetErrors(c *C) {
+	_, err := ini.Get([]byte(editDocument), "prod.nope", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "prod.nope"})
+	c.Assert(err, ErrorMatches, "ini: key 'prod.nope' not found")
+	_, err = ini.Get([]byte(editDocument), "base", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: 'base' is a section or a map of keys, not a key")
+	_, err = ini.Get([]byte("[s]\nx = 1\n[s]\nz = 2\n"), "s.x", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "s.x"})
+	_, err = ini.Get([]byte("[a:b]\n"), "a.x", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: inherit section 'b' does not exists")
+}
+
+var setTests = []struct {
+	data  string
+	path  string
+	value string
+	opts  ini.LoadOptions
+	out   string
+}{
+	{
+		editDocument, "name", "my app", ini.LoadOptions{},
+		"; the service\nname = my app\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		editDocument, "base.host", "a ; b", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\nhost = \"a ; b\" ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		editDocument, "prod.empty", "full", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty = full\n",
+	}, {
+		editDocument, "prod.port", "443", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\nport = 443\n",
+	}, {
+		editDocument, "base.db.user", "root", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\nhost = localhost ; the host\n  port=8080\n  db.user = root\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		editDocument, "debug", "on", ini.LoadOptions{},
+		"; the service\nname = app\ndebug = on\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		editDocument, "dev.host", "dev", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n\n[dev]\nhost = dev\n",
+	}, {
+		"[a]\r\nk=1", "a.j", "2", ini.LoadOptions{},
+		"[a]\r\nk=1\r\nj = 2\r\n",
+	}, {
+		"[a]\nk = 1\n", "b.k", "2", ini.LoadOptions{},
+		"[a]\nk = 1\n\n[b]\nk = 2\n",
+	}, {
+		"", "a.k", "1", ini.LoadOptions{},
+		"[a]\nk = 1\n",
+	}, {
+		"\ufeff[a]\nk = é\n", "a.k", "ü", ini.LoadOptions{},
+		"\ufeff[a]\nk = ü\n",
+	}, {
+		"[core]\n\tbare\n[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n", "core.bare", "false", ini.LoadOptions{Dialect: ini.DialectGit},
+		"[core]\n\tbare = false\n[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n",
+	}, {
+		"[core]\n\tbare\n[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n", "remote.origin.fetch", "c", ini.LoadOptions{Dialect: ini.DialectGit},
+		"[core]\n\tbare\n[remote \"origin\"]\n\tfetch = c\n",
+	}, {
+		"[core]\n\tbare\n", "remote.origin.url", "git@host:repo", ini.LoadOptions{Dialect: ini.DialectGit},
+		"[core]\n\tbare\n\n[remote \"origin\"]\n\turl = git@host:repo\n",
+	}, {
+		"[s]\nk = one\n  two\nz = 1\n", "s.k", "a\nb", ini.LoadOptions{Dialect: ini.DialectPython},
+		"[s]\nk = a\n\tb\nz = 1\n",
+	}, {
+		"export A=1\nB='x'\n", "A", "it's", ini.LoadOptions{Dialect: ini.DialectDotenv},
+		"export A=\"it's\"\nB='x'\n",
+	}, {
+		"a.b=\xe9\n", "a.c", "\u00fc", ini.LoadOptions{Dialect: ini.DialectProperties},
+		"a.b=\xe9\na.c=\\u00fc\n",
+	}, {
+		"[s]\nx = 1\nx = 2\n", "s.x", "3", ini.LoadOptions{},
+		"[s]\nx = 3\n",
+	}, {
+		"[s]\nx = 1\n[s]\nx = 2\n", "s.x", "3", ini.LoadOptions{},
+		"[s]\n[s]\nx = 3\n",
+	}, {
+		"[s]\nx = 1\n[s]\ny = 2\n", "s.x", "3", ini.LoadOptions{},
+		"[s]\n[s]\ny = 2\nx = 3\n",
+	}, {
+		"[s]\nk = 1\n", "s.k", "null", ini.LoadOptions{},
+		"[s]\nk = null\n",
+	}, {
+		"[s]\nk = 1\n", "s.k", "null", ini.LoadOptions{Dialect: ini.DialectPHP},
+		"[s]\nk = \"null\"\n",
+	}, {
+		"[s]\nk = 1\n", "s.k", "off", ini.LoadOptions{Dialect: ini.DialectPHP},
+		"[s]\nk = \"off\"\n",
+	},
+}
+
+func (s *S) TestSet(c *C) {
+	for _, item := range setTests {
+		out, err := ini.Set([]byte(item.data), item.path, item.value, item.opts)
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(string(out), Equals, item.out, Commentf("path: %s", item.path))
+		value, err := ini.Get(out, item.path, item.opts)
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(value, Equals, item.value, Commentf("path: %s", item.path))
+	}
+}
+
+var deleteTests = []struct {
+	data string
+	path string
+	opts ini.LoadOptions
+	out  string
+}{
+	{
+		editDocument, "base.host", ini.LoadOptions{},
+		"; the service\nname = app\n\n[base]\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		editDocument, "name", ini.LoadOptions{},
+		"; the service\n\n[base]\nhost = localhost ; the host\n  port=8080\n\n# production\n[prod : base]\nhost = \"example.com\"\nempty =\n",
+	}, {
+		"[s]\nk = one\n  two\nz = 1", "s.k", ini.LoadOptions{Dialect: ini.DialectPython},
+		"[s]\nz = 1",
+	}, {
+		"[s]\nk = 1\nz = 1", "s.z", ini.LoadOptions{},
+		"[s]\nk = 1\n",
+	}, {
+		"[remote \"origin\"]\n\tfetch = a\n\turl = u\n\tFetch = b\n", "remote.origin.fetch", ini.LoadOptions{Dialect: ini.DialectGit},
+		"[remote \"origin\"]\n\turl = u\n",
+	}, {
+		"[s]\nx = 1\nx = 2\n", "s.x", ini.LoadOptions{},
+		"[s]\n",
+	},
+}
+
+func (s *S) TestDelete(c *C) {
+	for _, item := range deleteTests {
+		out, err := ini.Delete([]byte(item.data), item.path, item.opts)
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(string(out), Equals, item.out, Commentf("path: %s", item.path))
+	}
+}
+
+func (s *S) TestEditErrors(c *C) {
+	_, err := ini.Delete([]byte(editDocument), "prod.nope", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "prod.nope"})
+	_, err = ini.Delete([]byte("[s]\nx = 1\n[s]\ny = 2\n"), "s.x", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "s.x"})
+	_, err = ini.Delete([]byte(editDocument), "prod.port", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: key 'prod.port' is inherited, so it cannot be deleted from its section")
+	_, err = ini.Set([]byte("[Unit]\n"), "Description", "x", ini.LoadOptions{Dialect: ini.DialectSystemd})
+	c.Assert(err, ErrorMatches, "ini: key 'Description' is not in a section")
+	_, err = ini.Set([]byte("[s]\nk = 1\n"), "s.k", "a\nb", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: cannot write a value holding a line break or both kinds of quotes")
+	_, err = ini.Set([]byte("\xff\xfe[\x00s\x00]\x00"), "s.k", "v", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: cannot edit a document that is not in UTF-8")
+}
@@ -1,6 +1,11 @@
 package ini
 
-import ()
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
 
 // Flush the buffer if needed.
 func flush(emitter *ini_emitter_t) bool {
@@ -123,399 +128,611 @@ func ini_emitter_emit(emitter *ini_emitter_t, event *ini_event_t) bool {
 
 // Check if we need to accumulate more events before emitting.
 //
-// We accumulate extra
-//  - 1 event for DOCUMENT-START
-//  - 2 events for SEQUENCE-START
-//  - 3 events for MAPPING-START
-//
+// The emitter never looks ahead: a section header is only written at the
+// SECTION-INHERIT event, once the names of the section and its subsections
+// are known.
 func ini_emitter_need_more_events(emitter *ini_emitter_t) bool {
-	if emitter.events_head == len(emitter.events) {
-		return true
-	}
-	var accumulate int
-	switch emitter.events[emitter.events_head].typ {
-	case ini_DOCUMENT_START_EVENT:
-		accumulate = 1
-		break
-	case ini_MAPPING_EVENT:
-		accumulate = 2
-		break
-	case ini_COMMENT_EVENT:
-		accumulate = 3
-		break
-	default:
-		return false
-	}
-	if len(emitter.events)-emitter.events_head > accumulate {
-		return false
-	}
-	var level int
-	for i := emitter.events_head; i < len(emitter.events); i++ {
-		switch emitter.events[i].typ {
-		case ini_DOCUMENT_START_EVENT, ini_MAPPING_EVENT:
-			level++
-		}
-		if level == 0 {
-			return false
-		}
-	}
-	return true
+	return emitter.events_head == len(emitter.events)
 }
 
 // State dispatcher.
 func ini_emitter_state_machine(emitter *ini_emitter_t, event *ini_event_t) bool {
 	switch emitter.state {
-	default:
 	case ini_EMIT_DOCUMENT_START_STATE:
-		return ini_emitter_emit_start(emitter, event)
-	case ini_EMIT_DOCUMENT_END_STATE:
-		return ini_emitter_emit_document(emitter, event)
+		return ini_emitter_emit_document_start(emitter, event)
 	case ini_EMIT_FIRST_SECTION_START_STATE:
 		return ini_emitter_emit_section_start(emitter, event, true)
 	case ini_EMIT_SECTION_START_STATE:
 		return ini_emitter_emit_section_start(emitter, event, false)
-	case ini_EMIT_SECTION_FIRST_NODE_KEY_STATE:
-		return ini_emitter_emit_node(emitter, event)
-	case ini_EMIT_ELEMENT_KEY_STATE:
-		return ini_emitter_emit_node(emitter, event)
-	case ini_EMIT_ELEMENT_VALUE_STATE:
-		return ini_emitter_emit_node(emitter, event)
-	case ini_EMIT_SECTION_END_STATE:
-		return ini_emitter_emit_section_end(emitter, event, false)
-	case ini_EMIT_COMMENT_START_STATE:
-		return ini_emitter_emit_comment(emitter, event)
+	case ini_EMIT_SECTION_INHERIT_STATE:
+		return ini_emitter_emit_section_inherit(emitter, event)
+	case ini_EMIT_SECTION_SUBSECTION_STATE:
+		return ini_emitter_emit_section_subsection(emitter, event)
+	case ini_EMIT_SECTION_ENTRY_STATE:
+		return ini_emitter_emit_section_entry(emitter, event)
+	case ini_EMIT_SECTION_KEY_STATE:
+		return ini_emitter_emit_section_key(emitter, event, false)
+	case ini_EMIT_SECTION_MAP_KEY_STATE:
+		return ini_emitter_emit_section_key(emitter, event, true)
+	case ini_EMIT_SECTION_VALUE_STATE:
+		return ini_emitter_emit_section_value(emitter, event)
+	case ini_EMIT_END_STATE:
+		return ini_emitter_set_emitter_error(emitter, "expected nothing after DOCUMENT-END")
 	}
 	panic("invalid emitter state")
 }
 
 // Expect DOCUMENT-START.
-func ini_emitter_emit_start(emitter *ini_emitter_t, event *ini_event_t) bool {
+func ini_emitter_emit_document_start(emitter *ini_emitter_t, event *ini_event_t) bool {
 	if event.typ != ini_DOCUMENT_START_EVENT {
-		return ini_emitter_set_emitter_error(emitter, "expected START")
+		return ini_emitter_set_emitter_error(emitter, "expected DOCUMENT-START")
+	}
+	if emitter.encoding == ini_ANY_ENCODING {
+		emitter.encoding = ini_UTF8_ENCODING
 	}
 	if emitter.line_break == ini_ANY_BREAK {
 		emitter.line_break = ini_LN_BREAK
 	}
+	if emitter.encoding == ini_UTF16LE_ENCODING || emitter.encoding == ini_UTF16BE_ENCODING {
+		if !ini_emitter_write_bom(emitter) {
+			return false
+		}
+	}
 
 	emitter.line = 0
 	emitter.column = 0
 	emitter.whitespace = true
 
-	emitter.state = ini_EMIT_DOCUMENT_END_STATE
+	emitter.state = ini_EMIT_FIRST_SECTION_START_STATE
 	return true
 }
 
-func ini_emitter_emit_document(emitter *ini_emitter_t, event *ini_event_t) bool {
-	if event.typ != ini_DOCUMENT_START_EVENT {
-		return ini_emitter_set_emitter_error(emitter, "expected DOCUMENT-START")
+// Expect the name of a section, a comment or DOCUMENT-END.
+func ini_emitter_emit_section_start(emitter *ini_emitter_t, event *ini_event_t, first bool) bool {
+	switch event.typ {
+	case ini_DOCUMENT_END_EVENT:
+		emitter.state = ini_EMIT_END_STATE
+		return ini_emitter_flush(emitter)
+	case ini_COMMENT_EVENT:
+		// The blank line between sections goes above the comments of the
+		// next one, rather than between them and its header.
+		if !emitter.section_comment && (emitter.line > 0 || emitter.column > 0) {
+			if !put_break(emitter) {
+				return false
+			}
+		}
+		emitter.section_comment = true
+		return ini_emitter_write_comment(emitter, event.value)
+	case ini_SCALAR_EVENT:
+		emitter.section = append(emitter.section[:0], event.value)
+		emitter.first_section = first
+		emitter.state = ini_EMIT_SECTION_INHERIT_STATE
+		return true
 	}
-	if emitter.line_break == ini_ANY_BREAK {
-		emitter.line_break = ini_LN_BREAK
+	return ini_emitter_set_emitter_error(emitter, "expected SCALAR, COMMENT or DOCUMENT-END")
+}
+
+// Expect MAPPING before the name of a subsection, or SECTION-INHERIT.
+func ini_emitter_emit_section_inherit(emitter *ini_emitter_t, event *ini_event_t) bool {
+	switch event.typ {
+	case ini_MAPPING_EVENT:
+		emitter.state = ini_EMIT_SECTION_SUBSECTION_STATE
+		return true
+	case ini_SECTION_INHERIT_EVENT:
+		// The first section goes without a header when it is the default
+		// one, as the parser reads the keys before any header into it.
+		if !emitter.first_section || len(emitter.section) > 1 ||
+			string(emitter.section[0]) != DEFAULT_SECTION || !ini_emitter_is_default_section(event.value) {
+			if !ini_emitter_write_section_header(emitter, event.value) {
+				return false
+			}
+		}
+		emitter.section_comment = false
+		emitter.state = ini_EMIT_SECTION_ENTRY_STATE
+		return true
 	}
+	return ini_emitter_set_emitter_error(emitter, "expected MAPPING or SECTION-INHERIT")
+}
 
-	emitter.line = 0
-	emitter.whitespace = true
+// Expect the name of a subsection.
+func ini_emitter_emit_section_subsection(emitter *ini_emitter_t, event *ini_event_t) bool {
+	if event.typ != ini_SCALAR_EVENT {
+		return ini_emitter_set_emitter_error(emitter, "expected SCALAR")
+	}
+	emitter.section = append(emitter.section, event.value)
+	emitter.state = ini_EMIT_SECTION_INHERIT_STATE
+	return true
+}
 
-	emitter.state = ini_EMIT_FIRST_SECTION_START_STATE
+// Expect SECTION-ENTRY.
+func ini_emitter_emit_section_entry(emitter *ini_emitter_t, event *ini_event_t) bool {
+	if event.typ != ini_SECTION_ENTRY_EVENT {
+		return ini_emitter_set_emitter_error(emitter, "expected SECTION-ENTRY")
+	}
+	emitter.state = ini_EMIT_SECTION_KEY_STATE
 	return true
 }
 
-// Expect a section start.
-func ini_emitter_emit_section_start(emitter *ini_emitter_t, event *ini_event_t, first bool) bool {
-	if first == true {
-		if string(emitter.scalar_data.value) == DEFAULT_SECTION {
+// Expect a key, a comment, a marker or the SECTION-ENTRY that ends the
+// section.  A marker is a line written as it is, such as the lines around
+// the sides of a merge conflict.  A key nested in a map follows the '.' that
+// the MAPPING event wrote.
+func ini_emitter_emit_section_key(emitter *ini_emitter_t, event *ini_event_t, nested bool) bool {
+	if !nested {
+		switch event.typ {
+		case ini_SECTION_ENTRY_EVENT:
+			emitter.state = ini_EMIT_SECTION_START_STATE
+			return true
+		case ini_COMMENT_EVENT:
+			return ini_emitter_write_comment(emitter, event.value)
+		case ini_MARKER_EVENT:
+			return write_all(emitter, event.value) && put_break(emitter)
+		}
+	}
+	if event.typ != ini_SCALAR_EVENT {
+		return ini_emitter_set_emitter_error(emitter, "expected SCALAR, COMMENT, MARKER or SECTION-ENTRY")
+	}
+	if !nested && emitter.dialect == ini_GIT_DIALECT {
+		if !put(emitter, '\t') {
+			return false
+		}
+	}
+	if emitter.dialect == ini_PROPERTIES_DIALECT {
+		if !ini_emitter_write_property(emitter, event.value, true) {
+			return false
+		}
+	} else if !write_all(emitter, event.value) {
+		return false
+	}
+	emitter.state = ini_EMIT_SECTION_VALUE_STATE
+	return true
+}
 
+// Expect MAPPING before a nested key, or the value of the key.
+func ini_emitter_emit_section_value(emitter *ini_emitter_t, event *ini_event_t) bool {
+	switch event.typ {
+	case ini_MAPPING_EVENT:
+		switch emitter.dialect {
+		case ini_GIT_DIALECT:
+			return ini_emitter_set_emitter_error(emitter, "git keys cannot hold maps")
+		case ini_SYSTEMD_DIALECT:
+			return ini_emitter_set_emitter_error(emitter, "systemd keys cannot hold maps")
+		case ini_DESKTOP_DIALECT:
+			return ini_emitter_set_emitter_error(emitter, "desktop entry keys cannot hold maps")
+		case ini_PYTHON_DIALECT:
+			return ini_emitter_set_emitter_error(emitter, "python keys cannot hold maps")
 		}
-	} else {
-		if !ini_emitter_write_indicator(emitter, []byte{'['}, false, false) {
+		if !put(emitter, '.') {
+			return false
+		}
+		emitter.state = ini_EMIT_SECTION_MAP_KEY_STATE
+		return true
+	case ini_SCALAR_EVENT:
+		indicator := []byte(" =")
+		if emitter.dialect == ini_SYSTEMD_DIALECT || emitter.dialect == ini_PROPERTIES_DIALECT ||
+			emitter.dialect == ini_DOTENV_DIALECT || emitter.dialect == ini_DESKTOP_DIALECT {
+			indicator = indicator[1:]
+		}
+		switch emitter.delimiter {
+		case 0:
+		case ' ', '\t':
+			indicator = nil
+		default:
+			indicator = []byte{emitter.delimiter}
+		}
+		if !ini_emitter_write_indicator(emitter, indicator, false, false) {
 			return false
 		}
-		if !ini_emitter_process_element(emitter) {
+		if !ini_emitter_write_value(emitter, event.value, event.scalar_style()) {
 			return false
 		}
-		if !ini_emitter_write_indicator(emitter, []byte{']'}, false, false) {
+		if len(event.comment) > 0 {
+			if !put(emitter, ' ') || !ini_emitter_write_comment(emitter, event.comment) {
+				return false
+			}
+		} else if !put_break(emitter) {
 			return false
 		}
+		emitter.state = ini_EMIT_SECTION_KEY_STATE
+		return true
 	}
-	emitter.states = append(emitter.states, ini_EMIT_SECTION_START_STATE)
-	return ini_emitter_emit_node(emitter, event)
+	return ini_emitter_set_emitter_error(emitter, "expected MAPPING or SCALAR")
 }
 
-// Expect a section end.
-func ini_emitter_emit_section_end(emitter *ini_emitter_t, event *ini_event_t, first bool) bool {
-	emitter.states = append(emitter.states, ini_EMIT_SECTION_END_STATE)
-	return ini_emitter_emit_node(emitter, event)
+// Check if the inherited section is the implicit default one.
+func ini_emitter_is_default_section(inherit []byte) bool {
+	return len(inherit) == 0 || string(inherit) == DEFAULT_SECTION
 }
 
-// Expect a node.
-func ini_emitter_emit_node(emitter *ini_emitter_t, event *ini_event_t) bool {
-	switch event.typ {
-	case ini_SCALAR_EVENT:
-		return ini_emitter_emit_scalar(emitter, event)
-	case ini_COMMENT_EVENT:
-		return ini_emitter_emit_comment(emitter, event)
-	default:
-		return ini_emitter_set_emitter_error(emitter,
-			"expected SCALAR, SECTION-START, COMMENT-START")
+// Write the header of the current section, after a blank line unless it is
+// the first line of the output.
+func ini_emitter_write_section_header(emitter *ini_emitter_t, inherit []byte) bool {
+	if !emitter.section_comment && (emitter.line > 0 || emitter.column > 0) {
+		if !put_break(emitter) {
+			return false
+		}
 	}
-	return false
-}
-
-// Expect a comment.
-func ini_emitter_emit_comment(emitter *ini_emitter_t, event *ini_event_t) bool {
-	if !ini_emitter_write_indicator(emitter, []byte{'#'}, false, false) {
+	if !put(emitter, '[') {
 		return false
 	}
-	if !ini_emitter_process_element(emitter) {
+	if emitter.dialect == ini_SYSTEMD_DIALECT {
+		if len(emitter.section) > 1 {
+			return ini_emitter_set_emitter_error(emitter, "systemd sections hold no subsections")
+		}
+		if !write_all(emitter, emitter.section[0]) {
+			return false
+		}
+	} else if emitter.dialect == ini_DESKTOP_DIALECT || emitter.dialect == ini_PYTHON_DIALECT {
+		if len(emitter.section) > 1 && emitter.dialect == ini_DESKTOP_DIALECT {
+			return ini_emitter_set_emitter_error(emitter, "desktop entry groups hold no subgroups")
+		}
+		if len(emitter.section) > 1 {
+			return ini_emitter_set_emitter_error(emitter, "python sections hold no subsections")
+		}
+		if !write_all(emitter, emitter.section[0]) {
+			return false
+		}
+	} else if emitter.dialect == ini_GIT_DIALECT {
+		if len(emitter.section) > 2 {
+			return ini_emitter_set_emitter_error(emitter, "git sections hold one subsection at most")
+		}
+		if !write_all(emitter, emitter.section[0]) {
+			return false
+		}
+		if len(emitter.section) > 1 {
+			if !put(emitter, ' ') || !ini_emitter_write_quoted(emitter, emitter.section[1]) {
+				return false
+			}
+		}
+	} else {
+		if len(emitter.section) > 2 {
+			return ini_emitter_set_emitter_error(emitter, "sections hold one subsection at most")
+		}
+		if !ini_emitter_write_section_key(emitter, emitter.section[0]) {
+			return false
+		}
+		if len(emitter.section) > 1 {
+			if !put(emitter, ' ') || !ini_emitter_write_quoted(emitter, emitter.section[1]) {
+				return false
+			}
+		}
+		if !ini_emitter_is_default_section(inherit) {
+			if !put(emitter, ':') || !ini_emitter_write_section_key(emitter, inherit) {
+				return false
+			}
+		}
+	}
+	if !put(emitter, ']') {
 		return false
 	}
-	return false
+	return put_break(emitter)
 }
 
-// Expect SCALAR.
-func ini_emitter_emit_scalar(emitter *ini_emitter_t, event *ini_event_t) bool {
-	if !ini_emitter_select_scalar_style(emitter, event) {
-		return false
+// Write a section key, quoted unless it only holds alphabetical characters,
+// digits, '_', '-' and non-ASCII characters.
+func ini_emitter_write_section_key(emitter *ini_emitter_t, name []byte) bool {
+	plain := len(name) > 0
+	for i := 0; plain && i < len(name); i += width(name[i]) {
+		plain = is_alpha(name, i) || !is_ascii(name, i)
 	}
-	if !ini_emitter_process_element(emitter) {
-		return false
+	if !plain {
+		return ini_emitter_write_quoted(emitter, name)
 	}
-	emitter.state = emitter.states[len(emitter.states)-1]
-	emitter.states = emitter.states[:len(emitter.states)-1]
-	return true
-}
-
-// Check if the document content is an empty scalar.
-func ini_emitter_check_empty_document(emitter *ini_emitter_t) bool {
-	return false // [Go] Huh?
+	return write_all(emitter, name)
 }
 
-// Check if the next events represent an empty section.
-func ini_emitter_check_empty_section(emitter *ini_emitter_t) bool {
-	if len(emitter.events)-emitter.events_head < 2 {
+// Write a double-quoted name, escaping quotes and backslashes.
+func ini_emitter_write_quoted(emitter *ini_emitter_t, name []byte) bool {
+	if !put(emitter, '"') {
 		return false
 	}
-	if emitter.events[emitter.events_head].typ == ini_MAPPING_EVENT &&
-			(emitter.events[emitter.events_head+1].typ == ini_MAPPING_EVENT ||  emitter.events[emitter.events_head+2].typ == ini_MAPPING_EVENT) {
-		return true
+	for i := 0; i < len(name); {
+		if name[i] == '"' || name[i] == '\\' {
+			if !put(emitter, '\\') {
+				return false
+			}
+		}
+		if !write(emitter, name, &i) {
+			return false
+		}
 	}
-	return false
-
+	return put(emitter, '"')
 }
 
-// Check if the next events represent an empty comment.
-func ini_emitter_check_empty_comment(emitter *ini_emitter_t) bool {
-	if len(emitter.events)-emitter.events_head < 2 {
+// Write a comment, up to the end of its line.
+func ini_emitter_write_comment(emitter *ini_emitter_t, comment []byte) bool {
+	indicator := emitter.indicator
+	if indicator == 0 {
+		indicator = '#'
+	}
+	if !put(emitter, indicator) {
+		return false
+	}
+	if len(comment) > 0 && !(put(emitter, ' ') && write_all(emitter, comment)) {
 		return false
 	}
-	return emitter.events[emitter.events_head].typ == ini_COMMENT_EVENT
+	return put_break(emitter)
 }
 
-// Determine an acceptable scalar style.
-func ini_emitter_select_scalar_style(emitter *ini_emitter_t, event *ini_event_t) bool {
-
-	style := event.scalar_style()
-	if style == ini_ANY_SCALAR_STYLE {
-		style = ini_PLAIN_SCALAR_STYLE
+// Write a value, in the style that reads back to the same value.  A quoted
+// style is kept when the value can be written in it.  Verbatim values are
+// written as they are, between the quotes of their style.
+func ini_emitter_write_value(emitter *ini_emitter_t, value []byte, style ini_scalar_style_t) bool {
+	if emitter.verbatim {
+		return ini_emitter_write_verbatim_value(emitter, value, style)
+	}
+	switch emitter.dialect {
+	case ini_GIT_DIALECT:
+		return ini_emitter_write_git_value(emitter, value)
+	case ini_SYSTEMD_DIALECT:
+		return ini_emitter_write_systemd_value(emitter, value)
+	case ini_PROPERTIES_DIALECT:
+		return ini_emitter_write_property(emitter, value, false)
+	case ini_DOTENV_DIALECT:
+		return ini_emitter_write_dotenv_value(emitter, value)
+	case ini_DESKTOP_DIALECT:
+		// The encoder escaped the value.
+		return write_all(emitter, value)
+	case ini_PYTHON_DIALECT:
+		return ini_emitter_write_python_value(emitter, value)
+	}
+	quote := byte('"')
+	if style == ini_SINGLE_QUOTED_SCALAR_STYLE {
+		quote = '\''
 	}
-
-	if style == ini_PLAIN_SCALAR_STYLE {
-		if len(emitter.scalar_data.value) == 0 {
-			style = ini_SINGLE_QUOTED_SCALAR_STYLE
+	if style != ini_DOUBLE_QUOTED_SCALAR_STYLE && style != ini_SINGLE_QUOTED_SCALAR_STYLE ||
+		bytes.IndexByte(value, quote) >= 0 || bytes.IndexAny(value, "\r\n") >= 0 {
+		if len(value) == 0 {
+			return true
 		}
-	}
-	if style == ini_SINGLE_QUOTED_SCALAR_STYLE {
-		if !emitter.scalar_data.single_quoted_allowed {
-			style = ini_DOUBLE_QUOTED_SCALAR_STYLE
+		style = ini_emitter_select_value_style(value)
+		if style == ini_PLAIN_SCALAR_STYLE && emitter.indicator != 0 && bytes.IndexByte(value, emitter.indicator) >= 0 {
+			// A comment character of the document, other than '#' and ';'.
+			switch {
+			case bytes.IndexByte(value, '"') < 0:
+				style = ini_DOUBLE_QUOTED_SCALAR_STYLE
+			case bytes.IndexByte(value, '\'') < 0:
+				style = ini_SINGLE_QUOTED_SCALAR_STYLE
+			}
 		}
 	}
-
-	emitter.scalar_data.style = style
-	return true
-}
-
-// Write a scalar.
-func ini_emitter_process_element(emitter *ini_emitter_t) bool {
-	switch emitter.scalar_data.style {
-	case ini_SINGLE_QUOTED_SCALAR_STYLE:
-		return ini_emitter_write_single_quoted_element(emitter, emitter.scalar_data.value)
-
+	if !put(emitter, ' ') {
+		return false
+	}
+	switch style {
+	case ini_PLAIN_SCALAR_STYLE:
+		return write_all(emitter, value)
 	case ini_DOUBLE_QUOTED_SCALAR_STYLE:
-		return ini_emitter_write_double_quoted_element(emitter, emitter.scalar_data.value)
+		return put(emitter, '"') && write_all(emitter, value) && put(emitter, '"')
+	case ini_SINGLE_QUOTED_SCALAR_STYLE:
+		return put(emitter, '\'') && write_all(emitter, value) && put(emitter, '\'')
 	}
-	panic("unknown scalar style")
+	return ini_emitter_set_emitter_error(emitter, "cannot write a value holding a line break or both kinds of quotes")
 }
 
-// Write the BOM character.
-func ini_emitter_write_bom(emitter *ini_emitter_t) bool {
-	if !flush(emitter) {
-		return false
+// Determine an acceptable style for a value.  Quoted values are read without
+// escapes, so a value holding a line break or both kinds of quotes has no
+// acceptable style.
+func ini_emitter_select_value_style(value []byte) ini_scalar_style_t {
+	plain := !is_blank(value, 0) && !is_blank(value, len(value)-1) && value[0] != '"' && value[0] != '\''
+	for i := 0; i < len(value); i++ {
+		if is_break(value, i) {
+			return ini_ANY_SCALAR_STYLE
+		}
+		if value[i] == '#' || value[i] == ';' || value[i] == '=' {
+			plain = false
+		}
 	}
-	pos := emitter.buffer_pos
-	emitter.buffer[pos+0] = '\xEF'
-	emitter.buffer[pos+1] = '\xBB'
-	emitter.buffer[pos+2] = '\xBF'
-	emitter.buffer_pos += 3
-	return true
+	switch {
+	case plain:
+		return ini_PLAIN_SCALAR_STYLE
+	case bytes.IndexByte(value, '"') < 0:
+		return ini_DOUBLE_QUOTED_SCALAR_STYLE
+	case bytes.IndexByte(value, '\'') < 0:
+		return ini_SINGLE_QUOTED_SCALAR_STYLE
+	}
+	return ini_ANY_SCALAR_STYLE
 }
 
-func ini_emitter_write_indicator(emitter *ini_emitter_t, indicator []byte, need_whitespace, is_whitespace bool) bool {
-	if need_whitespace && !emitter.whitespace {
-		if !put(emitter, ' ') {
-			return false
-		}
+// Write a git value.  Quotes, backslashes and control characters are
+// escaped, and the value is quoted when it has leading or trailing blanks
+// or holds a comment character.
+func ini_emitter_write_git_value(emitter *ini_emitter_t, value []byte) bool {
+	if len(value) == 0 {
+		return true
 	}
-	if !write_all(emitter, indicator) {
+	if !put(emitter, ' ') {
 		return false
 	}
-	emitter.whitespace = is_whitespace
-	emitter.open_ended = false
-	return true
-}
-
-func ini_emitter_write_single_quoted_element(emitter *ini_emitter_t, value []byte) bool {
-
-	if !ini_emitter_write_indicator(emitter, []byte{'\''}, true, false) {
+	quoted := is_blank(value, 0) || is_blank(value, len(value)-1) || bytes.IndexAny(value, "#;") >= 0
+	if quoted && !put(emitter, '"') {
 		return false
 	}
-
-	breaks := false
 	for i := 0; i < len(value); {
-		if is_space(value, i) {
+		var escape byte
+		switch value[i] {
+		case '"', '\\':
+			escape = value[i]
+		case '\n':
+			escape = 'n'
+		case '\t':
+			escape = 't'
+		case '\b':
+			escape = 'b'
+		}
+		if escape == 0 {
 			if !write(emitter, value, &i) {
 				return false
 			}
-		} else if is_break(value, i) {
-			if !breaks && value[i] == '\n' {
-				if !put_break(emitter) {
-					return false
-				}
+			continue
+		}
+		if !put(emitter, '\\') || !put(emitter, escape) {
+			return false
+		}
+		i++
+	}
+	return !quoted || put(emitter, '"')
+}
+
+// Write a systemd value verbatim.  A line break would end the value, so a
+// value holding one cannot be written.
+func ini_emitter_write_systemd_value(emitter *ini_emitter_t, value []byte) bool {
+	for i := 0; i < len(value); i++ {
+		if is_break(value, i) {
+			return ini_emitter_set_emitter_error(emitter, "cannot write a systemd value holding a line break")
+		}
+	}
+	return write_all(emitter, value)
+}
+
+// Write the key or the value of a Java properties line.  Backslashes,
+// control characters and the comment characters are escaped, and so are
+// non-ASCII characters, as '\uXXXX', to read back in any encoding.  A key
+// also escapes '=', ':' and blanks, and a value its leading blanks.
+func ini_emitter_write_property(emitter *ini_emitter_t, value []byte, key bool) bool {
+	for i := 0; i < len(value); {
+		var escape byte
+		switch value[i] {
+		case '\\', '#', '!':
+			escape = value[i]
+		case '=', ':', ' ':
+			if key || i == 0 && value[i] == ' ' {
+				escape = value[i]
 			}
-			if !write_break(emitter, value, &i) {
+		case '\t':
+			escape = 't'
+		case '\n':
+			escape = 'n'
+		case '\r':
+			escape = 'r'
+		case '\f':
+			escape = 'f'
+		}
+		if escape != 0 {
+			if !put(emitter, '\\') || !put(emitter, escape) {
 				return false
 			}
-			breaks = true
-		} else {
-			if value[i] == '\'' {
-				if !put(emitter, '\'') {
-					return false
-				}
-			}
+			i++
+			continue
+		}
+		if is_printable(value, i) && is_ascii(value, i) {
 			if !write(emitter, value, &i) {
 				return false
 			}
-			breaks = false
+			continue
 		}
+		r, w := utf8.DecodeRune(value[i:])
+		for _, code := range utf16.Encode([]rune{r}) {
+			if !write_all(emitter, []byte(fmt.Sprintf("\\u%04x", code))) {
+				return false
+			}
+		}
+		i += w
 	}
-	if !ini_emitter_write_indicator(emitter, []byte{'\''}, false, false) {
-		return false
-	}
-	emitter.whitespace = false
 	return true
 }
 
-func ini_emitter_write_double_quoted_element(emitter *ini_emitter_t, value []byte) bool {
-	if !ini_emitter_write_indicator(emitter, []byte{'"'}, true, false) {
+// Write a dotenv value.  The value is double-quoted, with its line breaks,
+// tabs, quotes, backslashes and '$' escaped, unless it only holds
+// characters that read back the same unquoted.
+func ini_emitter_write_dotenv_value(emitter *ini_emitter_t, value []byte) bool {
+	if bytes.IndexAny(value, " \t\r\n#'\"\\$") < 0 {
+		return write_all(emitter, value)
+	}
+	if !put(emitter, '"') {
 		return false
 	}
-
 	for i := 0; i < len(value); {
-		if !is_printable(value, i) || (!emitter.unicode && !is_ascii(value, i)) ||
-			is_bom(value, i) || is_break(value, i) ||
-			value[i] == '"' || value[i] == '\\' {
-
-			octet := value[i]
-
-			var w int
-			var v rune
-			switch {
-			case octet&0x80 == 0x00:
-				w, v = 1, rune(octet&0x7F)
-			case octet&0xE0 == 0xC0:
-				w, v = 2, rune(octet&0x1F)
-			case octet&0xF0 == 0xE0:
-				w, v = 3, rune(octet&0x0F)
-			case octet&0xF8 == 0xF0:
-				w, v = 4, rune(octet&0x07)
-			}
-			for k := 1; k < w; k++ {
-				octet = value[i+k]
-				v = (v << 6) + (rune(octet) & 0x3F)
-			}
-			i += w
-
-			if !put(emitter, '\\') {
-				return false
-			}
-
-			var ok bool
-			switch v {
-			case 0x00:
-				ok = put(emitter, '0')
-			case 0x07:
-				ok = put(emitter, 'a')
-			case 0x08:
-				ok = put(emitter, 'b')
-			case 0x09:
-				ok = put(emitter, 't')
-			case 0x0A:
-				ok = put(emitter, 'n')
-			case 0x0b:
-				ok = put(emitter, 'v')
-			case 0x0c:
-				ok = put(emitter, 'f')
-			case 0x0d:
-				ok = put(emitter, 'r')
-			case 0x1b:
-				ok = put(emitter, 'e')
-			case 0x22:
-				ok = put(emitter, '"')
-			case 0x5c:
-				ok = put(emitter, '\\')
-			case 0x85:
-				ok = put(emitter, 'N')
-			case 0xA0:
-				ok = put(emitter, '_')
-			case 0x2028:
-				ok = put(emitter, 'L')
-			case 0x2029:
-				ok = put(emitter, 'P')
-			default:
-				if v <= 0xFF {
-					ok = put(emitter, 'x')
-					w = 2
-				} else if v <= 0xFFFF {
-					ok = put(emitter, 'u')
-					w = 4
-				} else {
-					ok = put(emitter, 'U')
-					w = 8
-				}
-				for k := (w - 1) * 4; ok && k >= 0; k -= 4 {
-					digit := byte((v >> uint(k)) & 0x0F)
-					if digit < 10 {
-						ok = put(emitter, digit+'0')
-					} else {
-						ok = put(emitter, digit+'A'-10)
-					}
-				}
-			}
-			if !ok {
-				return false
-			}
-		} else if is_space(value, i) {
+		var escape byte
+		switch value[i] {
+		case '"', '\\', '$':
+			escape = value[i]
+		case '\n':
+			escape = 'n'
+		case '\r':
+			escape = 'r'
+		case '\t':
+			escape = 't'
+		}
+		if escape == 0 {
 			if !write(emitter, value, &i) {
 				return false
 			}
-		} else {
-			if !write(emitter, value, &i) {
+			continue
+		}
+		if !put(emitter, '\\') || !put(emitter, escape) {
+			return false
+		}
+		i++
+	}
+	return put(emitter, '"')
+}
+
+// Write the text of a value as it is, between the quotes of its style.
+func ini_emitter_write_verbatim_value(emitter *ini_emitter_t, value []byte, style ini_scalar_style_t) bool {
+	switch emitter.dialect {
+	case ini_SYSTEMD_DIALECT, ini_PROPERTIES_DIALECT, ini_DOTENV_DIALECT, ini_DESKTOP_DIALECT:
+	default:
+		if (len(value) > 0 || style != ini_PLAIN_SCALAR_STYLE) && !put(emitter, ' ') {
+			return false
+		}
+	}
+	switch style {
+	case ini_DOUBLE_QUOTED_SCALAR_STYLE:
+		return put(emitter, '"') && write_all(emitter, value) && put(emitter, '"')
+	case ini_SINGLE_QUOTED_SCALAR_STYLE:
+		return put(emitter, '\'') && write_all(emitter, value) && put(emitter, '\'')
+	}
+	return write_all(emitter, value)
+}
+
+// Write a Python value verbatim.  Its lines after the first are indented
+// with a tab, to continue the value.
+func ini_emitter_write_python_value(emitter *ini_emitter_t, value []byte) bool {
+	if len(value) == 0 {
+		return true
+	}
+	if !put(emitter, ' ') {
+		return false
+	}
+	for i := 0; i < len(value); {
+		if value[i] == '\n' {
+			if !put_break(emitter) || !put(emitter, '\t') {
 				return false
 			}
+			i++
+			continue
+		}
+		if !write(emitter, value, &i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Write the BOM character.
+func ini_emitter_write_bom(emitter *ini_emitter_t) bool {
+	if !flush(emitter) {
+		return false
+	}
+	pos := emitter.buffer_pos
+	emitter.buffer[pos+0] = '\xEF'
+	emitter.buffer[pos+1] = '\xBB'
+	emitter.buffer[pos+2] = '\xBF'
+	emitter.buffer_pos += 3
+	return true
+}
+
+func ini_emitter_write_indicator(emitter *ini_emitter_t, indicator []byte, need_whitespace, is_whitespace bool) bool {
+	if need_whitespace && !emitter.whitespace {
+		if !put(emitter, ' ') {
+			return false
 		}
 	}
-	if !ini_emitter_write_indicator(emitter, []byte{'"'}, false, false) {
+	if !write_all(emitter, indicator) {
 		return false
 	}
-	emitter.whitespace = false
+	emitter.whitespace = is_whitespace
+	emitter.open_ended = false
 	return true
 }
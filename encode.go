@@ -2,10 +2,10 @@ package ini
 
 import (
 	"encoding"
+	"fmt"
 	"reflect"
-	"regexp"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -13,23 +13,32 @@ type encoder struct {
 	emitter ini_emitter_t
 	event   ini_event_t
 	out     []byte
-	flow    bool
+	dialect Dialect
 }
 
-func newEncoder() (e *encoder) {
-	e = &encoder{}
+func newEncoder(opts DumpOptions) (e *encoder) {
+	e = &encoder{dialect: opts.Dialect}
 	e.must(ini_emitter_initialize(&e.emitter))
 	ini_emitter_set_output_string(&e.emitter, &e.out)
 	ini_emitter_set_unicode(&e.emitter, true)
+	ini_emitter_set_dialect(&e.emitter, opts.Dialect.dialect())
+	if opts.Dialect == DialectPHP || opts.Dialect == DialectWindows {
+		ini_emitter_set_comment_indicator(&e.emitter, ';')
+	}
+	if opts.Encoding != EncodingAuto {
+		ini_emitter_set_encoding(&e.emitter, opts.Encoding.encoding())
+	}
+	if opts.CRLF {
+		ini_emitter_set_break(&e.emitter, ini_CRLN_BREAK)
+	}
 	e.must(ini_document_start_event_initialize(&e.event))
 	e.emit()
 	return e
 }
 
 func (e *encoder) finish() {
-	e.must(ini_document_start_event_initialize(&e.event))
+	e.must(ini_document_end_event_initialize(&e.event))
 	e.emit()
-	e.emitter.open_ended = false
 }
 
 func (e *encoder) destroy() {
@@ -38,9 +47,7 @@ func (e *encoder) destroy() {
 
 func (e *encoder) emit() {
 	// This will internally delete the e.event value.
-	if !ini_emitter_emit(&e.emitter, &e.event) && e.event.typ != ini_DOCUMENT_END_EVENT {
-		e.must(false)
-	}
+	e.must(ini_emitter_emit(&e.emitter, &e.event))
 }
 
 func (e *encoder) must(ok bool) {
@@ -53,49 +60,240 @@ func (e *encoder) must(ok bool) {
 	}
 }
 
-func (e *encoder) marshal(in reflect.Value) {
-	if !in.IsValid() {
-		e.nilv()
-		return
+// item is a key and its value, in a map, a MapSlice or a struct.
+type item struct {
+	key   string
+	value reflect.Value
+}
+
+var mapSliceType = reflect.TypeOf(MapSlice{})
+
+// indirect returns the value that in is marshaled as: the result of its
+// MarshalINI or MarshalText method, or the value it points to.  It returns
+// the zero Value for nil.
+func (e *encoder) indirect(in reflect.Value) reflect.Value {
+	for in.IsValid() {
+		if (in.Kind() == reflect.Ptr || in.Kind() == reflect.Interface) && in.IsNil() {
+			return reflect.Value{}
+		}
+		iface := in.Interface()
+		if m, ok := iface.(Marshaler); ok {
+			v, err := m.MarshalINI()
+			if err != nil {
+				fail(err)
+			}
+			in = reflect.ValueOf(v)
+			continue
+		}
+		if m, ok := iface.(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				fail(err)
+			}
+			return reflect.ValueOf(string(text))
+		}
+		if in.Kind() != reflect.Ptr && in.Kind() != reflect.Interface {
+			break
+		}
+		in = in.Elem()
 	}
-	iface := in.Interface()
-	if m, ok := iface.(Marshaler); ok {
-		v, err := m.MarshalINI()
+	return in
+}
+
+// items returns the keys and values of in, if it is a map, a MapSlice or a
+// struct.  The keys of a map are sorted.
+func (e *encoder) items(in reflect.Value) (items []item, ok bool) {
+	in = e.indirect(in)
+	switch in.Kind() {
+	case reflect.Map:
+		for _, k := range in.MapKeys() {
+			items = append(items, item{fmt.Sprint(k.Interface()), in.MapIndex(k)})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].key < items[j].key })
+	case reflect.Struct:
+		sinfo, err := getStructInfo(in.Type())
 		if err != nil {
-			fail(err)
+			panic(err)
 		}
-		if v == nil {
-			e.nilv()
-			return
+		for _, info := range sinfo.FieldsList {
+			var value reflect.Value
+			if info.Inline == nil {
+				value = in.Field(info.Num)
+			} else {
+				value = in.FieldByIndex(info.Inline)
+			}
+			if info.OmitEmpty && isZero(value) {
+				continue
+			}
+			items = append(items, item{info.Key, value})
 		}
-		in = reflect.ValueOf(v)
-	} else if m, ok := iface.(encoding.TextMarshaler); ok {
-		text, err := m.MarshalText()
-		if err != nil {
-			fail(err)
+	case reflect.Slice:
+		if in.Type() != mapSliceType {
+			return nil, false
 		}
-		in = reflect.ValueOf(string(text))
+		for _, mapItem := range in.Interface().(MapSlice) {
+			items = append(items, item{fmt.Sprint(mapItem.Key), reflect.ValueOf(mapItem.Value)})
+		}
+	default:
+		return nil, false
 	}
-	switch in.Kind() {
-	case reflect.Interface:
-		if in.IsNil() {
-			e.nilv()
+	return items, true
+}
+
+// document marshals a map, a MapSlice or a struct as a document.  Its maps
+// are sections, and its other values are the keys of the default section.
+// Java properties and dotenv files have no sections, so their maps are
+// dotted keys.
+func (e *encoder) document(in reflect.Value) {
+	if !e.indirect(in).IsValid() {
+		return
+	}
+	items, ok := e.items(in)
+	if !ok {
+		failf("cannot marshal type %s into a document", in.Type())
+	}
+	if e.dialect.sectionless() {
+		e.section([]string{DEFAULT_SECTION}, items)
+		return
+	}
+	var keys, sections []item
+	for _, item := range items {
+		if _, ok := e.items(item.value); ok {
+			sections = append(sections, item)
 		} else {
-			e.marshal(in.Elem())
+			keys = append(keys, item)
 		}
-	case reflect.Map:
-		e.mapv(in)
-	case reflect.Ptr:
-		if in.IsNil() {
-			e.nilv()
+	}
+	if len(keys) > 0 {
+		if e.dialect.sectioned() {
+			failf("key '%s' is not in a section", keys[0].key)
+		}
+		e.section([]string{DEFAULT_SECTION}, keys)
+	}
+	for _, section := range sections {
+		items, _ := e.items(section.value)
+		e.section([]string{section.key}, items)
+	}
+}
+
+// section marshals the keys of a section.  Nested maps are dotted keys, or
+// subsections in git.
+func (e *encoder) section(path []string, items []item) {
+	var keys, subsections []item
+	for _, item := range items {
+		if _, ok := e.items(item.value); ok && e.dialect == DialectGit {
+			subsections = append(subsections, item)
 		} else {
-			e.marshal(in.Elem())
+			keys = append(keys, item)
 		}
+	}
+	if len(keys) > 0 || len(subsections) == 0 {
+		e.key(path)
+		e.must(ini_section_inherit_event_initialize(&e.event, []byte(DEFAULT_SECTION)))
+		e.emit()
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+		for _, item := range keys {
+			e.pair([]string{item.key}, item.value)
+		}
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+	}
+	for _, subsection := range subsections {
+		items, _ := e.items(subsection.value)
+		e.section(append(path[:len(path):len(path)], subsection.key), items)
+	}
+}
+
+// pair marshals a key and its value.  A map is marshaled as dotted keys, and
+// a slice, in git and systemd, as a key repeated for each of its values.  In
+// desktop entries, a map is marshaled as the localized values of the key,
+// and a slice as a list.
+func (e *encoder) pair(keys []string, in reflect.Value) {
+	if e.dialect == DialectDesktop {
+		e.desktopPair(keys[0], in)
+		return
+	}
+	if items, ok := e.items(in); ok {
+		for _, item := range items {
+			e.pair(append(keys[:len(keys):len(keys)], item.key), item.value)
+		}
+		return
+	}
+	in = e.indirect(in)
+	if in.Kind() == reflect.Slice && in.Type().Elem().Kind() != reflect.Uint8 || in.Kind() == reflect.Array {
+		if !e.dialect.multi() {
+			failf("cannot marshal type: %s", in.Type())
+		}
+		for i := 0; i < in.Len(); i++ {
+			e.key(keys)
+			e.marshal(in.Index(i))
+		}
+		return
+	}
+	e.key(keys)
+	e.marshal(in)
+}
+
+// desktopPair marshals a key of a desktop entry and its value.
+func (e *encoder) desktopPair(key string, in reflect.Value) {
+	if items, ok := e.items(in); ok {
+		for _, item := range items {
+			if item.key == "" {
+				e.desktopPair(key, item.value)
+			} else {
+				e.desktopPair(key+"["+item.key+"]", item.value)
+			}
+		}
+		return
+	}
+	in = e.indirect(in)
+	if in.Kind() == reflect.Slice && in.Type().Elem().Kind() != reflect.Uint8 || in.Kind() == reflect.Array {
+		var list []byte
+		for i := 0; i < in.Len(); i++ {
+			element := e.indirect(in.Index(i))
+			switch element.Kind() {
+			case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64, reflect.Bool:
+			default:
+				failf("cannot marshal type %s into a desktop entry list", in.Type())
+			}
+			list = append(list, desktop_escape(fmt.Sprint(element.Interface()), true)...)
+			list = append(list, ';')
+		}
+		e.key([]string{key})
+		e.emitNode(string(list), ini_PLAIN_SCALAR_STYLE)
+		return
+	}
+	e.key([]string{key})
+	e.marshal(in)
+}
+
+// key emits a key, nested in the maps of the keys before it.
+func (e *encoder) key(keys []string) {
+	for i, key := range keys {
+		if i > 0 {
+			e.must(ini_mapping_event_initialize(&e.event))
+			e.emit()
+		}
+		e.emitNode(key, ini_PLAIN_SCALAR_STYLE)
+	}
+}
+
+// marshal marshals a value.
+func (e *encoder) marshal(in reflect.Value) {
+	in = e.indirect(in)
+	if !in.IsValid() {
+		e.nilv()
+		return
+	}
+	switch in.Kind() {
 	case reflect.String:
 		e.stringv(in)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if in.Type() == durationType {
-			e.stringv(reflect.ValueOf(iface.(time.Duration).String()))
+			e.stringv(reflect.ValueOf(in.Interface().(time.Duration).String()))
 		} else {
 			e.intv(in)
 		}
@@ -106,46 +304,16 @@ func (e *encoder) marshal(in reflect.Value) {
 	case reflect.Bool:
 		e.boolv(in)
 	default:
-		panic("cannot marshal type: " + in.Type().String())
+		failf("cannot marshal type: %s", in.Type())
 	}
 }
 
-func (e *encoder) mapv(in reflect.Value) {
-}
-
-// isBase60 returns whether s is in base 60 notation as defined in YAML 1.1.
-//
-// The base 60 float notation in YAML 1.1 is a terrible idea and is unsupported
-// in YAML 1.2 and by this package, but these should be marshalled quoted for
-// the time being for compatibility with other parsers.
-func isBase60Float(s string) (result bool) {
-	// Fast path.
-	if s == "" {
-		return false
-	}
-	c := s[0]
-	if !(c == '+' || c == '-' || c >= '0' && c <= '9') || strings.IndexByte(s, ':') < 0 {
-		return false
-	}
-	// Do the full match.
-	return base60float.MatchString(s)
-}
-
-// From http://ini.org/type/float.html, except the regular expression there
-// is bogus. In practice parsers do not enforce the "\.[0-9_]*" suffix.
-var base60float = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+(?:\.[0-9_]*)?$`)
-
 func (e *encoder) stringv(in reflect.Value) {
-	var style ini_scalar_style_t
-	s := in.String()
-	if isBase60Float(s) {
-		style = ini_DOUBLE_QUOTED_SCALAR_STYLE
-	} else if strings.Contains(s, "\n") {
-		style = ini_PLAIN_SCALAR_STYLE
-	} else {
-		style = ini_PLAIN_SCALAR_STYLE
+	if e.dialect == DialectDesktop {
+		e.emitNode(desktop_escape(in.String(), false), ini_PLAIN_SCALAR_STYLE)
+		return
 	}
-	e.emitNode(s, style)
+	e.emitNode(in.String(), ini_PLAIN_SCALAR_STYLE)
 }
 
 func (e *encoder) boolv(in reflect.Value) {
@@ -169,8 +337,11 @@ func (e *encoder) uintv(in reflect.Value) {
 }
 
 func (e *encoder) floatv(in reflect.Value) {
-	// FIXME: Handle 64 bits here.
-	s := strconv.FormatFloat(float64(in.Float()), 'g', -1, 32)
+	precision := 64
+	if in.Kind() == reflect.Float32 {
+		precision = 32
+	}
+	s := strconv.FormatFloat(in.Float(), 'g', -1, precision)
 	switch s {
 	case "+Inf":
 		s = ".inf"
@@ -183,7 +354,7 @@ func (e *encoder) floatv(in reflect.Value) {
 }
 
 func (e *encoder) nilv() {
-	e.emitNode("null", ini_PLAIN_SCALAR_STYLE)
+	e.emitNode("", ini_PLAIN_SCALAR_STYLE)
 }
 
 func (e *encoder) emitNode(value string, style ini_scalar_style_t) {
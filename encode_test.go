@@ -1,5 +1,11 @@
 package ini_test
 
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
 /*
 var marshalTests = []struct {
 value interface{}
@@ -148,3 +154,215 @@ func TestMarshalerError(t *testing.T) {
 	}
 }
 */
+
+var marshalTests = []struct {
+	value interface{}
+	data  string
+}{
+	{nil, ""},
+	{map[string]string{"v": "hi"}, "v = hi\n"},
+	{
+		map[string]interface{}{
+			"a": 1,
+			"b": "x y",
+			"c": " lead",
+			"d": "a#b",
+			"e": nil,
+			"s": map[string]interface{}{"k": 1.5, "m": map[string]string{"x": "y"}},
+		},
+		"a = 1\nb = x y\nc = \" lead\"\nd = \"a#b\"\ne =\n\n[s]\nk = 1.5\nm.x = y\n",
+	},
+	{
+		&struct {
+			A int
+			B string `ini:"bee"`
+			C string `ini:"-"`
+		}{A: 1, B: "x", C: "y"},
+		"a = 1\nbee = x\n",
+	},
+}
+
+func (s *S) TestMarshal(c *C) {
+	for i, item := range marshalTests {
+		c.Logf("test %d: %q", i, item.data)
+		data, err := ini.Marshal(item.value)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, item.data)
+	}
+}
+
+func (s *S) TestMarshalGit(c *C) {
+	value := map[string]interface{}{
+		"core": map[string]interface{}{"bare": false, "editor": `vim "x"`},
+		"remote": map[string]interface{}{
+			"Origin": map[string]interface{}{"url": "u", "fetch": []string{"a", "b"}},
+		},
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectGit})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "[core]\n\tbare = false\n\teditor = vim \\\"x\\\"\n\n[remote \"Origin\"]\n\tfetch = a\n\tfetch = b\n\turl = u\n")
+
+	var v map[string]interface{}
+	err = ini.UnmarshalWithOptions(data, &v, ini.LoadOptions{Dialect: ini.DialectGit})
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, map[string]interface{}{
+		"core": map[interface{}]interface{}{"bare": false, "editor": `vim "x"`},
+		"remote": map[interface{}]interface{}{
+			"Origin": map[interface{}]interface{}{"url": "u", "fetch": []interface{}{"a", "b"}},
+		},
+	})
+}
+
+func (s *S) TestMarshalSystemd(c *C) {
+	type service struct {
+		ExecStartPre []string `ini:"ExecStartPre"`
+		ExecStart    string   `ini:"ExecStart"`
+	}
+	value := struct {
+		Unit    map[string]string `ini:"Unit"`
+		Service service           `ini:"Service"`
+	}{
+		Unit:    map[string]string{"Description": `My "svc" ; x`},
+		Service: service{ExecStartPre: []string{"-/bin/a", "@/bin/b arg"}, ExecStart: "/bin/c"},
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectSystemd})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "[Unit]\nDescription=My \"svc\" ; x\n\n[Service]\nExecStartPre=-/bin/a\nExecStartPre=@/bin/b arg\nExecStart=/bin/c\n")
+}
+
+func (s *S) TestMarshalEncodings(c *C) {
+	value := map[string]interface{}{"S": map[string]string{"k": "Caf\u00e9 \U0001F600"}}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectWindows, Encoding: ini.EncodingUTF16LE})
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, utf16Bytes("[S]\r\nk = Caf\u00e9 \U0001F600\r\n", false, true))
+
+	data, err = ini.MarshalWithOptions(value, ini.DumpOptions{Encoding: ini.EncodingUTF16BE, CRLF: true})
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, utf16Bytes("[S]\r\nk = Caf\u00e9 \U0001F600\r\n", true, true))
+
+	var v map[string]interface{}
+	c.Assert(ini.UnmarshalWithOptions(data, &v, ini.LoadOptions{Dialect: ini.DialectWindows}), IsNil)
+	c.Assert(v, DeepEquals, map[string]interface{}{"S": map[interface{}]interface{}{"k": "Caf\u00e9 \U0001F600"}})
+}
+
+var marshalErrorTests = []struct {
+	value   interface{}
+	dialect ini.Dialect
+	error   string
+}{
+	{map[string]string{"a": "x\ny"}, ini.DialectDefault, "ini: cannot write a value holding a line break or both kinds of quotes"},
+	{map[string]interface{}{"a": []int{1}}, ini.DialectDefault, "ini: cannot marshal type: \\[\\]int"},
+	{map[string]interface{}{"a": 1}, ini.DialectGit, "ini: key 'a' is not in a section"},
+	{map[string]interface{}{"a": 1}, ini.DialectSystemd, "ini: key 'a' is not in a section"},
+	{map[string]interface{}{"Service": map[string]interface{}{"a": map[string]int{"b": 1}}}, ini.DialectSystemd, "ini: systemd keys cannot hold maps"},
+	{map[string]interface{}{"Service": map[string]string{"a": "x\ny"}}, ini.DialectSystemd, "ini: cannot write a systemd value holding a line break"},
+}
+
+func (s *S) TestMarshalProperties(c *C) {
+	value := map[string]interface{}{
+		"server":           map[string]interface{}{"host": "example.com", "port": 8080},
+		"key with=escapes": " a\tb #c",
+		"name":             "Caf\u00e9 \U0001F600",
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectProperties})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "key\\ with\\=escapes=\\ a\\tb \\#c\n"+
+		"name=Caf\\u00e9 \\ud83d\\ude00\n"+
+		"server.host=example.com\n"+
+		"server.port=8080\n")
+
+	var v map[string]interface{}
+	c.Assert(ini.UnmarshalWithOptions(data, &v, ini.LoadOptions{Dialect: ini.DialectProperties}), IsNil)
+	c.Assert(v, DeepEquals, map[string]interface{}{
+		"key with=escapes": " a\tb #c",
+		"name":             "Caf\u00e9 \U0001F600",
+		"server.host":      "example.com",
+		"server.port":      8080,
+	})
+
+	_, err = ini.MarshalWithOptions(map[string]string{"k": "\u20ac"}, ini.DumpOptions{Encoding: ini.EncodingLatin1})
+	c.Assert(err, ErrorMatches, "ini: cannot write the character '\u20ac' in ISO-8859-1")
+}
+
+func (s *S) TestMarshalDotenv(c *C) {
+	value := map[string]interface{}{
+		"NAME":  "two words",
+		"PORT":  8080,
+		"PATH":  "${HOME}/bin",
+		"MULTI": "first\n\"second\"",
+		"EMPTY": "",
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectDotenv})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "EMPTY=\n"+
+		"MULTI=\"first\\n\\\"second\\\"\"\n"+
+		"NAME=\"two words\"\n"+
+		"PATH=\"\\${HOME}/bin\"\n"+
+		"PORT=8080\n")
+
+	var v map[string]interface{}
+	c.Assert(ini.UnmarshalWithOptions(data, &v, ini.LoadOptions{Dialect: ini.DialectDotenv}), IsNil)
+	value["EMPTY"] = nil
+	c.Assert(v, DeepEquals, value)
+}
+
+func (s *S) TestMarshalDesktop(c *C) {
+	value := map[string]interface{}{
+		"Desktop Entry": ini.MapSlice{
+			{Key: "Name", Value: map[string]string{"": "Browser", "de": "Netzbetrachter"}},
+			{Key: "Comment", Value: " Browse the web\nand more"},
+			{Key: "Exec", Value: "\"/opt/browser/bin/browser\" %u"},
+			{Key: "Categories", Value: []string{"Network", "Semi;colon"}},
+			{Key: "Terminal", Value: false},
+		},
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectDesktop})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `[Desktop Entry]
+Name=Browser
+Name[de]=Netzbetrachter
+Comment=\sBrowse the web\nand more
+Exec="/opt/browser/bin/browser" %u
+Categories=Network;Semi\;colon;
+Terminal=false
+`)
+
+	var entry struct {
+		Entry struct {
+			Name       ini.LocaleString `ini:"Name"`
+			Comment    string           `ini:"Comment"`
+			Categories []string         `ini:"Categories"`
+		} `ini:"Desktop Entry"`
+	}
+	err = ini.UnmarshalWithOptions(data, &entry, ini.LoadOptions{Dialect: ini.DialectDesktop, Locale: "de_DE"})
+	c.Assert(err, IsNil)
+	c.Assert(string(entry.Entry.Name), Equals, "Netzbetrachter")
+	c.Assert(entry.Entry.Comment, Equals, " Browse the web\nand more")
+	c.Assert(entry.Entry.Categories, DeepEquals, []string{"Network", "Semi;colon"})
+}
+
+func (s *S) TestMarshalPython(c *C) {
+	value := map[string]interface{}{
+		"topsecret.server.com:22": ini.MapSlice{
+			{Key: "port", Value: 50022},
+			{Key: "motd", Value: "first line\nsecond line"},
+			{Key: "empty", Value: ""},
+		},
+	}
+	data, err := ini.MarshalWithOptions(value, ini.DumpOptions{Dialect: ini.DialectPython})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "[topsecret.server.com:22]\nport = 50022\nmotd = first line\n\tsecond line\nempty =\n")
+
+	var v map[string]map[string]string
+	c.Assert(ini.UnmarshalWithOptions(data, &v, ini.LoadOptions{Dialect: ini.DialectPython}), IsNil)
+	c.Assert(v, DeepEquals, map[string]map[string]string{
+		"topsecret.server.com:22": {"port": "50022", "motd": "first line\nsecond line", "empty": ""},
+	})
+}
+
+func (s *S) TestMarshalErrors(c *C) {
+	for _, item := range marshalErrorTests {
+		_, err := ini.MarshalWithOptions(item.value, ini.DumpOptions{Dialect: item.dialect})
+		c.Assert(err, ErrorMatches, item.error)
+	}
+}
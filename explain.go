@@ -0,0 +1,256 @@
+package ini
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// An Origin is a value of a key and where it is set.
+type Origin struct {
+	// File is the Path of the document that sets the value, or the name of
+	// the file that the document includes.
+	File string
+
+	// Section is the name of the section that sets the value, with its
+	// subsections, or "" for the default section.
+	Section string
+
+	// Line is the line of the key, from 1.
+	Line int
+
+	// Value is the value, as Unmarshal decodes it into an interface{}.
+	Value interface{}
+
+	// Inherited reports whether the section that sets the value is not the
+	// section of the key, but a section that it inherits.
+	Inherited bool
+}
+
+func (o Origin) String() string {
+	where := fmt.Sprintf("line %d", o.Line)
+	if o.File != "" {
+		where = fmt.Sprintf("%s:%d", o.File, o.Line)
+	}
+	section := "the default section"
+	if o.Section != "" {
+		section = "[" + o.Section + "]"
+	}
+	if o.Inherited {
+		return fmt.Sprintf("%s, inherited from %s at %s", diff_format(o.Value), section, where)
+	}
+	return fmt.Sprintf("%s, set in %s at %s", diff_format(o.Value), section, where)
+}
+
+// An Explanation is where the effective value of a key comes from.
+type Explanation struct {
+	// Path is the path of the key, as Get names it.
+	Path string
+
+	// Values are the effective values of the key, with their origins: one
+	// value, or the values of a key that appears several times, in git and
+	// systemd files.
+	Values []Origin
+
+	// Overridden are the other values that the key is set to, in its
+	// section and in the sections that its section inherits, which the
+	// effective values override, in the order of the documents.
+	Overridden []Origin
+}
+
+// Explain returns where the effective value of the key at path in an INI
+// document comes from: the file, the section and the line that set it,
+// whether its section inherits it, and the values that it overrides.  The
+// key is named and its value found as with Get.  The files that the
+// document includes are read again, as LoadOptions reads them.
+func Explain(in []byte, path string, opts LoadOptions) (ex *Explanation, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	d := newDecoder(opts)
+	p := newParser(in, opts)
+	defer p.destroy()
+	var n *node
+	x := &explainer{p: p, d: d, in: in, sources: make(map[string]*source)}
+	if doc := p.parse(); doc != nil {
+		x.collect(doc)
+		n = p.resolve(doc, path)
+	}
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	if n == nil {
+		return nil, &NotFoundError{path}
+	}
+	if !is_value(n) {
+		failf("'%s' is a section or a map of keys, not a key", path)
+	}
+
+	section, key := x.split(path)
+	ex = &Explanation{Path: path}
+	valueNodes := []*node{n}
+	if n.kind == sequenceNode {
+		valueNodes = n.children
+	}
+	effective := make(map[*source_entry]bool)
+	for _, valueNode := range valueNodes {
+		origin := Origin{File: valueNode.file, Line: valueNode.line + 1}
+		if s, ent := x.find(valueNode.file, valueNode.line); ent != nil {
+			effective[ent] = true
+			origin.Section, origin.Line = explain_section(s), ent.line+1
+			origin.Inherited = !p.match(origin.Section, section)
+		}
+		d.unmarshal(valueNode, reflect.ValueOf(&origin.Value).Elem())
+		ex.Values = append(ex.Values, origin)
+	}
+
+	// The other values are read from the sources of the document and of
+	// the files that it includes, in the sections that the section reads.
+	// The keys of the default section below the header of the section are
+	// not read, unless they fall through.
+	visible := x.visible(section)
+	for _, file := range x.files {
+		for _, s := range x.sources[file].sections {
+			name := explain_section(s)
+			if !visible[p.diff_id(name)] {
+				continue
+			}
+			if name == "" && section != "" && file == opts.Path && s.line > x.header && !opts.DefaultFallThrough {
+				continue
+			}
+			for _, ent := range s.entries {
+				if effective[ent] || !p.match(strings.Join(ent.keys, "."), key) {
+					continue
+				}
+				origin := Origin{File: file, Section: name, Line: ent.line + 1, Inherited: !p.match(name, section)}
+				valueNode := &node{kind: scalarNode, tag: ent.tag, value: string(ent.value), style: ent.style}
+				d.unmarshal(valueNode, reflect.ValueOf(&origin.Value).Elem())
+				ex.Overridden = append(ex.Overridden, origin)
+			}
+		}
+	}
+	if len(d.terrors) > 0 {
+		return nil, &TypeError{d.terrors}
+	}
+	return ex, nil
+}
+
+// An explainer finds the origins of values in the sources of the document
+// and of the files that it includes.
+type explainer struct {
+	p       *parser
+	d       *decoder
+	in      []byte
+	files   []string // The files that sources were read from, in order.
+	sources map[string]*source
+	header  int // The line of the first header of the section of the key.
+}
+
+// source returns the source of a file, which is read the first time.
+func (x *explainer) source(file string) *source {
+	if src, ok := x.sources[file]; ok {
+		return src
+	}
+	in := x.in
+	opts := x.p.opts
+	if file != opts.Path {
+		readFile := opts.ReadFile
+		if readFile == nil {
+			readFile = ioutil.ReadFile
+		}
+		var err error
+		if in, err = readFile(file); err != nil {
+			fail(err)
+		}
+		opts.Path = file
+	}
+	src, err := ini_source(in, opts)
+	if err != nil {
+		fail(err)
+	}
+	x.files = append(x.files, file)
+	x.sources[file] = src
+	return src
+}
+
+// collect reads the sources of the document and of the files that it
+// includes, which its nodes name.  The sequences of the values of keys
+// that appear several times name no file.
+func (x *explainer) collect(n *node) {
+	if n.file != "" || x.p.opts.Path == "" {
+		x.source(n.file)
+	}
+	for _, child := range n.children {
+		x.collect(child)
+	}
+}
+
+// find returns the key of a file on a line, and its section, or nil.
+func (x *explainer) find(file string, line int) (*source_section, *source_entry) {
+	for _, s := range x.source(file).sections {
+		for _, ent := range s.entries {
+			if ent.line <= line && line <= ent.end {
+				return s, ent
+			}
+		}
+	}
+	return nil, nil
+}
+
+// split splits a path into the name of the section, with its subsections,
+// and the name of the key, as the sections of the document name them.  A
+// path that names no section names a key of the default section.
+func (x *explainer) split(path string) (section, key string) {
+	key = path
+	for _, s := range x.source(x.p.opts.Path).sections {
+		name := explain_section(s)
+		if name == "" || len(name) <= len(section) || len(path) <= len(name) || path[len(name)] != '.' {
+			continue
+		}
+		if x.p.match(name, path[:len(name)]) {
+			section, key = name, path[len(name)+1:]
+		}
+	}
+	return section, key
+}
+
+// visible returns the ids of the sections that a section reads keys from:
+// itself, the sections that it inherits and the default section.
+func (x *explainer) visible(section string) map[string]bool {
+	visible := map[string]bool{x.p.diff_id(section): true, "": true}
+	x.header = -1
+	sections := x.source(x.p.opts.Path).sections
+	for name := section; name != ""; {
+		var base *source_section
+		for _, s := range sections {
+			if x.p.match(explain_section(s), name) {
+				base = s
+				break
+			}
+		}
+		if base == nil {
+			break
+		}
+		if x.header < 0 {
+			x.header = base.line
+		}
+		if base.base == "" || visible[x.p.diff_id(base.base)] {
+			break
+		}
+		name = base.base
+		visible[x.p.diff_id(name)] = true
+	}
+	if x.header < 0 {
+		x.header = 1 << 30
+	}
+	return visible
+}
+
+// explain_section returns the name of a section of a source, with its
+// subsections, or "" for the default section.
+func explain_section(s *source_section) string {
+	if s.names == nil || len(s.names) == 1 && s.names[0] == DEFAULT_SECTION {
+		return ""
+	}
+	return strings.Join(s.names, ".")
+}
@@ -0,0 +1,98 @@
+package ini_test
+
+import (
+	"os"
+
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var explainDocument = `name = app
+
+[base]
+host = localhost
+port = 8080
+
+[prod : base]
+host = example.com
+port = 1
+port = 2
+`
+
+var explainTests = []struct {
+	path       string
+	values     []ini.Origin
+	overridden []ini.Origin
+}{
+	{
+		"prod.host",
+		[]ini.Origin{{File: "app.ini", Section: "prod", Line: 8, Value: "example.com"}},
+		[]ini.Origin{{File: "app.ini", Section: "base", Line: 4, Value: "localhost", Inherited: true}},
+	}, {
+		"prod.port",
+		[]ini.Origin{{File: "app.ini", Section: "prod", Line: 9, Value: 1}},
+		[]ini.Origin{
+			{File: "app.ini", Section: "base", Line: 5, Value: 8080, Inherited: true},
+			{File: "app.ini", Section: "prod", Line: 10, Value: 2},
+		},
+	}, {
+		"prod.name",
+		[]ini.Origin{{File: "app.ini", Line: 1, Value: "app", Inherited: true}},
+		nil,
+	}, {
+		"base.port",
+		[]ini.Origin{{File: "app.ini", Section: "base", Line: 5, Value: 8080}},
+		nil,
+	}, {
+		"name",
+		[]ini.Origin{{File: "app.ini", Line: 1, Value: "app"}},
+		nil,
+	},
+}
+
+func (s *S) TestExplain(c *C) {
+	for _, item := range explainTests {
+		ex, err := ini.Explain([]byte(explainDocument), item.path, ini.LoadOptions{Path: "app.ini"})
+		c.Assert(err, IsNil, Commentf("path: %s", item.path))
+		c.Assert(ex, DeepEquals, &ini.Explanation{Path: item.path, Values: item.values, Overridden: item.overridden})
+	}
+}
+
+func (s *S) TestExplainInclude(c *C) {
+	files := map[string]string{
+		"/etc/app/main":  "[core]\n\tx = 1\n[include]\n\tpath = extra\n",
+		"/etc/app/extra": "[core]\n\tx = 2\n\ty = 3\n",
+	}
+	readFile := func(filename string) ([]byte, error) {
+		if data, ok := files[filename]; ok {
+			return []byte(data), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	opts := ini.LoadOptions{Dialect: ini.DialectGit, Path: "/etc/app/main", ReadFile: readFile}
+	ex, err := ini.Explain([]byte(files["/etc/app/main"]), "core.x", opts)
+	c.Assert(err, IsNil)
+	c.Assert(ex.Values, DeepEquals, []ini.Origin{
+		{File: "/etc/app/main", Section: "core", Line: 2, Value: 1},
+		{File: "/etc/app/extra", Section: "core", Line: 2, Value: 2},
+	})
+	c.Assert(ex.Overridden, IsNil)
+	c.Assert(ex.Values[1].String(), Equals, "'2', set in [core] at /etc/app/extra:2")
+}
+
+func (s *S) TestExplainErrors(c *C) {
+	_, err := ini.Explain([]byte(explainDocument), "prod.nope", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "prod.nope"})
+	_, err = ini.Explain([]byte(explainDocument), "prod", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: 'prod' is a section or a map of keys, not a key")
+}
+
+func (s *S) TestExplainRepeatedSection(c *C) {
+	ex, err := ini.Explain([]byte("[s]\nx = 1\n[s]\nx = 2\n"), "s.x", ini.LoadOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(ex.Values, DeepEquals, []ini.Origin{{Section: "s", Line: 4, Value: 2}})
+	c.Assert(ex.Overridden, DeepEquals, []ini.Origin{{Section: "s", Line: 2, Value: 1}})
+	_, err = ini.Explain([]byte("[s]\nx = 1\n[s]\ny = 2\n"), "s.x", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.NotFoundError{Path: "s.x"})
+}
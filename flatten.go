@@ -0,0 +1,111 @@
+package ini
+
+// Flatten writes an INI document with its inheritance expanded: every
+// section holds the keys that it inherits, from [child:parent] headers and
+// from the default section, and names no parent, so that INI parsers
+// without inheritance read the same values.  Values are written as the
+// text that they are.  Comments are kept, but not copied with the keys
+// that sections inherit.
+func Flatten(in []byte) ([]byte, error) {
+	return FlattenWithOptions(in, ConvertOptions{})
+}
+
+// FlattenWithOptions is like Flatten, but the document is read and written
+// as described by opts.
+func FlattenWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	opts.Load.RawValues = true
+	doc, err := ini_document(in, opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range doc.entries {
+		e.base = ""
+	}
+	return ini_write(doc, opts.Dump, nil)
+}
+
+// Factorize is the reverse of Flatten: the keys that every section which
+// inherits no section sets to the same value move into a new parent
+// section, named "common", above the first section, and these sections
+// inherit it.  The keys that sections inherit from the default section,
+// or from the sections they already inherit, are not written again.  A
+// document whose sections share no key is written as it is.
+//
+// Only the default dialect has inheritance, and a document that already
+// holds a section with the name of the parent is reported in a
+// *ConvertError.
+func Factorize(in []byte) ([]byte, error) {
+	return FactorizeWithOptions(in, ConvertOptions{})
+}
+
+// FactorizeWithOptions is like Factorize, but the document is read and
+// written as described by opts, and the parent section is named Parent.
+func FactorizeWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	parent := opts.Parent
+	if parent == "" {
+		parent = "common"
+	}
+	if opts.Dump.Dialect != DialectDefault {
+		return nil, &ConvertError{[]string{"the sections of this dialect cannot inherit other sections"}}
+	}
+	opts.Load.RawValues = true
+	doc, err := ini_document(in, opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	doc.strip()
+
+	first := -1
+	var sections []*entry
+	for i, e := range doc.entries {
+		if _, ok := e.value.([]*entry); !ok {
+			continue
+		}
+		if e.key == parent {
+			return nil, &ConvertError{[]string{e.problem("section '%s' already exists", parent)}}
+		}
+		if first < 0 {
+			first = i
+		}
+		if e.base == "" {
+			sections = append(sections, e)
+		}
+	}
+	if common := factorize(sections); len(common) > 0 {
+		for _, section := range sections {
+			own := []*entry{}
+			for _, e := range section.value.([]*entry) {
+				if entry_find(common, e.key) == nil {
+					own = append(own, e)
+				}
+			}
+			section.value, section.base = own, parent
+		}
+		entries := append([]*entry{}, doc.entries[:first]...)
+		entries = append(entries, &entry{key: parent, value: common})
+		doc.entries = append(entries, doc.entries[first:]...)
+	}
+	return ini_write(doc, opts.Dump, nil)
+}
+
+// factorize returns the keys that all of at least two sections set to the
+// same value, in the order of the first section, with its comments.
+func factorize(sections []*entry) (common []*entry) {
+	if len(sections) < 2 {
+		return nil
+	}
+	for _, e := range sections[0].value.([]*entry) {
+		shared := true
+		for _, section := range sections[1:] {
+			other := entry_find(section.value.([]*entry), e.key)
+			if other == nil || !entry_equal(e.value, other.value) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			common = append(common, &entry{key: e.key, value: entry_copy(e.value), comments: e.comments, comment: e.comment})
+		}
+	}
+	return common
+}
@@ -0,0 +1,83 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var flattenTests = []struct {
+	in, out string
+	opts    ini.ConvertOptions
+}{
+	{
+		"# app\nname = app\n\n[base]\nhost = localhost\nport = 80\n\n[web : base]\n# web\nport = 8080\n",
+		"# app\nname = app\n\n[base]\nhost = localhost\nport = 80\nname = app\n\n[web]\n# web\nport = 8080\nhost = localhost\nname = app\n",
+		ini.ConvertOptions{},
+	}, {
+		"[a]\nx = 1\n[b : a]\ny = 2\n[c : b]\nz = 3\n",
+		"[a]\nx = 1\n\n[b]\ny = 2\nx = 1\n\n[c]\nz = 3\ny = 2\nx = 1\n",
+		ini.ConvertOptions{},
+	}, {
+		"[a]\nx = 1\n",
+		"[a]\nx = 1\n",
+		ini.ConvertOptions{},
+	},
+}
+
+func (s *S) TestFlatten(c *C) {
+	for _, item := range flattenTests {
+		out, err := ini.FlattenWithOptions([]byte(item.in), item.opts)
+		c.Assert(err, IsNil)
+		c.Assert(string(out), Equals, item.out, Commentf("in: %q", item.in))
+	}
+}
+
+var factorizeTests = []struct {
+	in, out string
+	opts    ini.ConvertOptions
+}{
+	{
+		"[a]\n# host\nhost = h\nport = 1\nx = 1\n\n[b]\nhost = h\nport = 1\ny = 2\n\n[c]\nport = 1\nhost = h\n",
+		"[common]\n# host\nhost = h\nport = 1\n\n[a:common]\nx = 1\n\n[b:common]\ny = 2\n\n[c:common]\n",
+		ini.ConvertOptions{},
+	}, {
+		"name = app\n\n[a]\nx = 1\nv = 1\n\n[b]\nx = 1\nv = 2\n\n[c : a]\nw = 3\n",
+		"name = app\n\n[shared]\nx = 1\n\n[a:shared]\nv = 1\n\n[b:shared]\nv = 2\n\n[c:a]\nw = 3\n",
+		ini.ConvertOptions{Parent: "shared"},
+	}, {
+		"[a]\nx = 1\n\n[b]\nx = 2\n",
+		"[a]\nx = 1\n\n[b]\nx = 2\n",
+		ini.ConvertOptions{},
+	}, {
+		"[a]\nx = 1\n",
+		"[a]\nx = 1\n",
+		ini.ConvertOptions{},
+	},
+}
+
+func (s *S) TestFactorize(c *C) {
+	for _, item := range factorizeTests {
+		out, err := ini.FactorizeWithOptions([]byte(item.in), item.opts)
+		c.Assert(err, IsNil)
+		c.Assert(string(out), Equals, item.out, Commentf("in: %q", item.in))
+	}
+}
+
+func (s *S) TestFactorizeFlatten(c *C) {
+	in := "[a]\nhost = h\nx = 1\n\n[b]\nhost = h\ny = 2\n"
+	out, err := ini.Factorize([]byte(in))
+	c.Assert(err, IsNil)
+	out, err = ini.Flatten(out)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, "[common]\nhost = h\n\n[a]\nx = 1\nhost = h\n\n[b]\ny = 2\nhost = h\n")
+}
+
+func (s *S) TestFlattenErrors(c *C) {
+	_, err := ini.Flatten([]byte("[a] x\n"))
+	c.Assert(err, ErrorMatches, "ini: must have a line break before the first section key")
+	_, err = ini.Factorize([]byte("[common]\nx = 1\n[a]\nx = 1\n"))
+	c.Assert(err, ErrorMatches, "ini: cannot convert:\n  line 1: section 'common' already exists")
+	_, err = ini.FactorizeWithOptions([]byte("[a]\nx = 1\n"), ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectGit}})
+	c.Assert(err, ErrorMatches, "ini: cannot convert:\n  the sections of this dialect cannot inherit other sections")
+}
@@ -0,0 +1,252 @@
+package ini
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// FormatOptions changes how Format rewrites documents.  The zero value
+// formats documents of the default dialect, in the order of their keys.
+type FormatOptions struct {
+	// Load reads the document that is formatted.  Its dialect is the
+	// dialect of the output.
+	Load LoadOptions
+
+	// SortKeys sorts the keys of every section by name, along with their
+	// comments.  Sections stay in the order of the document.
+	SortKeys bool
+}
+
+// Format rewrites an INI document in the canonical layout of its dialect:
+// one blank line above every section header and none elsewhere, the same
+// spacing around every delimiter, no trailing whitespace, and values
+// quoted only when they need to be, in the style that Marshal picks.
+// Comments are kept, on their lines or above the key or the section that
+// follows them, and so are the encoding and the line breaks.  Comments
+// start with the first of the CommentPrefixes of Load when the dialect's
+// own comment character is not one of them, and keys are delimited from
+// their values with the first of its KeyValueDelimiters when '=' is not
+// one of them, so that the document reads back with the same options.
+//
+// The keys of a section are neither merged nor inherited, so that the
+// formatted document reads back to the same values.  PHP values keep their
+// quotes, which make strings of them, and dotenv values are kept as they
+// are, with their variables and escape sequences.
+func Format(in []byte, opts FormatOptions) (out []byte, err error) {
+	src, err := ini_source(in, opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	if len(src.errors) > 0 {
+		return nil, &ParseError{src.errors}
+	}
+	defer handleErr(&err)
+	e := newEncoder(DumpOptions{Dialect: opts.Load.Dialect, Encoding: src.encoding, CRLF: src.crlf}.preset())
+	defer e.destroy()
+	load := opts.Load.preset()
+	indicator := e.emitter.indicator
+	if indicator == 0 {
+		indicator = '#'
+	}
+	if load.CommentPrefixes != "" && strings.IndexByte(load.CommentPrefixes, indicator) < 0 {
+		ini_emitter_set_comment_indicator(&e.emitter, load.CommentPrefixes[0])
+	}
+	if load.KeyValueDelimiters != "" && strings.IndexByte(load.KeyValueDelimiters, '=') < 0 {
+		ini_emitter_set_delimiter(&e.emitter, load.KeyValueDelimiters[0])
+	}
+	if opts.Load.Dialect == DialectDotenv {
+		ini_emitter_set_verbatim(&e.emitter, true)
+	}
+	e.format(src, opts.SortKeys)
+	e.finish()
+	return e.out, nil
+}
+
+// A source is a document as its events give it, with its comments, for
+// the formatter and the linter.  Nothing is merged or inherited.
+type source struct {
+	sections []*source_section
+	comments []string // The comments below the last key.
+	encoding Encoding
+	crlf     bool
+	errors   []string // The problems found in recovery mode.
+	lines    []int    // The lines of the problems, from 0.
+}
+
+// A source_section is a section header and the keys below it.
+type source_section struct {
+	names    []string // nil for the keys above the first header
+	base     string   // The inherited section.
+	line     int      // The line of the header, from 0.
+	comments []string // The comment lines above the header.
+	entries  []*source_entry
+}
+
+// A source_entry is a key and its value.
+type source_entry struct {
+	keys      []string // The key, and its dotted keys.
+	value     []byte
+	tag       string
+	style     ini_scalar_style_t
+	line, end int      // The lines of the key and of the end of the value.
+	export    bool     // Does the key of a dotenv file follow export?
+	comments  []string // The comment lines above the key.
+	comment   string   // The comment on the line of the value.
+}
+
+// ini_source reads the sections, the keys and the comments of a document.
+// A comment goes above the section or the key below it, or after the value
+// on its line.
+func ini_source(in []byte, opts LoadOptions) (src *source, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	p := newParser(in, opts)
+	defer p.destroy()
+	ini_parser_set_keep_comments(&p.parser, true)
+	src = &source{crlf: bytes.Contains(in, []byte("\r\n")) ||
+		bytes.Contains(in, []byte("\r\x00\n\x00")) || bytes.Contains(in, []byte("\x00\r\x00\n"))}
+	var lines [][]byte
+	if opts.Dialect == DialectDotenv {
+		lines = bytes.Split(bytes.TrimPrefix(in, []byte("\xef\xbb\xbf")), []byte("\n"))
+	}
+
+	p.skip()
+	for p.event.typ != ini_DOCUMENT_END_EVENT {
+		s := &source_section{line: p.event.start_mark.line}
+		names := source_names(p)
+		if p.event.typ == ini_SECTION_INHERIT_EVENT {
+			s.names = names
+			if base := string(p.event.value); base != DEFAULT_SECTION {
+				s.base = base
+			}
+			p.skip()
+		}
+		p.skip()
+		for p.event.typ != ini_SECTION_ENTRY_EVENT {
+			ent := &source_entry{line: p.event.start_mark.line}
+			if lines != nil {
+				ent.export = dotenv_export(lines, p.event.start_mark)
+			}
+			ent.keys = source_names(p)
+			ent.value = append([]byte{}, p.event.value...)
+			ent.tag = string(p.event.tag)
+			ent.style = p.event.scalar_style()
+			ent.end = p.event.end_mark.line
+			s.entries = append(s.entries, ent)
+			p.skip()
+		}
+		src.sections = append(src.sections, s)
+		p.skip()
+	}
+	src.errors = p.errors()
+	for _, problem := range p.parser.errors {
+		src.lines = append(src.lines, problem.problem_mark.line)
+	}
+
+	switch p.parser.encoding {
+	case ini_UTF16LE_ENCODING:
+		src.encoding = EncodingUTF16LE
+	case ini_UTF16BE_ENCODING:
+		src.encoding = EncodingUTF16BE
+	case ini_LATIN1_ENCODING:
+		src.encoding = EncodingLatin1
+	}
+
+	comments, next := p.parser.comment_list, 0
+	above := func(line int) (lines []string) {
+		for next < len(comments) && comments[next].mark.line < line {
+			lines = append(lines, string(comments[next].value))
+			next++
+		}
+		return lines
+	}
+	for _, s := range src.sections {
+		if s.names != nil {
+			s.comments = above(s.line + 1)
+		}
+		for _, ent := range s.entries {
+			ent.comments = above(ent.line)
+			if next < len(comments) && comments[next].mark.line <= ent.end {
+				ent.comment = string(comments[next].value)
+				next++
+			}
+		}
+	}
+	src.comments = above(len(in) + 1)
+	return src, nil
+}
+
+// source_names reads the name of a section or a key, with the names of its
+// subsections or its dotted keys, and stops at the event after them.
+func source_names(p *parser) (names []string) {
+	for {
+		if p.event.typ != ini_SCALAR_EVENT {
+			failf("expected a name, got %s", p.event.event_type())
+		}
+		names = append(names, string(p.event.value))
+		p.skip()
+		if p.event.typ != ini_MAPPING_EVENT {
+			return names
+		}
+		p.skip()
+	}
+}
+
+// dotenv_export reports whether the key of a dotenv file at a mark follows
+// the export keyword, which the scanner drops.
+func dotenv_export(lines [][]byte, mark ini_mark_t) bool {
+	if mark.line >= len(lines) {
+		return false
+	}
+	prefix := []rune(string(lines[mark.line]))
+	if mark.column < len(prefix) {
+		prefix = prefix[:mark.column]
+	}
+	return strings.TrimSpace(string(prefix)) == "export"
+}
+
+// format marshals the sections and the keys of a source document, with
+// their comments.  PHP values keep their quotes, and so do verbatim values.
+func (e *encoder) format(src *source, sortKeys bool) {
+	for _, s := range src.sections {
+		e.comments(s.comments, "")
+		names, base := s.names, s.base
+		if names == nil {
+			names = []string{DEFAULT_SECTION}
+		}
+		if base == "" {
+			base = DEFAULT_SECTION
+		}
+		e.key(names)
+		e.must(ini_section_inherit_event_initialize(&e.event, []byte(base)))
+		e.emit()
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+		entries := s.entries
+		if sortKeys {
+			entries = append([]*source_entry{}, entries...)
+			sort.SliceStable(entries, func(i, j int) bool {
+				return strings.Join(entries[i].keys, ".") < strings.Join(entries[j].keys, ".")
+			})
+		}
+		for _, ent := range entries {
+			e.comments(ent.comments, "")
+			keys := ent.keys
+			if ent.export {
+				keys = append([]string{"export " + keys[0]}, keys[1:]...)
+			}
+			e.key(keys)
+			style := ini_PLAIN_SCALAR_STYLE
+			if e.dialect == DialectPHP || e.emitter.verbatim {
+				style = ent.style
+			}
+			e.must(ini_scalar_event_initialize(&e.event, ent.value, style))
+			e.event.comment = []byte(ent.comment)
+			e.emit()
+		}
+		e.must(ini_section_entry_event_initialize(&e.event))
+		e.emit()
+	}
+	e.comments(src.comments, "")
+}
@@ -0,0 +1,84 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var formatTests = []struct {
+	data string
+	opts ini.FormatOptions
+	out  string
+}{
+	{
+		"# top\nname=x   \n[b]\n  z=1 ; c\n  a =  'q r'  \n\n\n; below\n[c : b]\nk=\"NO\"\n",
+		ini.FormatOptions{},
+		"# top\nname = x\n\n[b]\nz = 1 # c\na = q r\n\n# below\n[c:b]\nk = NO\n",
+	}, {
+		"[b]\nz=1\n# the a\na=2\n",
+		ini.FormatOptions{SortKeys: true},
+		"[b]\n# the a\na = 2\nz = 1\n",
+	}, {
+		"[core]\r\n\tx=1 # c\r\n[remote \"o\"]\r\nurl=u\r\n",
+		ini.FormatOptions{Load: ini.LoadOptions{Dialect: ini.DialectGit}},
+		"[core]\r\n\tx = 1 # c\r\n\r\n[remote \"o\"]\r\n\turl = u\r\n",
+	}, {
+		"[remote  \"o\" ]\nurl=u\n",
+		ini.FormatOptions{},
+		"[remote \"o\"]\nurl = u\n",
+	}, {
+		"a=\"x\"\nb= off ;c\n",
+		ini.FormatOptions{Load: ini.LoadOptions{Dialect: ini.DialectPHP}},
+		"a = \"x\"\nb = off ; c\n",
+	}, {
+		"export A=\"$B x\"\nB = 'y'  # c\n",
+		ini.FormatOptions{Load: ini.LoadOptions{Dialect: ini.DialectDotenv}},
+		"export A=\"$B x\"\nB='y' # c\n",
+	}, {
+		"[s]\nk = a\n  b\n",
+		ini.FormatOptions{Load: ini.LoadOptions{Dialect: ini.DialectPython}},
+		"[s]\nk = a\n\tb\n",
+	}, {
+		"% top\n[s]\nk=v % c\nq = '50%'\n",
+		ini.FormatOptions{Load: ini.LoadOptions{CommentPrefixes: "%"}},
+		"% top\n[s]\nk = v % c\nq = \"50%\"\n",
+	}, {
+		"[s]\nk :v\nj: a=b\n",
+		ini.FormatOptions{Load: ini.LoadOptions{KeyValueDelimiters: ":"}},
+		"[s]\nk: v\nj: \"a=b\"\n",
+	}, {
+		"[s]\nk   v\n",
+		ini.FormatOptions{Load: ini.LoadOptions{KeyValueDelimiters: " "}},
+		"[s]\nk v\n",
+	},
+}
+
+func (s *S) TestFormat(c *C) {
+	for _, item := range formatTests {
+		out, err := ini.Format([]byte(item.data), item.opts)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(string(out), Equals, item.out, Commentf("data: %q", item.data))
+		again, err := ini.Format(out, item.opts)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(string(again), Equals, item.out, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestFormatRoundTrip(c *C) {
+	for _, item := range formatTests {
+		out, err := ini.Format([]byte(item.data), item.opts)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		var before, after map[string]interface{}
+		c.Assert(ini.UnmarshalWithOptions([]byte(item.data), &before, item.opts.Load), IsNil)
+		c.Assert(ini.UnmarshalWithOptions(out, &after, item.opts.Load), IsNil, Commentf("out: %q", out))
+		c.Assert(after, DeepEquals, before, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestFormatErrors(c *C) {
+	_, err := ini.Format([]byte("[a] x\n"), ini.FormatOptions{})
+	c.Assert(err, ErrorMatches, "ini: must have a line break before the first section key")
+	_, err = ini.Format([]byte("a = 1\n[b] x\n"), ini.FormatOptions{Load: ini.LoadOptions{Recover: true}})
+	c.Assert(err, ErrorMatches, "ini: parse errors:\n  line 2: .*")
+}
@@ -3,6 +3,7 @@ package ini
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"strings"
 	"sync"
@@ -50,10 +51,154 @@ var (
 	itemType = reflect.TypeOf(map[string]interface{}{})
 )
 
+// LoadOptions changes how INI documents are scanned, parsed and decoded.
+// The zero value gives the behavior of Unmarshal.
+type LoadOptions struct {
+	// Recover makes the scanner skip to the next line break after an
+	// error instead of stopping at the first one. Every bad line is
+	// reported in a *ParseError, and the valid lines are still decoded.
+	Recover bool
+
+	// Insensitive matches section and key names regardless of case when
+	// merging sections and keys, looking up inherited sections and
+	// decoding into struct fields. The spelling of the first occurrence
+	// of a name is kept, and so is the first value of a key that is set
+	// several times, as when names are matched with their case.
+	Insensitive bool
+
+	// KeyValueDelimiters lists the characters that delimit a key from
+	// its value, such as "=:". A space or a tab makes any run of blanks
+	// a delimiter, and values may hold the listed characters. Only '=' is
+	// accepted when it is empty, and values may not hold another '=';
+	// "=" accepts the same keys but lets values hold one, as in a=b=c.
+	KeyValueDelimiters string
+
+	// CommentPrefixes lists the characters that start a comment.
+	// '#' and ';' are used when it is empty.
+	CommentPrefixes string
+
+	// IgnoreInlineComment only accepts comments on lines of their own,
+	// so a comment prefix inside a key or a value is kept verbatim.
+	IgnoreInlineComment bool
+
+	// SpaceBeforeInlineComment only accepts a comment after a key or a
+	// value when the comment prefix follows a space or a tab, such as
+	// in "url = http://host/#frag ; comment".
+	SpaceBeforeInlineComment bool
+
+	// SectionNameChars lists the characters allowed in section names on
+	// top of letters, digits, '_' and '-', such as " ." for names like
+	// [server eu.west]. Quoted section names, such as ["a b"], may always
+	// hold any character, and so may the quoted names of subsections, such
+	// as [remote "origin"], which nest in their section as in git. Python
+	// files read the quotes as part of the name.
+	SectionNameChars string
+
+	// NestedSections reads dotted section names such as [a.b] as the
+	// section b nested in the section a, in the same way dotted keys
+	// are read as nested maps.
+	NestedSections bool
+
+	// FlatKeys keeps dotted keys such as spring.datasource.url as plain
+	// keys, instead of reading them as maps nested by the dots.
+	FlatKeys bool
+
+	// KeyConflict decides what happens when dotted keys use a key both
+	// for a value and for a map, as in "a = 1" and "a.b = 2".
+	KeyConflict KeyConflict
+
+	// DefaultFallThrough makes the keys of the default section fall
+	// through to every other section that lacks them, wherever they are
+	// in the document, [default] sections below the other sections
+	// included.  Without it, a section only inherits the keys of the
+	// default section above its header.
+	DefaultFallThrough bool
+
+	// AllowBooleanKeys reads a key that stands without a delimiter and a
+	// value, such as "bare" in git or MySQL files, as a key with the
+	// value true.
+	AllowBooleanKeys bool
+
+	// Dialect selects a preset for the syntax of a family of INI files,
+	// on top of the other options.
+	Dialect Dialect
+
+	// Path is the path of the document, when it was read from a file.
+	// Files included by a relative path are looked up next to it.
+	Path string
+
+	// ReadFile reads the included files.  ioutil.ReadFile is used when
+	// it is nil.
+	ReadFile func(filename string) ([]byte, error)
+
+	// GitDir is the git directory, such as /home/me/project/.git, that
+	// the includeIf "gitdir:" conditions of git files are matched against.
+	GitDir string
+
+	// GitBranch is the checked out branch that the includeIf "onbranch:"
+	// conditions of git files are matched against.
+	GitBranch string
+
+	// Constants holds the PHP constants that an unquoted value of a PHP
+	// file is replaced with when it names one of them.
+	Constants map[string]string
+
+	// MySQLVersion is the version, such as "8.0", of the server that reads
+	// a MySQL file.  The [name-version] groups of that version are merged
+	// into the [name] groups, and the groups of other versions are
+	// dropped.  Every group is kept as it is when it is empty.
+	MySQLVersion string
+
+	// Locale is the locale, such as "de_DE.UTF-8", that a LocaleString of
+	// a desktop entry file picks its value for.  It defaults to the
+	// LC_ALL, LC_MESSAGES or LANG environment variable.
+	Locale string
+
+	// Interpolation is the syntax of the references to other values in
+	// the values of a Python configparser file.
+	Interpolation Interpolation
+
+	// Encoding is the character encoding of the document.
+	Encoding Encoding
+
+	// RawValues decodes every value as a string, without booleans,
+	// numbers or nulls, and leaves the ${NAME} variables and constants of
+	// PHP files as they are, as PHP's INI_SCANNER_RAW mode does.
+	RawValues bool
+}
+
+// KeyConflict is a policy for keys that are used both for a value and,
+// with dotted keys, for a map of values.
+type KeyConflict int
+
+const (
+	// KeyConflictOverwrite keeps the later of the value and the map.
+	KeyConflictOverwrite KeyConflict = iota
+
+	// KeyConflictKeep keeps the earlier of the value and the map.
+	KeyConflictKeep
+
+	// KeyConflictError fails, or reports the line in recovery mode.
+	KeyConflictError
+)
+
 func Unmarshal(in []byte, out interface{}) (err error) {
+	return UnmarshalWithOptions(in, out, LoadOptions{})
+}
+
+// InsensitiveUnmarshal is like Unmarshal, but section and key names
+// are matched regardless of case.
+func InsensitiveUnmarshal(in []byte, out interface{}) (err error) {
+	return UnmarshalWithOptions(in, out, LoadOptions{Insensitive: true})
+}
+
+// UnmarshalWithOptions is like Unmarshal, but the document is read
+// as described by opts.
+func UnmarshalWithOptions(in []byte, out interface{}, opts LoadOptions) (err error) {
 	defer handleErr(&err)
-	d := newDecoder()
-	p := newParser(in)
+	opts = opts.preset()
+	d := newDecoder(opts)
+	p := newParser(in, opts)
 	defer p.destroy()
 	node := p.parse()
 	if node != nil {
@@ -63,17 +208,82 @@ func Unmarshal(in []byte, out interface{}) (err error) {
 		}
 		d.unmarshal(node, v)
 	}
+	if len(p.parser.errors) > 0 {
+		return &ParseError{p.errors()}
+	}
 	if len(d.terrors) > 0 {
 		return &TypeError{d.terrors}
 	}
 	return nil
 }
 
+// UnmarshalFile is like UnmarshalWithOptions, but the document is read
+// from the named file, which becomes the Path of opts.
+func UnmarshalFile(filename string, out interface{}, opts LoadOptions) error {
+	in, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	opts.Path = filename
+	return UnmarshalWithOptions(in, out, opts)
+}
+
+// DumpOptions changes how values are marshaled into INI documents.
+// The zero value gives the behavior of Marshal.
+type DumpOptions struct {
+	// Dialect selects the syntax of the output.
+	Dialect Dialect
+
+	// Encoding is the character encoding of the output.  UTF-16 output
+	// starts with a byte order mark.
+	Encoding Encoding
+
+	// CRLF ends the lines of the output with CR LF instead of LF.
+	CRLF bool
+}
+
+// An Encoding is the character encoding of a document.
+type Encoding int
+
+const (
+	// EncodingAuto reads documents in the encoding of their byte order
+	// mark, or else in UTF-16 when they start with an ASCII character in
+	// two bytes, or else in UTF-8.  It writes UTF-8.
+	EncodingAuto Encoding = iota
+
+	EncodingUTF8    // UTF-8, without a byte order mark.
+	EncodingUTF16LE // UTF-16, little endian, with a byte order mark.
+	EncodingUTF16BE // UTF-16, big endian, with a byte order mark.
+	EncodingLatin1  // ISO-8859-1.
+)
+
+// encoding returns the reader and writer encoding of e.
+func (e Encoding) encoding() ini_encoding_t {
+	switch e {
+	case EncodingUTF8:
+		return ini_UTF8_ENCODING
+	case EncodingUTF16LE:
+		return ini_UTF16LE_ENCODING
+	case EncodingUTF16BE:
+		return ini_UTF16BE_ENCODING
+	case EncodingLatin1:
+		return ini_LATIN1_ENCODING
+	}
+	return ini_ANY_ENCODING
+}
+
 func Marshal(in interface{}) (out []byte, err error) {
+	return MarshalWithOptions(in, DumpOptions{})
+}
+
+// MarshalWithOptions is like Marshal, but the document is written as
+// described by opts.
+func MarshalWithOptions(in interface{}, opts DumpOptions) (out []byte, err error) {
 	defer handleErr(&err)
-	e := newEncoder()
+	opts = opts.preset()
+	e := newEncoder(opts)
 	defer e.destroy()
-	e.marshal(reflect.ValueOf(in))
+	e.document(reflect.ValueOf(in))
 	e.finish()
 	out = e.out
 	return
@@ -113,6 +323,17 @@ func (e *TypeError) Error() string {
 	return fmt.Sprintf("ini: unmarshal errors:\n  %s", strings.Join(e.Errors, "\n  "))
 }
 
+// A ParseError is returned by UnmarshalWithOptions in recovery mode when
+// one or more lines of the INI document cannot be parsed. When this error
+// is returned, the valid lines are still unmarshaled.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ini: parse errors:\n  %s", strings.Join(e.Errors, "\n  "))
+}
+
 // --------------------------------------------------------------------------
 // Maintain a mapping of keys to structure field indexes
 
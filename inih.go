@@ -6,6 +6,19 @@ import (
 
 const DEFAULT_SECTION = "default"
 
+// The stream encoding.
+type ini_encoding_t int
+
+const (
+	// Let the parser choose the encoding.
+	ini_ANY_ENCODING ini_encoding_t = iota
+
+	ini_UTF8_ENCODING    // The default UTF-8 encoding.
+	ini_UTF16LE_ENCODING // The UTF-16-LE encoding with BOM.
+	ini_UTF16BE_ENCODING // The UTF-16-BE encoding with BOM.
+	ini_LATIN1_ENCODING  // The ISO-8859-1 encoding.
+)
+
 type ini_break_t int
 
 // Line break types.
@@ -18,6 +31,22 @@ const (
 	ini_CRLN_BREAK // Use CR LN for line breaks (DOS style).
 )
 
+type ini_dialect_t int
+
+// Dialects.
+const (
+	// The syntax described in the package documentation.
+	ini_DEFAULT_DIALECT ini_dialect_t = iota
+
+	ini_GIT_DIALECT     // The syntax of git-config files.
+	ini_SYSTEMD_DIALECT // The syntax of systemd unit files.
+	ini_MYSQL_DIALECT      // The syntax of MySQL option files.
+	ini_PROPERTIES_DIALECT // The syntax of Java properties files.
+	ini_DOTENV_DIALECT     // The syntax of dotenv files.
+	ini_DESKTOP_DIALECT    // The syntax of desktop entry files.
+	ini_PYTHON_DIALECT     // The syntax of Python configparser files.
+)
+
 type ini_error_type_t int
 
 // Many bad things could happen with the parser and emitter.
@@ -143,6 +172,7 @@ const (
     ini_MAPPING_EVENT  // An MAPPING event.
     ini_SCALAR_EVENT  // An SCALAR event.
 	ini_COMMENT_EVENT // A COMMENT event.
+	ini_MARKER_EVENT  // A MARKER event.
 )
 
 // The event structure.
@@ -162,6 +192,9 @@ type ini_event_t struct {
 
 	// The style (for ini_ELEMENT_START_EVENT).
 	style ini_style_t
+
+	// The comment on the line of the value (for ini_SCALAR_EVENT).
+	comment []byte
 }
 
 func (e *ini_event_t) event_type() string {
@@ -182,6 +215,8 @@ func (e *ini_event_t) event_type() string {
 		return "ini_SCALAR_EVENT"
 	case ini_COMMENT_EVENT:
 		return "ini_COMMENT_EVENT"
+	case ini_MARKER_EVENT:
+		return "ini_MARKER_EVENT"
 	}
 	return "<unknown token>"
 }
@@ -200,6 +235,7 @@ const (
     ini_MAP_TAG = "map"
 	
 	ini_SECTION_TAG = "section"
+	ini_LOCALE_TAG  = "locale" // The tag 'locale' for the values of a localized key, by locale.
 
     ini_DEFAULT_SCALAR_TAG   = ini_STR_TAG // The default scalar tag is str
 )
@@ -231,6 +267,7 @@ const (
 	ini_PARSE_SECTION_FIRST_START_STATE // Expect SECTION-FIRST-ENTRY.
 	ini_PARSE_SECTION_START_STATE       // Expect SECTION-ENTRY.
 	ini_PARSE_SECTION_INHERIT_STATE 	// Expect SECTION-INHERIT.
+	ini_PARSE_SECTION_SUBSECTION_STATE  // Expect the name of a subsection.
 	ini_PARSE_SECTION_ENTRY_STATE       // Expect SECTION-ENTRY.
 	ini_PARSE_SECTION_KEY_STATE   // Expect a KEY.
     ini_PARSE_SECTION_VALUE_STATE   // Expect a VALUE.
@@ -251,6 +288,8 @@ func (ps ini_parser_state_t) String() string {
 		return "ini_PARSE_SECTION_START_STATE"
 	case ini_PARSE_SECTION_INHERIT_STATE:
 		return "ini_PARSE_SECTION_INHERIT_STATE"
+	case ini_PARSE_SECTION_SUBSECTION_STATE:
+		return "ini_PARSE_SECTION_SUBSECTION_STATE"
 	case ini_PARSE_SECTION_ENTRY_STATE:
 		return "ini_PARSE_SECTION_ENTRY_STATE"
 	case ini_PARSE_SECTION_KEY_STATE:
@@ -304,6 +343,8 @@ type ini_parser_t struct {
 	raw_buffer     []byte // The raw buffer.
 	raw_buffer_pos int    // The current position of the buffer.
 
+	encoding ini_encoding_t // The input encoding.
+
 	offset int        // The offset of the current position (in bytes).
 	mark   ini_mark_t // The mark of the current position.
 
@@ -313,6 +354,26 @@ type ini_parser_t struct {
 	document_start_produced bool // Have we started to scan the input stream?
 	document_end_produced   bool // Have we reached the end of the input stream?
 
+	dialect ini_dialect_t // The syntax of the input.
+
+	delimiters      []byte // The key-value delimiters, '=' if not set.
+	blank_delimiter bool   // Can blanks delimit a key from its value?
+	section_header  bool   // Is the scanner inside a section header?
+	value_allowed   bool   // May a VALUE token follow?
+	key_column      int    // The column of the current key, that Python continuation lines are indented from.
+
+	section_chars []byte // The extra characters allowed in plain section keys.
+	flat_keys     bool   // Are dotted keys kept as plain keys?
+	boolean_keys  bool   // May a key stand without a value?
+
+	comments             []byte // The comment prefixes, '#' and ';' if not set.
+	no_inline_comments   bool   // Do comments only start at the beginning of a line?
+	inline_comment_blank bool   // Must an inline comment follow a blank?
+	blank_before         bool   // Did a blank precede the current scalar?
+
+	keep_comments bool            // Are the comments collected?
+	comment_list  []ini_comment_t // The comments collected.
+
 	tokens          []ini_token_t // The tokens queue.
 	tokens_head     int           // The head of the tokens queue.
 	tokens_parsed   int           // The number of tokens fetched from the queue.
@@ -322,6 +383,22 @@ type ini_parser_t struct {
 	state  ini_parser_state_t   // The current parser state.
 	states []ini_parser_state_t // The parser states stack.
 	marks  []ini_mark_t         // The stack of marks.
+
+	// Recovery stuff
+	recover bool            // Skip to the next line break after an error?
+	errors  []ini_problem_t // The problems collected while recovering.
+}
+
+// A comment collected for the converters.
+type ini_comment_t struct {
+	value []byte     // The text of the comment, without its prefix.
+	mark  ini_mark_t // Where the comment starts.
+}
+
+// A problem collected in recovery mode.
+type ini_problem_t struct {
+	problem      string     // Error description.
+	problem_mark ini_mark_t // Where the problem was found.
 }
 
 // Emitter Definitions
@@ -349,16 +426,15 @@ const (
 	// Expect DOCUMENT-START.
 	ini_EMIT_DOCUMENT_START_STATE ini_emitter_state_t = iota
 
-	ini_EMIT_DOCUMENT_END_STATE           // Expect DOCUMENT-END.
-	ini_EMIT_FIRST_SECTION_START_STATE    // Expect the first section
-	ini_EMIT_SECTION_START_STATE          // Expect the start of section.
-	ini_EMIT_SECTION_FIRST_NODE_KEY_STATE // Expect the start of section.
-	ini_EMIT_ELEMENT_KEY_STATE            // Expect the start of section.
-	ini_EMIT_ELEMENT_VALUE_STATE          // Expect the node.
-	ini_EMIT_SECTION_END_STATE            // Expect the end of section.
-	ini_EMIT_COMMENT_START_STATE          // Expect the start of section.
-	ini_EMIT_COMMENT_VALUE_STATE          // Expect the content of section.
-	ini_EMIT_COMMENT_END_STATE            // Expect the end of section.
+	ini_EMIT_FIRST_SECTION_START_STATE // Expect the name of the first section or DOCUMENT-END.
+	ini_EMIT_SECTION_START_STATE       // Expect the name of a section or DOCUMENT-END.
+	ini_EMIT_SECTION_INHERIT_STATE     // Expect MAPPING or SECTION-INHERIT.
+	ini_EMIT_SECTION_SUBSECTION_STATE  // Expect the name of a subsection.
+	ini_EMIT_SECTION_ENTRY_STATE       // Expect SECTION-ENTRY.
+	ini_EMIT_SECTION_KEY_STATE         // Expect a key or SECTION-ENTRY.
+	ini_EMIT_SECTION_MAP_KEY_STATE     // Expect a key nested in a map.
+	ini_EMIT_SECTION_VALUE_STATE       // Expect MAPPING or a value.
+	ini_EMIT_END_STATE                 // Expect nothing.
 )
 
 // The emitter structure.
@@ -385,10 +461,16 @@ type ini_emitter_t struct {
 	raw_buffer     []byte // The raw buffer.
 	raw_buffer_pos int    // The current position of the buffer.
 
+	encoding ini_encoding_t // The stream encoding.
+
 	// Emitter stuff
 
-	unicode    bool        // Allow unescaped non-ASCII characters?
-	line_break ini_break_t // The preferred line break.
+	unicode    bool          // Allow unescaped non-ASCII characters?
+	line_break ini_break_t   // The preferred line break.
+	dialect    ini_dialect_t // The syntax of the output.
+	indicator  byte          // The character that starts comments.
+	delimiter  byte          // The character that delimits keys from values, or 0 for '='.
+	verbatim   bool          // Are values written as they are?
 
 	state  ini_emitter_state_t   // The current emitter state.
 	states []ini_emitter_state_t // The stack of states.
@@ -396,26 +478,12 @@ type ini_emitter_t struct {
 	events      []ini_event_t // The event queue.
 	events_head int           // The head of the event queue.
 
-	level int // The current flow level.
-
-	root_context    bool // Is it the document root context?
-	mapping_context bool // Is it a mapping context?
+	section         [][]byte // The names of the current section and its subsections.
+	first_section   bool     // Is the current section the first one?
+	section_comment bool     // Do comments stand above the header of the next section?
 
 	line       int  // The current line.
 	column     int  // The current column.
 	whitespace bool // If the last character was a whitespace?
 	open_ended bool // If an explicit document end is required?
-
-	// Scalar analysis.
-	scalar_data struct {
-		value                 []byte             // The scalar value.
-		multiline             bool               // Does the scalar contain line breaks?
-		single_quoted_allowed bool               // Can the scalar be expressed in the single quoted style?
-		style                 ini_scalar_style_t // The output style.
-	}
-
-	// Dumper stuff
-
-	opened bool // If the document was already opened?
-	closed bool // If the document was already closed?
 }
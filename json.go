@@ -0,0 +1,250 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ToJSON converts an INI document into a JSON object.  Sections become
+// objects, dotted keys nested objects and the keys of the default section
+// the keys of the outer object.  Values keep the type they decode into
+// with Unmarshal, and keys stay in the order of the document.
+func ToJSON(in []byte) ([]byte, error) {
+	return ToJSONWithOptions(in, LoadOptions{})
+}
+
+// ToJSONWithOptions is like ToJSON, but the document is read as described
+// by opts.  With RawValues, every value becomes a JSON string.
+func ToJSONWithOptions(in []byte, opts LoadOptions) (out []byte, err error) {
+	defer handleErr(&err)
+	opts = opts.preset()
+	d := newDecoder(opts)
+	p := newParser(in, opts)
+	defer p.destroy()
+	object := MapSlice{}
+	if n := p.parse(); n != nil {
+		object = d.jsonObject(n, object)
+	}
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	if len(d.terrors) > 0 {
+		return nil, &TypeError{d.terrors}
+	}
+	var buf bytes.Buffer
+	json_write(&buf, object, "")
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// FromJSON converts a JSON object into an INI document.  It is the
+// reverse of ToJSON: objects become sections, objects nested in them
+// dotted keys, and the other values of the outer object the keys of the
+// default section.  Keys stay in the order of the JSON object.  Integers
+// keep their precision up to 64 bits, and keys holding a '.' are only
+// converted in the dialects that do not read dotted keys as nested keys.
+func FromJSON(in []byte) ([]byte, error) {
+	return FromJSONWithOptions(in, DumpOptions{})
+}
+
+// FromJSONWithOptions is like FromJSON, but the document is written as
+// described by opts.
+func FromJSONWithOptions(in []byte, opts DumpOptions) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+	value, err := json_read(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("ini: invalid JSON: data after the top-level object")
+	}
+	object, ok := value.(MapSlice)
+	if !ok {
+		return nil, errors.New("ini: cannot convert a JSON value that is not an object into a document")
+	}
+	if opts.Dialect.dotted() {
+		if err := json_check(object, true); err != nil {
+			return nil, err
+		}
+	}
+	return MarshalWithOptions(object, opts)
+}
+
+// json_check returns an error for the first key of object that holds a
+// '.', which would read back as nested keys.  The keys of the outer object
+// that hold objects are section names, which may hold one.
+func json_check(object MapSlice, top bool) error {
+	for _, item := range object {
+		key := item.Key.(string)
+		nested, isObject := item.Value.(MapSlice)
+		if strings.Contains(key, ".") && !(top && isObject) {
+			return errors.New("ini: cannot convert the JSON key '" + key + "', which would read back as nested keys")
+		}
+		if isObject {
+			if err := json_check(nested, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonObject adds the keys of a document, a section or a map of dotted
+// keys to object.  A key that is already in object takes the later value,
+// in the place of the earlier one.
+func (d *decoder) jsonObject(n *node, object MapSlice) MapSlice {
+	for i := 0; i+1 < len(n.children); i += 2 {
+		keyNode, valueNode := n.children[i], n.children[i+1]
+		if n.kind == documentNode && keyNode.value == DEFAULT_SECTION {
+			object = d.jsonObject(valueNode, object)
+			continue
+		}
+		var value interface{}
+		if valueNode.kind == sectionNode || valueNode.kind == mappingNode {
+			value = d.jsonObject(valueNode, MapSlice{})
+		} else {
+			value = d.jsonValue(valueNode)
+		}
+		object = json_set(object, keyNode.value, value)
+	}
+	return object
+}
+
+// jsonValue returns the value of a key.  Infinities and NaNs, which JSON
+// cannot hold, are kept as strings.
+func (d *decoder) jsonValue(n *node) interface{} {
+	if n.kind == sequenceNode {
+		values := make([]interface{}, 0, len(n.children))
+		for _, child := range n.children {
+			values = append(values, d.jsonValue(child))
+		}
+		return values
+	}
+	var value interface{}
+	d.unmarshal(n, reflect.ValueOf(&value).Elem())
+	if f, ok := value.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+		return n.value
+	}
+	return value
+}
+
+// json_set sets the value of key in object.
+func json_set(object MapSlice, key string, value interface{}) MapSlice {
+	for i := range object {
+		if object[i].Key == key {
+			object[i].Value = value
+			return object
+		}
+	}
+	return append(object, MapItem{Key: key, Value: value})
+}
+
+// json_write writes value as indented JSON.  The keys of a MapSlice are
+// written in order.
+func json_write(buf *bytes.Buffer, value interface{}, indent string) {
+	switch value := value.(type) {
+	case MapSlice:
+		if len(value) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i, item := range value {
+			buf.WriteString(indent + "  ")
+			json_write(buf, item.Key, "")
+			buf.WriteString(": ")
+			json_write(buf, item.Value, indent+"  ")
+			if i < len(value)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(value) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, element := range value {
+			buf.WriteString(indent + "  ")
+			json_write(buf, element, indent+"  ")
+			if i < len(value)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	default:
+		var out bytes.Buffer
+		enc := json.NewEncoder(&out)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(value); err != nil {
+			fail(err)
+		}
+		buf.Write(bytes.TrimRight(out.Bytes(), "\n"))
+	}
+}
+
+// json_read reads a JSON value.  Objects are read as a MapSlice, to keep
+// the order of their keys, and numbers as int64 when they are integers.
+func json_read(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, errors.New("ini: invalid JSON: " + err.Error())
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			object := MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, errors.New("ini: invalid JSON: " + err.Error())
+				}
+				value, err := json_read(dec)
+				if err != nil {
+					return nil, err
+				}
+				object = json_set(object, key.(string), value)
+			}
+			_, err = dec.Token()
+			return object, err
+		case '[':
+			array := []interface{}{}
+			for dec.More() {
+				value, err := json_read(dec)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+			_, err = dec.Token()
+			return array, err
+		}
+	case json.Number:
+		if i, err := token.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(token.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		if !strings.ContainsAny(token.String(), ".eE") {
+			return nil, errors.New("ini: cannot convert the JSON integer " + token.String() + ", which does not fit in 64 bits")
+		}
+		f, err := token.Float64()
+		if err != nil {
+			return nil, errors.New("ini: invalid JSON number: " + token.String())
+		}
+		return f, nil
+	}
+	return token, nil
+}
@@ -0,0 +1,147 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var toJSONTests = []struct {
+	options ini.LoadOptions
+	data    string
+	json    string
+}{
+	{
+		ini.LoadOptions{},
+		"",
+		"{}\n",
+	}, {
+		ini.LoadOptions{},
+		"[server]\nport= 8080\nratio= 0.5\ndebug= true\nempty=\nhost.name= <example>\nhost.ip= 10.0.0.1\n[client]\nport= 80\n",
+		`{
+  "server": {
+    "port": 8080,
+    "ratio": 0.5,
+    "debug": true,
+    "empty": null,
+    "host": {
+      "name": "<example>",
+      "ip": "10.0.0.1"
+    }
+  },
+  "client": {
+    "port": 80
+  }
+}
+`,
+	}, {
+		ini.LoadOptions{},
+		"name= app\n[server]\nport= 8080\n",
+		`{
+  "name": "app",
+  "server": {
+    "port": 8080,
+    "name": "app"
+  }
+}
+`,
+	}, {
+		ini.LoadOptions{RawValues: true},
+		"[server]\nport= 8080\ndebug= true\nempty=\n",
+		`{
+  "server": {
+    "port": "8080",
+    "debug": "true",
+    "empty": ""
+  }
+}
+`,
+	}, {
+		ini.LoadOptions{Dialect: ini.DialectGit},
+		"[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n",
+		`{
+  "remote": {
+    "origin": {
+      "fetch": [
+        "a",
+        "b"
+      ]
+    }
+  }
+}
+`,
+	},
+}
+
+func (s *S) TestToJSON(c *C) {
+	for _, item := range toJSONTests {
+		out, err := ini.ToJSONWithOptions([]byte(item.data), item.options)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(string(out), Equals, item.json, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestToJSONErrors(c *C) {
+	_, err := ini.ToJSON([]byte("[a:b]\nc= d\n"))
+	c.Assert(err, ErrorMatches, "ini: inherit section 'b' does not exists")
+}
+
+var fromJSONTests = []struct {
+	json string
+	data string
+}{
+	{
+		"{}",
+		"",
+	}, {
+		`{"name": "app", "server": {"port": 8080, "ratio": 0.5, "debug": true, "empty": null, "host": {"name": "a b"}}, "client": {"port": 80}}`,
+		"name = app\n\n[server]\nport = 8080\nratio = 0.5\ndebug = true\nempty =\nhost.name = a b\n\n[client]\nport = 80\n",
+	}, {
+		`{"a": {"big": 18446744073709551615, "id": 9007199254740993}}`,
+		"[a]\nbig = 18446744073709551615\nid = 9007199254740993\n",
+	},
+}
+
+func (s *S) TestFromJSON(c *C) {
+	for _, item := range fromJSONTests {
+		out, err := ini.FromJSON([]byte(item.json))
+		c.Assert(err, IsNil, Commentf("json: %s", item.json))
+		c.Assert(string(out), Equals, item.data, Commentf("json: %s", item.json))
+	}
+}
+
+var fromJSONErrorTests = []struct {
+	json  string
+	error string
+}{
+	{"[1, 2]", "ini: cannot convert a JSON value that is not an object into a document"},
+	{`{"a": 1`, "ini: invalid JSON: unexpected end of JSON input"},
+	{`{"a": 1} {}`, "ini: invalid JSON: data after the top-level object"},
+	{`{"a": [1, 2]}`, `ini: cannot marshal type: \[\]interface \{\}`},
+	{`{"s": {"a.b": 1}}`, `ini: cannot convert the JSON key 'a.b', which would read back as nested keys`},
+	{`{"a.b": 1}`, `ini: cannot convert the JSON key 'a.b', which would read back as nested keys`},
+	{`{"a": 99999999999999999999}`, `ini: cannot convert the JSON integer 99999999999999999999, which does not fit in 64 bits`},
+}
+
+func (s *S) TestFromJSONErrors(c *C) {
+	for _, item := range fromJSONErrorTests {
+		_, err := ini.FromJSON([]byte(item.json))
+		c.Assert(err, ErrorMatches, item.error, Commentf("json: %s", item.json))
+	}
+}
+
+func (s *S) TestJSONRoundTrip(c *C) {
+	data := "[server]\nport = 8080\nhost.name = example\n"
+	out, err := ini.ToJSON([]byte(data))
+	c.Assert(err, IsNil)
+	back, err := ini.FromJSON(out)
+	c.Assert(err, IsNil)
+	c.Assert(string(back), Equals, data)
+}
+
+func (s *S) TestFromJSONDottedKeys(c *C) {
+	// Dotted keys are plain keys in the dialects that do not nest them.
+	out, err := ini.FromJSONWithOptions([]byte(`{"s": {"a.b": 1}}`), ini.DumpOptions{Dialect: ini.DialectPHP})
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, "[s]\na.b = 1\n")
+}
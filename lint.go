@@ -0,0 +1,188 @@
+package ini
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lint reports the problems of an INI document that Unmarshal reads
+// without an error, but perhaps not as its author meant: keys set twice
+// in a section, sections defined twice, sections that inherit an unknown
+// section, keys that shadow the keys their section inherits, and plain
+// values that read as booleans, nulls or integers although they are not
+// written the way Marshal writes them, such as NO, which reads as false.
+// Keys and sections that a dialect repeats on purpose are not reported.
+//
+// The document is read in recovery mode, and the lines that cannot be
+// parsed are reported too.  Every problem starts with its line, and they
+// are sorted by line.  An error is returned only when the document cannot
+// be read at all.
+func Lint(in []byte, opts LoadOptions) (problems []string, err error) {
+	opts.Recover = true
+	src, err := ini_source(in, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer handleErr(&err)
+	l := &linter{src: src, d: newDecoder(opts.preset()), dialect: opts.Dialect}
+	for i, problem := range src.errors {
+		l.problems = append(l.problems, lint_problem{src.lines[i], problem})
+	}
+	l.sections()
+	l.keys()
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		return l.problems[i].line < l.problems[j].line
+	})
+	for _, problem := range l.problems {
+		problems = append(problems, problem.msg)
+	}
+	return problems, nil
+}
+
+// A lint_problem is a problem of a document, on a line from 0.
+type lint_problem struct {
+	line int
+	msg  string
+}
+
+type linter struct {
+	src      *source
+	d        *decoder
+	dialect  Dialect
+	problems []lint_problem
+
+	// The first header of every section, by name.
+	defined map[string]*source_section
+}
+
+// report adds a problem on a line from 0.
+func (l *linter) report(line int, format string, args ...interface{}) {
+	l.problems = append(l.problems, lint_problem{line, fmt.Sprintf("line %d: ", line+1) + fmt.Sprintf(format, args...)})
+}
+
+// name returns the name that a section or a key is looked up by.
+func (l *linter) name(name string) string {
+	if l.d.insensitive {
+		name = strings.ToLower(name)
+	}
+	if l.d.dashes {
+		name = strings.Replace(name, "-", "_", -1)
+	}
+	return name
+}
+
+// section_name returns the name of a section, with its subsections.
+func section_name(s *source_section) string {
+	if s.names == nil {
+		return DEFAULT_SECTION
+	}
+	return strings.Join(s.names, ".")
+}
+
+// sections reports the sections that are defined twice, where a dialect
+// does not merge them, and the sections that inherit unknown sections.
+func (l *linter) sections() {
+	l.defined = make(map[string]*source_section)
+	merged := l.dialect == DialectGit || l.dialect == DialectSystemd || l.dialect == DialectMySQL
+	for _, s := range l.src.sections {
+		name := l.name(section_name(s))
+		if first, ok := l.defined[name]; ok {
+			if !merged && s.names != nil && first.names != nil {
+				l.report(s.line, "section '%s' is already defined on line %d", section_name(s), first.line+1)
+			}
+			continue
+		}
+		l.defined[name] = s
+	}
+	for _, s := range l.src.sections {
+		if s.base == "" {
+			continue
+		}
+		base, ok := l.defined[l.name(s.base)]
+		if !ok {
+			l.report(s.line, "section '%s' inherits the unknown section '%s'", section_name(s), s.base)
+		} else if base.line > s.line {
+			l.report(s.line, "section '%s' inherits the section '%s', which is only defined below it", section_name(s), s.base)
+		}
+	}
+}
+
+// keys reports the keys that are set twice in a section, where a dialect
+// does not repeat them, the keys that shadow inherited keys, and the values
+// that read ambiguously.
+func (l *linter) keys() {
+	seen := make(map[string]map[string]*source_entry)
+	for _, s := range l.src.sections {
+		name := l.name(section_name(s))
+		keys := seen[name]
+		if keys == nil {
+			keys = make(map[string]*source_entry)
+			seen[name] = keys
+		}
+		for _, ent := range s.entries {
+			key := l.name(strings.Join(ent.keys, "."))
+			if first, ok := keys[key]; ok && !l.dialect.multi() {
+				l.report(ent.line, "key '%s' is already set on line %d", strings.Join(ent.keys, "."), first.line+1)
+			} else if !ok {
+				keys[key] = ent
+			}
+			l.value(ent)
+		}
+	}
+	for _, s := range l.src.sections {
+		for _, ent := range s.entries {
+			l.shadow(s, ent, seen)
+		}
+	}
+}
+
+// shadow reports a key that shadows a key of a section that its section
+// inherits, directly or through other sections.
+func (l *linter) shadow(s *source_section, ent *source_entry, seen map[string]map[string]*source_entry) {
+	key := l.name(strings.Join(ent.keys, "."))
+	visited := map[string]bool{l.name(section_name(s)): true}
+	for base := s.base; base != ""; {
+		name := l.name(base)
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		if inherited, ok := seen[name][key]; ok {
+			l.report(ent.line, "key '%s' shadows the key of section '%s' on line %d",
+				strings.Join(ent.keys, "."), base, inherited.line+1)
+			return
+		}
+		section, ok := l.defined[name]
+		if !ok {
+			return
+		}
+		base = section.base
+	}
+}
+
+// value reports a value that reads as a boolean, a null or an integer, but
+// is not written the way Marshal writes it.
+func (l *linter) value(ent *source_entry) {
+	if ent.style != ini_PLAIN_SCALAR_STYLE && (l.dialect == DialectPHP || l.dialect == DialectDotenv) {
+		return
+	}
+	value := string(ent.value)
+	tag, resolved := l.d.resolve(ent.tag, value)
+	key := strings.Join(ent.keys, ".")
+	switch tag {
+	case ini_BOOL_TAG:
+		if value != strconv.FormatBool(resolved.(bool)) {
+			l.report(ent.line, "the value '%s' of key '%s' reads as the boolean %v", value, key, resolved)
+		}
+	case ini_NULL_TAG:
+		if value != "" {
+			l.report(ent.line, "the value '%s' of key '%s' reads as null", value, key)
+		}
+	case ini_INT_TAG:
+		if value != fmt.Sprint(resolved) {
+			l.report(ent.line, "the value '%s' of key '%s' reads as the integer %v", value, key, resolved)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var lintTests = []struct {
+	data     string
+	opts     ini.LoadOptions
+	problems []string
+}{
+	{"a = 1\n[s]\nb = true\n", ini.LoadOptions{}, nil},
+	{
+		"a = NO\nb = 0x1F\nc = ~\nd = \"yes\"\n", ini.LoadOptions{},
+		[]string{
+			"line 1: the value 'NO' of key 'a' reads as the boolean false",
+			"line 2: the value '0x1F' of key 'b' reads as the integer 31",
+			"line 3: the value '~' of key 'c' reads as null",
+			"line 4: the value 'yes' of key 'd' reads as the boolean true",
+		},
+	}, {
+		"[s]\nk = 1\nk = 2\n[t : s]\nk = 3\n[s]\n[u : v]\n[w : x]\n[x]\n[broken\n", ini.LoadOptions{},
+		[]string{
+			"line 3: key 'k' is already set on line 2",
+			"line 5: key 'k' shadows the key of section 's' on line 2",
+			"line 6: section 's' is already defined on line 1",
+			"line 7: section 'u' inherits the unknown section 'v'",
+			"line 8: section 'w' inherits the section 'x', which is only defined below it",
+			"line 10: did not find expected <section-entry>",
+		},
+	}, {
+		"[A]\nx = 1\n[a]\nX = 2\n", ini.LoadOptions{Insensitive: true},
+		[]string{
+			"line 3: section 'a' is already defined on line 1",
+			"line 4: key 'X' is already set on line 2",
+		},
+	},
+	{"[core]\n\tx = 1\n\tx = 2\n[core]\n", ini.LoadOptions{Dialect: ini.DialectGit}, nil},
+	{"[client]\nport = 1\n[client]\n", ini.LoadOptions{Dialect: ini.DialectMySQL}, nil},
+	{
+		"a = \"no\"\nb = none\n", ini.LoadOptions{Dialect: ini.DialectPHP},
+		[]string{"line 2: the value 'none' of key 'b' reads as the boolean false"},
+	},
+}
+
+func (s *S) TestLint(c *C) {
+	for _, item := range lintTests {
+		problems, err := ini.Lint([]byte(item.data), item.opts)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(problems, DeepEquals, item.problems, Commentf("data: %q", item.data))
+	}
+}
@@ -0,0 +1,310 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MergeOptions changes how Merge3 merges documents.  The zero value merges
+// documents of the default dialect, and keeps our side of conflicts.
+type MergeOptions struct {
+	// Load reads the three documents, and the merged document is written
+	// in its dialect.  Values are read as text, as with RawValues, so that
+	// merged values are written the way they were.
+	Load LoadOptions
+
+	// Markers writes both sides of every conflict between the lines
+	// "<<<<<<< ours", "=======" and ">>>>>>> theirs", as version control
+	// systems do, for someone to resolve.  The merged document does not
+	// read back until they are removed.  Without Markers, conflicts keep
+	// our side.
+	Markers bool
+
+	// BasePath, OursPath and TheirsPath are the paths of the three
+	// documents, which take the place of the Path of Load, so that the
+	// files that each document includes are found from its own path.
+	BasePath, OursPath, TheirsPath string
+}
+
+// A Conflict is a key that both sides of a merge changed, in different
+// ways.
+type Conflict struct {
+	// Path is the path of the key, as Get names it.
+	Path string
+
+	// Base, Ours and Theirs are the values of the key in the three
+	// documents, as text, or nil where a document lacks the key.  A map
+	// of dotted keys is a MapSlice, and a key that appears several times,
+	// in git and systemd files, is the []interface{} of its values.
+	Base, Ours, Theirs interface{}
+
+	// OursLine and TheirsLine are the lines of the key in our document and
+	// in theirs, from 1, or 0 where the document lacks the key.
+	OursLine, TheirsLine int
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: ours %s, theirs %s", c.Path,
+		merge_format(c.Ours, c.OursLine), merge_format(c.Theirs, c.TheirsLine))
+}
+
+// merge_format formats a side of a Conflict.
+func merge_format(value interface{}, line int) string {
+	if line == 0 {
+		return "deleted"
+	}
+	return fmt.Sprintf("%s (line %d)", diff_format(value), line)
+}
+
+// Merge3 merges the changes that two documents made to the document that
+// they share, such as the config file that an operator edited and the new
+// default of an upgrade, and returns the merged document with the
+// conflicts.  Sections and keys that only one side added, changed or
+// removed take the change.  Keys that both sides changed, in different
+// ways, are conflicts: the merged document holds our side, or both sides
+// with Markers.  Keys are compared by their values, as with Diff, but the
+// keys that sections inherit are merged where they are set.
+//
+// The merged document is written in the layout of Marshal, with our
+// comments, and with their comments on the keys and sections that come
+// from their side.
+func Merge3(base, ours, theirs []byte, opts MergeOptions) (out []byte, conflicts []Conflict, err error) {
+	opts.Load.RawValues = true
+	var docs [3]*document
+	paths := []string{opts.BasePath, opts.OursPath, opts.TheirsPath}
+	for i, in := range [][]byte{base, ours, theirs} {
+		load := opts.Load
+		load.Path = paths[i]
+		if docs[i], err = ini_document(in, load); err != nil {
+			return nil, nil, err
+		}
+		docs[i].strip()
+	}
+	m := &merger{markers: opts.Markers}
+	doc := &document{
+		entries:  m.merge("", docs[0].entries, docs[1].entries, docs[2].entries),
+		comments: docs[1].comments,
+	}
+	dump := DumpOptions{Dialect: opts.Load.Dialect, CRLF: bytes.Contains(ours, []byte("\r\n"))}
+	if out, err = ini_write(doc, dump, nil); err != nil {
+		return nil, nil, err
+	}
+	return out, m.conflicts, nil
+}
+
+// strip removes the keys that the sections of a document inherit from the
+// default section and from the sections they name, which hold the line of
+// the key that they copy.
+func (doc *document) strip() {
+	var defaults []*entry
+	for _, e := range doc.entries {
+		if _, ok := e.value.([]*entry); !ok {
+			defaults = append(defaults, e)
+		}
+	}
+	var sections []*entry
+	for _, e := range doc.entries {
+		if _, ok := e.value.([]*entry); ok {
+			sections = append(sections, &entry{key: e.key, value: e.value, base: e.base})
+		}
+	}
+	for _, e := range doc.entries {
+		if _, ok := e.value.([]*entry); !ok {
+			continue
+		}
+		sources := [][]*entry{defaults}
+		seen := map[string]bool{e.key: true}
+		for base := e.base; base != "" && !seen[base]; {
+			seen[base] = true
+			section := entry_find(sections, base)
+			if section == nil {
+				break
+			}
+			sources = append(sources, section.value.([]*entry))
+			base = section.base
+		}
+		e.value = strip_entries(e.value.([]*entry), sources)
+	}
+}
+
+// strip_entries returns the keys of a section or a map that are not copies
+// of the keys of the same name in sources.
+func strip_entries(entries []*entry, sources [][]*entry) []*entry {
+	var own []*entry
+	for _, e := range entries {
+		var inherited bool
+		var nested [][]*entry
+		for _, source := range sources {
+			if copied := entry_find(source, e.key); copied != nil {
+				if children, ok := copied.value.([]*entry); ok {
+					nested = append(nested, children)
+				} else if copied.line == e.line {
+					inherited = true
+				}
+			}
+		}
+		if children, ok := e.value.([]*entry); ok && len(nested) > 0 {
+			children = strip_entries(children, nested)
+			if len(children) == 0 {
+				continue
+			}
+			copied := *e
+			copied.value = children
+			e = &copied
+		} else if inherited {
+			continue
+		}
+		own = append(own, e)
+	}
+	return own
+}
+
+// A merge_conflict holds the sides of a key that both sides changed, which
+// are written between markers.  A side is nil where it removed the key.
+type merge_conflict struct {
+	ours, theirs *entry
+}
+
+// A merger merges the entries of three documents.
+type merger struct {
+	markers   bool
+	conflicts []Conflict
+}
+
+// merge merges the keys of the document, a section or a map of dotted
+// keys, named by the path before them.  Our keys keep their order, and
+// their new keys follow.
+func (m *merger) merge(path string, base, ours, theirs []*entry) (merged []*entry) {
+	for _, o := range ours {
+		if e := m.merge_entry(path, entry_find(base, o.key), o, entry_find(theirs, o.key)); e != nil {
+			merged = append(merged, e)
+		}
+	}
+	for _, t := range theirs {
+		if entry_find(ours, t.key) != nil {
+			continue
+		}
+		if e := m.merge_entry(path, entry_find(base, t.key), nil, t); e != nil {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+// merge_entry merges the three sides of a key, which are nil where the
+// documents lack it, and returns the merged key, or nil.  Sections and maps
+// that both sides hold are merged key by key, so that they keep our
+// comments.
+func (m *merger) merge_entry(path string, b, o, t *entry) *entry {
+	both := o != nil && t != nil && merge_map(o) && merge_map(t)
+	switch {
+	case merge_equal(o, t), merge_equal(b, t):
+		return o
+	case merge_equal(b, o) && !both:
+		return merge_comments(t, o)
+	}
+	if merge_map(b) && merge_map(o) && merge_map(t) {
+		merged := &entry{}
+		if o != nil {
+			*merged = *o
+		} else {
+			*merged = *t
+		}
+		switch {
+		case t == nil:
+			merged.base = o.base
+		case o == nil || b != nil && o.base == b.base:
+			merged.base = t.base
+		}
+		entries := m.merge(path+merged.key+".", merge_entries(b), merge_entries(o), merge_entries(t))
+		if len(entries) == 0 && (o == nil || t == nil) {
+			return nil
+		}
+		merged.value = entries
+		return merged
+	}
+
+	c := Conflict{Base: merge_value(b), Ours: merge_value(o), Theirs: merge_value(t)}
+	key := ""
+	if o != nil {
+		key, c.OursLine = o.key, o.line
+	}
+	if t != nil {
+		key, c.TheirsLine = t.key, t.line
+	}
+	c.Path = path + key
+	m.conflicts = append(m.conflicts, c)
+	if !m.markers {
+		return o
+	}
+	return &entry{key: key, conflict: &merge_conflict{o, t}}
+}
+
+// merge_comments returns their side of a key that ours did not change, with
+// our comments.
+func merge_comments(t, o *entry) *entry {
+	if t == nil || o == nil {
+		return t
+	}
+	merged := *t
+	merged.comments, merged.comment = o.comments, o.comment
+	return &merged
+}
+
+// merge_equal reports whether two sides of a key, which are nil where a
+// document lacks it, are the same.
+func merge_equal(a, b *entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.base == b.base && entry_equal(a.value, b.value)
+}
+
+// merge_map reports whether a side of a key is a section or a map, or nil.
+func merge_map(e *entry) bool {
+	if e == nil {
+		return true
+	}
+	_, ok := e.value.([]*entry)
+	return ok
+}
+
+// merge_entries returns the keys of a section or a map, which is nil where
+// a document lacks it.
+func merge_entries(e *entry) []*entry {
+	if e == nil {
+		return nil
+	}
+	return e.value.([]*entry)
+}
+
+// merge_value returns the value of a side of a key for a Conflict.
+func merge_value(e *entry) interface{} {
+	if e == nil {
+		return nil
+	}
+	if entries, ok := e.value.([]*entry); ok {
+		return entry_map(entries)
+	}
+	return e.value
+}
+
+// conflict marshals the sides of a key that both sides of a merge changed,
+// between markers.
+func (e *encoder) conflict(keys []string, c *merge_conflict) {
+	e.marker("<<<<<<< ours")
+	if c.ours != nil {
+		e.convertPair(keys, c.ours)
+	}
+	e.marker("=======")
+	if c.theirs != nil {
+		e.convertPair(keys, c.theirs)
+	}
+	e.marker(">>>>>>> theirs")
+}
+
+// marker marshals a line as it is.
+func (e *encoder) marker(line string) {
+	e.must(ini_marker_event_initialize(&e.event, []byte(line)))
+	e.emit()
+}
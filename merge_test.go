@@ -0,0 +1,158 @@
+package ini_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var mergeBase = `; app
+name = app
+
+[server]
+host = localhost
+port = 8080
+timeout = 30
+
+[log]
+level = info
+`
+
+var mergeOurs = `; app
+name = app
+
+[server]
+# ours
+host = 0.0.0.0
+port = 8080
+timeout = 60
+
+[log]
+level = info
+`
+
+var mergeTheirs = `; app
+name = app2
+
+[server]
+host = localhost
+port = 9090
+timeout = 45
+# new
+workers = 4
+
+[metrics]
+on = yes
+`
+
+var mergeTests = []struct {
+	base, ours, theirs string
+	opts               ini.MergeOptions
+	out                string
+	conflicts          []string
+}{
+	{
+		mergeBase, mergeOurs, mergeTheirs, ini.MergeOptions{},
+		"# app\nname = app2\n\n[server]\n# ours\nhost = 0.0.0.0\nport = 9090\ntimeout = 60\n# new\nworkers = 4\n\n[metrics]\non = yes\n",
+		[]string{"server.timeout: ours '60' (line 8), theirs '45' (line 7)"},
+	}, {
+		mergeBase, mergeOurs, mergeTheirs, ini.MergeOptions{Markers: true},
+		"# app\nname = app2\n\n[server]\n# ours\nhost = 0.0.0.0\nport = 9090\n" +
+			"<<<<<<< ours\ntimeout = 60\n=======\ntimeout = 45\n>>>>>>> theirs\n" +
+			"# new\nworkers = 4\n\n[metrics]\non = yes\n",
+		[]string{"server.timeout: ours '60' (line 8), theirs '45' (line 7)"},
+	}, {
+		"[a]\nx = 1\ny = 2\n", "", "[a]\nx = 1\ny = 3\n", ini.MergeOptions{Markers: true},
+		"[a]\n<<<<<<< ours\n=======\ny = 3\n>>>>>>> theirs\n",
+		[]string{"a.y: ours deleted, theirs '3' (line 3)"},
+	}, {
+		"[a]\nx = 1\ny = 2\n", "", "[a]\nx = 1\n", ini.MergeOptions{},
+		"", nil,
+	}, {
+		"[s]\na=1\n", "# mine\n[s]\n# why\na=1\n", "[s]\na=1\nb=2\n", ini.MergeOptions{},
+		"# mine\n[s]\n# why\na = 1\nb = 2\n", nil,
+	}, {
+		"[s]\na=1\n", "[s]\na=1 # why\n", "[s]\na=2\n", ini.MergeOptions{},
+		"[s]\n# why\na = 2\n", nil,
+	}, {
+		"[s]\na=1\n", "[s]\na=2\n", "", ini.MergeOptions{},
+		"[s]\na = 2\n",
+		[]string{"s.a: ours '2' (line 2), theirs deleted"},
+	}, {
+		"[s]\na.b=1\n", "[s]\na.b=2\n", "[s]\n", ini.MergeOptions{},
+		"[s]\na.b = 2\n",
+		[]string{"s.a.b: ours '2' (line 2), theirs deleted"},
+	}, {
+		"[base]\nx = 1\n[c : base]\ny = 2\n",
+		"[base]\nx = 5\n[c : base]\ny = 2\n",
+		"[base]\nx = 1\n[c : base]\ny = 3\nz = 4\n",
+		ini.MergeOptions{},
+		"[base]\nx = 5\n\n[c:base]\ny = 3\nz = 4\n", nil,
+	}, {
+		"[core]\n\tx = 1\n[remote \"o\"]\n\turl = a\n",
+		"[core]\n\tx = 2\n[remote \"o\"]\n\turl = a\n",
+		"[core]\n\tx = 1\n[remote \"o\"]\n\turl = b\n\tfetch = c\n\tfetch = d\n",
+		ini.MergeOptions{Load: ini.LoadOptions{Dialect: ini.DialectGit}},
+		"[core]\n\tx = 2\n\n[remote \"o\"]\n\turl = b\n\tfetch = c\n\tfetch = d\n", nil,
+	},
+}
+
+func (s *S) TestMerge3(c *C) {
+	for _, item := range mergeTests {
+		out, conflicts, err := ini.Merge3([]byte(item.base), []byte(item.ours), []byte(item.theirs), item.opts)
+		c.Assert(err, IsNil)
+		c.Assert(string(out), Equals, item.out, Commentf("ours: %q theirs: %q", item.ours, item.theirs))
+		var lines []string
+		for _, conflict := range conflicts {
+			lines = append(lines, conflict.String())
+		}
+		c.Assert(lines, DeepEquals, item.conflicts)
+	}
+}
+
+func (s *S) TestMerge3Conflict(c *C) {
+	_, conflicts, err := ini.Merge3([]byte("[a]\nx = 1\n"), []byte("[a]\nx = 2\n"), []byte("[a]\nx.y = 3\n"), ini.MergeOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, DeepEquals, []ini.Conflict{{
+		Path:     "a.x",
+		Base:     "1",
+		Ours:     "2",
+		Theirs:   ini.MapSlice{{Key: "y", Value: "3"}},
+		OursLine: 2, TheirsLine: 2,
+	}})
+}
+
+func (s *S) TestMerge3Paths(c *C) {
+	dir := c.MkDir()
+	var paths []string
+	for _, name := range []string{"base", "ours", "theirs"} {
+		paths = append(paths, filepath.Join(dir, name, "x.service"))
+	}
+	files := map[string]string{
+		paths[0]:                               "[Service]\nUser=a\n",
+		paths[1]:                               "[Service]\nUser=a\n",
+		paths[2]:                               "[Service]\nUser=a\n",
+		filepath.Join(paths[2]+".d", "o.conf"): "[Service]\nGroup=g\n",
+	}
+	for name, data := range files {
+		c.Assert(os.MkdirAll(filepath.Dir(name), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(name, []byte(data), 0644), IsNil)
+	}
+	opts := ini.MergeOptions{
+		Load:     ini.LoadOptions{Dialect: ini.DialectSystemd},
+		BasePath: paths[0], OursPath: paths[1], TheirsPath: paths[2],
+	}
+	out, conflicts, err := ini.Merge3([]byte(files[paths[0]]), []byte(files[paths[1]]), []byte(files[paths[2]]), opts)
+	c.Assert(err, IsNil)
+	c.Assert(conflicts, IsNil)
+	c.Assert(string(out), Equals, "[Service]\nUser=a\nGroup=g\n")
+}
+
+func (s *S) TestMerge3Errors(c *C) {
+	_, _, err := ini.Merge3([]byte("a = 1\n"), []byte("[a] x\n"), nil, ini.MergeOptions{})
+	c.Assert(err, ErrorMatches, "ini: must have a line break before the first section key")
+}
@@ -3,7 +3,7 @@ package ini
 // The parser implements the following grammar:
 //
 // document		::= DOCUMENT-START section* DOCUMENT-END
-// section      ::= SECTION-START (node | comment)* SECTION-END
+// section      ::= SECTION-START SCALAR (MAP SCALAR)* (SECTION-INHERIT SCALAR)? SECTION-ENTRY (node | comment)*
 // node         ::= KEY VALUE SCALAR
 // comment      ::= COMMENT SCALAR
 
@@ -37,8 +37,11 @@ func ini_parser_parse(parser *ini_parser_t, event *ini_event_t) bool {
 	return ini_parser_state_machine(parser, event)
 }
 
-// Set parser error.
+// Set parser error, unless the scanner already failed.
 func ini_parser_set_parser_error(parser *ini_parser_t, problem string, problem_mark ini_mark_t) bool {
+	if parser.error != ini_NO_ERROR {
+		return false
+	}
 	parser.error = ini_PARSER_ERROR
 	parser.problem = problem
 	parser.problem_mark = problem_mark
@@ -66,6 +69,8 @@ func ini_parser_state_machine(parser *ini_parser_t, event *ini_event_t) bool {
 		return ini_parser_parse_section_start(parser, event, false)
 	case ini_PARSE_SECTION_INHERIT_STATE:
 		return ini_parser_parse_section_inherit(parser, event)
+	case ini_PARSE_SECTION_SUBSECTION_STATE:
+		return ini_parser_parse_section_subsection(parser, event)
 	case ini_PARSE_SECTION_ENTRY_STATE:
 		return ini_parser_parse_section_entry(parser, event)
 	case ini_PARSE_SECTION_KEY_STATE:
@@ -75,7 +80,6 @@ func ini_parser_state_machine(parser *ini_parser_t, event *ini_event_t) bool {
 	default:
 		panic("invalid parser state")
 	}
-	return false
 }
 
 func ini_parser_parse_document_start(parser *ini_parser_t, event *ini_event_t) bool {
@@ -161,6 +165,17 @@ func ini_parser_parse_section_inherit(parser *ini_parser_t, event *ini_event_t)
 	end_mark := parser.mark
 	token := peek_token(parser)
 	if token != nil {
+		if token.typ == ini_MAP_TOKEN {
+			// MAP Token ('"' in git, before the name of a subsection)
+			skip_token(parser)
+			parser.state = ini_PARSE_SECTION_SUBSECTION_STATE
+			*event = ini_event_t{
+				typ:        ini_MAPPING_EVENT,
+				start_mark: token.start_mark,
+				end_mark:   token.end_mark,
+			}
+			return true
+		}
 		if token.typ == ini_SECTION_INHERIT_TOKEN {
 			skip_token(parser)
 			token = peek_token(parser)
@@ -190,8 +205,34 @@ func ini_parser_parse_section_inherit(parser *ini_parser_t, event *ini_event_t)
 		return false
 	}
 }
+
+// Parse the name of a subsection.
+func ini_parser_parse_section_subsection(parser *ini_parser_t, event *ini_event_t) bool {
+	token := peek_token(parser)
+	if token == nil {
+		return false
+	}
+	if token.typ != ini_SCALAR_TOKEN {
+		return ini_parser_set_parser_error(parser, "did not find expected <scalar>", token.start_mark)
+	}
+	skip_token(parser)
+	parser.state = ini_PARSE_SECTION_INHERIT_STATE
+	*event = ini_event_t{
+		typ:        ini_SCALAR_EVENT,
+		start_mark: token.start_mark,
+		end_mark:   token.end_mark,
+		value:      token.value,
+		tag:        []byte(ini_STR_TAG),
+		style:      ini_style_t(token.style),
+	}
+	return true
+}
+
 func ini_parser_parse_section_entry(parser *ini_parser_t, event *ini_event_t) bool {
 	token := peek_token(parser)
+	if token == nil {
+		return false
+	}
 	parser.state = ini_PARSE_SECTION_KEY_STATE
 	if token != nil && token.typ == ini_SECTION_ENTRY_TOKEN {
 		skip_token(parser)
@@ -225,6 +266,7 @@ func ini_parser_parse_section_key(parser *ini_parser_t, event *ini_event_t) bool
 			if token != nil && token.typ == ini_SCALAR_TOKEN {
 				skip_token(parser)
 				parser.state = ini_PARSE_SECTION_VALUE_STATE
+				parser.marks = append(parser.marks, token.start_mark)
 				*event = ini_event_t{
 					typ:        ini_SCALAR_EVENT,
 					start_mark: token.start_mark,
@@ -254,6 +296,9 @@ func ini_parser_parse_section_key(parser *ini_parser_t, event *ini_event_t) bool
 }
 
 func ini_parser_parse_section_value(parser *ini_parser_t, event *ini_event_t) bool {
+	// A missing value is reported on the line of its key.
+	key_mark := parser.marks[len(parser.marks)-1]
+	parser.marks = parser.marks[:len(parser.marks)-1]
 	token := peek_token(parser)
 	if token != nil {
 		if token.typ == ini_MAP_TOKEN {
@@ -281,10 +326,10 @@ func ini_parser_parse_section_value(parser *ini_parser_t, event *ini_event_t) bo
 				return ini_parser_set_parser_error(parser, "did not find expected <scalar>", token.start_mark)
 			}
 		} else {
-			return ini_parser_set_parser_error(parser, "did not find expected <value> or <map>", token.start_mark)
+			return ini_parser_set_parser_error(parser, "did not find expected <value> or <map>", key_mark)
 		}
 	} else {
-		return ini_parser_set_parser_error(parser, "did not find expected <value> or <map>", parser.mark)
+		return ini_parser_set_parser_error(parser, "did not find expected <value> or <map>", key_mark)
 	}
 	return true
 }
@@ -0,0 +1,218 @@
+package ini
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// A Match is a value that Query finds.
+type Match struct {
+	// Path is the path of the key, as Get names it.
+	Path string
+
+	// Value is the value, as Unmarshal decodes it into an interface{}.
+	Value interface{}
+
+	// Line and Column are the position of the value in the document, from
+	// 1.  The value of a key that a section inherits is where the
+	// inherited section sets it.
+	Line, Column int
+}
+
+// Query returns the values of the keys of an INI document whose paths match
+// a pattern.  Paths are named as with Get, and the pattern is a path whose
+// names may hold the wildcards of path.Match, such as "*.timeout", which
+// matches the timeout key of every section, or "server.log_*".  Unlike with
+// path.Match, the wildcards match slashes too, so that "url.*.insteadOf"
+// matches the subsection of [url "https://example.com/"].  The name "**"
+// matches any number of names, so that "**.url" matches the url keys of
+// subsections and of maps of dotted keys too.
+//
+// Sections hold the keys that they inherit, as they do with Get, and a key
+// that appears several times, in git and systemd files, matches once for
+// each of its values.  The matches follow the order of the sections, with
+// the keys that a section inherits after the keys that it sets.  Finding
+// no match is not an error.
+func Query(in []byte, pattern string, opts LoadOptions) (matches []Match, err error) {
+	if pattern == "" {
+		return nil, &QueryError{pattern}
+	}
+	names := strings.Split(pattern, ".")
+	opts = opts.preset()
+	q := &query{seen: make(map[*node]bool), globs: make(map[string]*regexp.Regexp)}
+	for _, name := range names {
+		if opts.Dialect == DialectMySQL {
+			name = fold_dashes(name)
+		}
+		// Git names, such as insteadOf, are read in lower case.
+		glob, ok := query_glob(name, opts.Insensitive || opts.Dialect == DialectGit)
+		if !ok {
+			return nil, &QueryError{pattern}
+		}
+		q.globs[name] = glob
+	}
+	defer handleErr(&err)
+	d := newDecoder(opts)
+	p := newParser(in, opts)
+	defer p.destroy()
+	q.p, q.d = p, d
+	if doc := p.parse(); doc != nil {
+		q.match(doc, "", names)
+	}
+	if len(p.parser.errors) > 0 {
+		return nil, &ParseError{p.errors()}
+	}
+	if len(d.terrors) > 0 {
+		return nil, &TypeError{d.terrors}
+	}
+	return q.matches, nil
+}
+
+// A QueryError is returned by Query for a malformed pattern.
+type QueryError struct {
+	Pattern string
+}
+
+func (e *QueryError) Error() string {
+	return "ini: malformed pattern '" + e.Pattern + "'"
+}
+
+// A query matches the names of a pattern against a node tree.
+type query struct {
+	p       *parser
+	d       *decoder
+	seen    map[*node]bool            // The values that already matched.
+	globs   map[string]*regexp.Regexp // The names of the pattern, compiled.
+	matches []Match
+}
+
+// match adds the values below a node, whose path is prefix, that match the
+// names of a pattern.  The keys of the default section are matched as keys
+// of the document.
+func (q *query) match(n *node, prefix string, names []string) {
+	if len(names) == 0 {
+		if is_value(n) {
+			q.add(n, strings.TrimSuffix(prefix, "."))
+		}
+		return
+	}
+	if names[0] == "**" {
+		q.match(n, prefix, names[1:])
+	}
+	if n.kind != documentNode && n.kind != sectionNode && n.kind != mappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.children); i += 2 {
+		name, valueNode := n.children[i].value, n.children[i+1]
+		if n.kind == documentNode && name == DEFAULT_SECTION {
+			q.match(valueNode, prefix, names)
+			continue
+		}
+		if names[0] == "**" {
+			q.match(valueNode, prefix+name+".", names)
+			continue
+		}
+		if q.name(names[0], name) {
+			q.match(valueNode, prefix+name+".", names[1:])
+		}
+		// A name that holds dots, such as the name of a git subsection, is
+		// matched whole above, and also matches as many names of the
+		// pattern, which split it at its dots.
+		parts := strings.Split(name, ".")
+		if len(parts) == 1 || len(parts) > len(names) {
+			continue
+		}
+		matched := true
+		for j, part := range parts {
+			if !q.name(names[j], part) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			q.match(valueNode, prefix+name+".", names[len(parts):])
+		}
+	}
+}
+
+// name reports whether a name matches a name of a pattern, as names are
+// matched by the dialect.
+func (q *query) name(pattern, name string) bool {
+	if q.p.opts.Dialect == DialectMySQL {
+		pattern, name = fold_dashes(pattern), fold_dashes(name)
+	}
+	return q.globs[pattern].MatchString(name)
+}
+
+// query_glob compiles a name of a pattern, with the wildcards of path.Match,
+// which match any character, or reports false for a malformed name.
+func query_glob(pattern string, insensitive bool) (*regexp.Regexp, bool) {
+	expr := "^(?s)"
+	if insensitive {
+		expr += "(?i)"
+	}
+	chars := []rune(pattern)
+	for i := 0; i < len(chars); i++ {
+		switch chars[i] {
+		case '*':
+			expr += ".*"
+		case '?':
+			expr += "."
+		case '\\':
+			if i++; i == len(chars) {
+				return nil, false
+			}
+			expr += regexp.QuoteMeta(string(chars[i]))
+		case '[':
+			class := "["
+			if i+1 < len(chars) && chars[i+1] == '^' {
+				class += "^"
+				i++
+			}
+			empty := true
+			for i++; i < len(chars) && chars[i] != ']'; i++ {
+				empty = false
+				switch chars[i] {
+				case '-':
+					class += "-"
+					continue
+				case '\\':
+					if i++; i == len(chars) {
+						return nil, false
+					}
+					if chars[i] == '-' {
+						class += "\\-"
+						continue
+					}
+				}
+				class += regexp.QuoteMeta(string(chars[i]))
+			}
+			if i == len(chars) || empty {
+				return nil, false
+			}
+			expr += class + "]"
+		default:
+			expr += regexp.QuoteMeta(string(chars[i]))
+		}
+	}
+	re, err := regexp.Compile(expr + "$")
+	return re, err == nil
+}
+
+// add adds the values of a key.
+func (q *query) add(n *node, path string) {
+	if q.seen[n] {
+		return
+	}
+	q.seen[n] = true
+	valueNodes := []*node{n}
+	if n.kind == sequenceNode {
+		valueNodes = n.children
+	}
+	for _, valueNode := range valueNodes {
+		var value interface{}
+		q.d.unmarshal(valueNode, reflect.ValueOf(&value).Elem())
+		q.matches = append(q.matches, Match{Path: path, Value: value, Line: valueNode.line + 1, Column: valueNode.column + 1})
+	}
+}
@@ -0,0 +1,86 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var queryDocument = `timeout = 5
+
+[server]
+host = a
+timeout = 30
+
+[db : server]
+timeout = 60
+log.level = info
+`
+
+var queryTests = []struct {
+	data    string
+	pattern string
+	opts    ini.LoadOptions
+	matches []ini.Match
+}{
+	{
+		queryDocument, "*.timeout", ini.LoadOptions{},
+		[]ini.Match{
+			{Path: "server.timeout", Value: 30, Line: 5, Column: 11},
+			{Path: "db.timeout", Value: 60, Line: 8, Column: 11},
+		},
+	},
+	{queryDocument, "timeout", ini.LoadOptions{}, []ini.Match{{Path: "timeout", Value: 5, Line: 1, Column: 11}}},
+	{queryDocument, "db.host", ini.LoadOptions{}, []ini.Match{{Path: "db.host", Value: "a", Line: 4, Column: 8}}},
+	{queryDocument, "**.level", ini.LoadOptions{}, []ini.Match{{Path: "db.log.level", Value: "info", Line: 9, Column: 13}}},
+	{queryDocument, "db.log.*", ini.LoadOptions{}, []ini.Match{{Path: "db.log.level", Value: "info", Line: 9, Column: 13}}},
+	{queryDocument, "s*.t*", ini.LoadOptions{}, []ini.Match{{Path: "server.timeout", Value: 30, Line: 5, Column: 11}}},
+	{queryDocument, "SERVER.Host", ini.LoadOptions{Insensitive: true}, []ini.Match{{Path: "server.host", Value: "a", Line: 4, Column: 8}}},
+	{queryDocument, "db", ini.LoadOptions{}, nil},
+	{queryDocument, "nope.*", ini.LoadOptions{}, nil},
+	{
+		"[remote \"origin\"]\n\turl = a\n\tfetch = x\n\tfetch = y2\n", "remote.*.fetch", ini.LoadOptions{Dialect: ini.DialectGit},
+		[]ini.Match{
+			{Path: "remote.origin.fetch", Value: "x", Line: 3, Column: 10},
+			{Path: "remote.origin.fetch", Value: "y2", Line: 4, Column: 10},
+		},
+	}, {
+		"[includeIf \"gitdir:~/work/\"]\n\tpath = w\n[url \"https://example.com/\"]\n\tinsteadOf = e:\n",
+		"*.*.*", ini.LoadOptions{Dialect: ini.DialectGit},
+		[]ini.Match{
+			{Path: "includeif.gitdir:~/work/.path", Value: "w", Line: 2, Column: 9},
+			{Path: "url.https://example.com/.insteadof", Value: "e:", Line: 4, Column: 14},
+		},
+	}, {
+		"[url \"https://example.com/\"]\n\tinsteadOf = e:\n", "url.https:[/]/*.com/.insteadOf", ini.LoadOptions{Dialect: ini.DialectGit},
+		[]ini.Match{{Path: "url.https://example.com/.insteadof", Value: "e:", Line: 2, Column: 14}},
+	}, {
+		"[a]\nx = 1\n[b]\nx = 2\n", "**", ini.LoadOptions{},
+		[]ini.Match{
+			{Path: "a.x", Value: 1, Line: 2, Column: 5},
+			{Path: "b.x", Value: 2, Line: 4, Column: 5},
+		},
+	},
+}
+
+func (s *S) TestQuery(c *C) {
+	for _, item := range queryTests {
+		matches, err := ini.Query([]byte(item.data), item.pattern, item.opts)
+		c.Assert(err, IsNil, Commentf("pattern: %s", item.pattern))
+		c.Assert(matches, DeepEquals, item.matches, Commentf("pattern: %s", item.pattern))
+	}
+}
+
+func (s *S) TestQueryErrors(c *C) {
+	_, err := ini.Query([]byte(queryDocument), "[", ini.LoadOptions{})
+	c.Assert(err, DeepEquals, &ini.QueryError{Pattern: "["})
+	c.Assert(err, ErrorMatches, "ini: malformed pattern '\\['")
+	_, err = ini.Query([]byte(queryDocument), "a.b\\", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: malformed pattern 'a\\.b\\\\'")
+	_, err = ini.Query([]byte(queryDocument), "[]", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: malformed pattern '\\[\\]'")
+	_, err = ini.Query([]byte(queryDocument), "", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: malformed pattern ''")
+	_, err = ini.Query([]byte("[a] x\n"), "*", ini.LoadOptions{})
+	c.Assert(err, ErrorMatches, "ini: must have a line break before the first section key")
+}
@@ -15,9 +15,49 @@ func ini_parser_set_reader_error(parser *ini_parser_t, problem string, offset in
 
 // Byte order marks.
 const (
-	bom_UTF8 = "\xef\xbb\xbf"
+	bom_UTF8    = "\xef\xbb\xbf"
+	bom_UTF16LE = "\xff\xfe"
+	bom_UTF16BE = "\xfe\xff"
 )
 
+// Determine the input stream encoding by checking the BOM symbol.  If no BOM
+// is found, a NUL octet in the first two octets of an ASCII character is
+// taken as UTF-16, as Windows tools write it, and UTF-8 is assumed
+// otherwise.  Return true on success, false on failure.
+func ini_parser_determine_encoding(parser *ini_parser_t) bool {
+	// Ensure that we had enough bytes in the raw buffer.
+	for !parser.eof && len(parser.raw_buffer)-parser.raw_buffer_pos < 3 {
+		if !ini_parser_update_raw_buffer(parser) {
+			return false
+		}
+	}
+
+	// Determine the encoding.
+	buf := parser.raw_buffer
+	pos := parser.raw_buffer_pos
+	avail := len(buf) - pos
+	if avail >= 2 && buf[pos] == bom_UTF16LE[0] && buf[pos+1] == bom_UTF16LE[1] {
+		parser.encoding = ini_UTF16LE_ENCODING
+		parser.raw_buffer_pos += 2
+		parser.offset += 2
+	} else if avail >= 2 && buf[pos] == bom_UTF16BE[0] && buf[pos+1] == bom_UTF16BE[1] {
+		parser.encoding = ini_UTF16BE_ENCODING
+		parser.raw_buffer_pos += 2
+		parser.offset += 2
+	} else if avail >= 3 && buf[pos] == bom_UTF8[0] && buf[pos+1] == bom_UTF8[1] && buf[pos+2] == bom_UTF8[2] {
+		parser.encoding = ini_UTF8_ENCODING
+		parser.raw_buffer_pos += 3
+		parser.offset += 3
+	} else if avail >= 2 && buf[pos] != 0 && buf[pos] < 0x80 && buf[pos+1] == 0 {
+		parser.encoding = ini_UTF16LE_ENCODING
+	} else if avail >= 2 && buf[pos] == 0 && buf[pos+1] != 0 && buf[pos+1] < 0x80 {
+		parser.encoding = ini_UTF16BE_ENCODING
+	} else {
+		parser.encoding = ini_UTF8_ENCODING
+	}
+	return true
+}
+
 // Update the raw buffer.
 func ini_parser_update_raw_buffer(parser *ini_parser_t) bool {
 	size_read := 0
@@ -68,6 +108,13 @@ func ini_parser_update_buffer(parser *ini_parser_t, length int) bool {
 		return true
 	}
 
+	// Determine the input encoding if it is not known yet.
+	if parser.encoding == ini_ANY_ENCODING {
+		if !ini_parser_determine_encoding(parser) {
+			return false
+		}
+	}
+
 	// Move the unread characters to the beginning of the buffer.
 	buffer_len := len(parser.buffer)
 	if parser.buffer_pos > 0 && parser.buffer_pos < buffer_len {
@@ -102,98 +149,194 @@ func ini_parser_update_buffer(parser *ini_parser_t, length int) bool {
 
 			raw_unread := len(parser.raw_buffer) - parser.raw_buffer_pos
 
-			// Decode a UTF-8 character.  Check RFC 3629
-			// (http://www.ietf.org/rfc/rfc3629.txt) for more details.
-			//
-			// The following table (taken from the RFC) is used for
-			// decoding.
-			//
-			//    Char. number range |        UTF-8 octet sequence
-			//      (hexadecimal)    |              (binary)
-			//   --------------------+------------------------------------
-			//   0000 0000-0000 007F | 0xxxxxxx
-			//   0000 0080-0000 07FF | 110xxxxx 10xxxxxx
-			//   0000 0800-0000 FFFF | 1110xxxx 10xxxxxx 10xxxxxx
-			//   0001 0000-0010 FFFF | 11110xxx 10xxxxxx 10xxxxxx 10xxxxxx
-			//
-			// Additionally, the characters in the range 0xD800-0xDFFF
-			// are prohibited as they are reserved for use with UTF-16
-			// surrogate pairs.
-
-			// Determine the length of the UTF-8 sequence.
-			octet := parser.raw_buffer[parser.raw_buffer_pos]
-			switch {
-			case octet&0x80 == 0x00:
-				width = 1
-			case octet&0xE0 == 0xC0:
-				width = 2
-			case octet&0xF0 == 0xE0:
-				width = 3
-			case octet&0xF8 == 0xF0:
-				width = 4
-			default:
-				// The leading octet is invalid.
-				return ini_parser_set_reader_error(parser,
-					"invalid leading UTF-8 octet",
-					parser.offset, int(octet))
-			}
+			switch parser.encoding {
+			case ini_UTF8_ENCODING:
+				// Decode a UTF-8 character.  Check RFC 3629
+				// (http://www.ietf.org/rfc/rfc3629.txt) for more details.
+				//
+				// The following table (taken from the RFC) is used for
+				// decoding.
+				//
+				//    Char. number range |        UTF-8 octet sequence
+				//      (hexadecimal)    |              (binary)
+				//   --------------------+------------------------------------
+				//   0000 0000-0000 007F | 0xxxxxxx
+				//   0000 0080-0000 07FF | 110xxxxx 10xxxxxx
+				//   0000 0800-0000 FFFF | 1110xxxx 10xxxxxx 10xxxxxx
+				//   0001 0000-0010 FFFF | 11110xxx 10xxxxxx 10xxxxxx 10xxxxxx
+				//
+				// Additionally, the characters in the range 0xD800-0xDFFF
+				// are prohibited as they are reserved for use with UTF-16
+				// surrogate pairs.
+
+				// Determine the length of the UTF-8 sequence.
+				octet := parser.raw_buffer[parser.raw_buffer_pos]
+				switch {
+				case octet&0x80 == 0x00:
+					width = 1
+				case octet&0xE0 == 0xC0:
+					width = 2
+				case octet&0xF0 == 0xE0:
+					width = 3
+				case octet&0xF8 == 0xF0:
+					width = 4
+				default:
+					// The leading octet is invalid.
+					return ini_parser_set_reader_error(parser,
+						"invalid leading UTF-8 octet",
+						parser.offset, int(octet))
+				}
+
+				// Check if the raw buffer contains an incomplete character.
+				if width > raw_unread {
+					if parser.eof {
+						return ini_parser_set_reader_error(parser,
+							"incomplete UTF-8 octet sequence",
+							parser.offset, -1)
+					}
+					break inner
+				}
+
+				// Decode the leading octet.
+				switch {
+				case octet&0x80 == 0x00:
+					value = rune(octet & 0x7F)
+				case octet&0xE0 == 0xC0:
+					value = rune(octet & 0x1F)
+				case octet&0xF0 == 0xE0:
+					value = rune(octet & 0x0F)
+				case octet&0xF8 == 0xF0:
+					value = rune(octet & 0x07)
+				default:
+					value = 0
+				}
+
+				// Check and decode the trailing octets.
+				for k := 1; k < width; k++ {
+					octet = parser.raw_buffer[parser.raw_buffer_pos+k]
+
+					// Check if the octet is valid.
+					if (octet & 0xC0) != 0x80 {
+						return ini_parser_set_reader_error(parser,
+							"invalid trailing UTF-8 octet",
+							parser.offset+k, int(octet))
+					}
+
+					// Decode the octet.
+					value = (value << 6) + rune(octet&0x3F)
+				}
 
-			// Check if the raw buffer contains an incomplete character.
-			if width > raw_unread {
-				if parser.eof {
+				// Check the length of the sequence against the value.
+				switch {
+				case width == 1:
+				case width == 2 && value >= 0x80:
+				case width == 3 && value >= 0x800:
+				case width == 4 && value >= 0x10000:
+				default:
 					return ini_parser_set_reader_error(parser,
-						"incomplete UTF-8 octet sequence",
+						"invalid length of a UTF-8 sequence",
 						parser.offset, -1)
 				}
-				break inner
-			}
 
-			// Decode the leading octet.
-			switch {
-			case octet&0x80 == 0x00:
-				value = rune(octet & 0x7F)
-			case octet&0xE0 == 0xC0:
-				value = rune(octet & 0x1F)
-			case octet&0xF0 == 0xE0:
-				value = rune(octet & 0x0F)
-			case octet&0xF8 == 0xF0:
-				value = rune(octet & 0x07)
-			default:
-				value = 0
-			}
+				// Check the range of the value.
+				if value >= 0xD800 && value <= 0xDFFF || value > 0x10FFFF {
+					return ini_parser_set_reader_error(parser,
+						"invalid Unicode character",
+						parser.offset, int(value))
+				}
 
-			// Check and decode the trailing octets.
-			for k := 1; k < width; k++ {
-				octet = parser.raw_buffer[parser.raw_buffer_pos+k]
+			case ini_UTF16LE_ENCODING, ini_UTF16BE_ENCODING:
 
-				// Check if the octet is valid.
-				if (octet & 0xC0) != 0x80 {
+				var low, high int
+				if parser.encoding == ini_UTF16LE_ENCODING {
+					low, high = 0, 1
+				} else {
+					low, high = 1, 0
+				}
+
+				// The UTF-16 encoding is not as simple as one might
+				// naively think.  Check RFC 2781
+				// (http://www.ietf.org/rfc/rfc2781.txt).
+				//
+				// Normally, two subsequent bytes describe a Unicode
+				// character.  However a special technique (called a
+				// surrogate pair) is used for specifying character
+				// values larger than 0xFFFF.
+				//
+				// A surrogate pair consists of two pseudo-characters:
+				//      high surrogate area (0xD800-0xDBFF)
+				//      low surrogate area (0xDC00-0xDFFF)
+				//
+				// The following formulas are used for decoding
+				// and encoding characters using surrogate pairs:
+				//
+				//  U  = U' + 0x10000   (0x01 00 00 <= U <= 0x10 FF FF)
+				//  U' = yyyyyyyyyyxxxxxxxxxx   (0 <= U' <= 0x0F FF FF)
+				//  W1 = 110110yyyyyyyyyy
+				//  W2 = 110111xxxxxxxxxx
+				//
+				// where U is the character value, W1 is the high surrogate
+				// area, W2 is the low surrogate area.
+
+				// Check for incomplete UTF-16 character.
+				if raw_unread < 2 {
+					if parser.eof {
+						return ini_parser_set_reader_error(parser,
+							"incomplete UTF-16 character",
+							parser.offset, -1)
+					}
+					break inner
+				}
+
+				// Get the character.
+				value = rune(parser.raw_buffer[parser.raw_buffer_pos+low]) +
+					(rune(parser.raw_buffer[parser.raw_buffer_pos+high]) << 8)
+
+				// Check for unexpected low surrogate area.
+				if value&0xFC00 == 0xDC00 {
 					return ini_parser_set_reader_error(parser,
-						"invalid trailing UTF-8 octet",
-						parser.offset+k, int(octet))
+						"unexpected low surrogate area",
+						parser.offset, int(value))
 				}
 
-				// Decode the octet.
-				value = (value << 6) + rune(octet&0x3F)
-			}
+				// Check for a high surrogate area.
+				if value&0xFC00 == 0xD800 {
+					width = 4
 
-			// Check the length of the sequence against the value.
-			switch {
-			case width == 1:
-			case width == 2 && value >= 0x80:
-			case width == 3 && value >= 0x800:
-			case width == 4 && value >= 0x10000:
-			default:
-				return ini_parser_set_reader_error(parser,
-					"invalid length of a UTF-8 sequence",
-					parser.offset, -1)
-			}
+					// Check for incomplete surrogate pair.
+					if raw_unread < 4 {
+						if parser.eof {
+							return ini_parser_set_reader_error(parser,
+								"incomplete UTF-16 surrogate pair",
+								parser.offset, -1)
+						}
+						break inner
+					}
 
-			// Check the range of the value.
-			if value >= 0xD800 && value <= 0xDFFF || value > 0x10FFFF {
-				return ini_parser_set_reader_error(parser,
-					"invalid Unicode character",
-					parser.offset, int(value))
+					// Get the next character.
+					value2 := rune(parser.raw_buffer[parser.raw_buffer_pos+low+2]) +
+						(rune(parser.raw_buffer[parser.raw_buffer_pos+high+2]) << 8)
+
+					// Check for a low surrogate area.
+					if value2&0xFC00 != 0xDC00 {
+						return ini_parser_set_reader_error(parser,
+							"expected low surrogate area",
+							parser.offset+2, int(value2))
+					}
+
+					// Generate the value of the surrogate pair.
+					value = 0x10000 + ((value & 0x3FF) << 10) + (value2 & 0x3FF)
+				} else {
+					width = 2
+				}
+
+			case ini_LATIN1_ENCODING:
+				// Every octet is the character of the same value.
+				value = rune(parser.raw_buffer[parser.raw_buffer_pos])
+				width = 1
+
+			default:
+				panic("impossible")
 			}
 
 			// Check if the character is in the allowed range:
@@ -164,6 +164,70 @@ func resolve(tag string, in string) (rtag string, out interface{}) {
 	return ini_BINARY_TAG, encodeBase64(in)
 }
 
+// resolvePHP resolves an unquoted value of a PHP file as PHP's
+// INI_SCANNER_TYPED mode does, with its own booleans and null.  Tagged
+// values, such as quoted ones, are resolved as usual.
+func resolvePHP(tag string, in string) (rtag string, out interface{}) {
+	if tag != "" {
+		return resolve(tag, in)
+	}
+	switch strings.ToLower(in) {
+	case "true", "on", "yes":
+		return ini_BOOL_TAG, true
+	case "false", "off", "no", "none":
+		return ini_BOOL_TAG, false
+	case "null", "":
+		return ini_NULL_TAG, nil
+	}
+	if intv, err := strconv.ParseInt(in, 10, 64); err == nil {
+		if intv == int64(int(intv)) {
+			return ini_INT_TAG, int(intv)
+		}
+		return ini_INT_TAG, intv
+	}
+	if iniStyleFloat.MatchString(in) {
+		if floatv, err := strconv.ParseFloat(in, 64); err == nil {
+			return ini_FLOAT_TAG, floatv
+		}
+	}
+	return resolve(ini_STR_TAG, in)
+}
+
+// resolvePython resolves an untagged value of a Python file: the words of
+// configparser's getboolean are booleans, regardless of case, and numbers
+// are numbers.  Anything else is a string.
+func resolvePython(tag string, in string) (rtag string, out interface{}) {
+	if tag != "" {
+		return resolve(tag, in)
+	}
+	switch strings.ToLower(in) {
+	case "true", "on", "yes":
+		return ini_BOOL_TAG, true
+	case "false", "off", "no":
+		return ini_BOOL_TAG, false
+	}
+	if intv, err := strconv.ParseInt(in, 10, 64); err == nil {
+		if intv == int64(int(intv)) {
+			return ini_INT_TAG, int(intv)
+		}
+		return ini_INT_TAG, intv
+	}
+	if iniStyleFloat.MatchString(in) {
+		if floatv, err := strconv.ParseFloat(in, 64); err == nil {
+			return ini_FLOAT_TAG, floatv
+		}
+	}
+	return resolve(ini_STR_TAG, in)
+}
+
+// resolveRaw resolves every untagged value as a string.
+func resolveRaw(tag string, in string) (rtag string, out interface{}) {
+	if tag == "" {
+		tag = ini_STR_TAG
+	}
+	return resolve(tag, in)
+}
+
 // encodeBase64 encodes s as base64 that is broken up into multiple lines
 // as appropriate for the resulting length.
 func encodeBase64(s string) string {
@@ -370,8 +370,12 @@ func ini_parser_fetch_more_tokens(parser *ini_parser_t) bool {
 		if !need_more_tokens {
 			break
 		}
-		// Fetch the next token.
-		if !ini_parser_fetch_next_token(parser) {
+		// Fetch the next token, or the whole next line when recovering.
+		if parser.recover {
+			if !ini_parser_fetch_line_tokens(parser) {
+				return false
+			}
+		} else if !ini_parser_fetch_next_token(parser) {
 			return false
 		}
 	}
@@ -380,6 +384,145 @@ func ini_parser_fetch_more_tokens(parser *ini_parser_t) bool {
 	return true
 }
 
+// Record a problem found in recovery mode.
+func ini_parser_record_error(parser *ini_parser_t, problem string, problem_mark ini_mark_t) {
+	parser.errors = append(parser.errors, ini_problem_t{problem, problem_mark})
+}
+
+// Fetch all the tokens of the next line.
+//
+// In recovery mode the parser never sees a partial line: a line is either
+// queued as a whole, or dropped after its problem was recorded.  The scanner
+// then skips to the next line break and carries on.
+func ini_parser_fetch_line_tokens(parser *ini_parser_t) bool {
+	// Ensure that the buffer is initialized.
+	if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+		return false
+	}
+	// Check if we just started scanning.  Fetch DOCUMENT-START then.
+	if !parser.document_start_produced {
+		return ini_parser_fetch_document_start(parser)
+	}
+
+	// The queue is empty, so the tokens of the line start at its head.
+	parser.tokens = parser.tokens[:0]
+	parser.tokens_head = 0
+	head := 0
+	line := -1
+	for {
+		if !ini_parser_scan_to_next_token(parser) {
+			return false
+		}
+		if is_z(parser.buffer, parser.buffer_pos) {
+			if len(parser.tokens) == head {
+				return ini_parser_fetch_document_end(parser)
+			}
+			break
+		}
+		if line < 0 {
+			line = parser.mark.line
+		} else if parser.mark.line != line {
+			break
+		}
+		if !ini_parser_fetch_next_token(parser) {
+			if parser.error != ini_SCANNER_ERROR {
+				return false
+			}
+			ini_parser_record_error(parser, parser.problem, parser.problem_mark)
+			parser.error = ini_NO_ERROR
+			parser.problem = ""
+			parser.context = ""
+			parser.tokens = parser.tokens[:head]
+			parser.section_header = false
+			parser.value_allowed = false
+			return ini_parser_skip_to_line_break(parser)
+		}
+	}
+
+	// Check the production of the line.
+	if problem, problem_mark := ini_parser_check_line_tokens(parser.tokens[head:]); problem != "" {
+		ini_parser_record_error(parser, problem, problem_mark)
+		parser.tokens = parser.tokens[:head]
+	}
+	return true
+}
+
+// Check that the tokens of a line form a complete production:
+//
+// line         ::= SECTION-START SCALAR (MAP SCALAR)* (SECTION-INHERIT SCALAR)? SECTION-ENTRY
+//                | KEY SCALAR (MAP KEY SCALAR)* VALUE SCALAR
+//
+// Return the problem and where it was found, or an empty problem.
+func ini_parser_check_line_tokens(tokens []ini_token_t) (string, ini_mark_t) {
+	i := 0
+	expect := func(typ ini_token_type_t) bool {
+		if i < len(tokens) && tokens[i].typ == typ {
+			i++
+			return true
+		}
+		return false
+	}
+	mark := func() ini_mark_t {
+		if i < len(tokens) {
+			return tokens[i].start_mark
+		}
+		return tokens[len(tokens)-1].end_mark
+	}
+	switch tokens[0].typ {
+	case ini_SECTION_START_TOKEN:
+		i++
+		if !expect(ini_SCALAR_TOKEN) {
+			return "did not find expected <scalar>", mark()
+		}
+		for expect(ini_MAP_TOKEN) {
+			if !expect(ini_SCALAR_TOKEN) {
+				return "did not find expected <scalar>", mark()
+			}
+		}
+		if expect(ini_SECTION_INHERIT_TOKEN) && !expect(ini_SCALAR_TOKEN) {
+			return "did not find expected <scalar>", mark()
+		}
+		if !expect(ini_SECTION_ENTRY_TOKEN) {
+			return "did not find expected <section-entry>", mark()
+		}
+	case ini_KEY_TOKEN:
+		for expect(ini_KEY_TOKEN) {
+			if !expect(ini_SCALAR_TOKEN) {
+				return "did not find expected <scalar>", mark()
+			}
+			if !expect(ini_MAP_TOKEN) {
+				break
+			}
+		}
+		if !expect(ini_VALUE_TOKEN) {
+			return "did not find expected <value> or <map>", mark()
+		}
+		if !expect(ini_SCALAR_TOKEN) {
+			return "did not find expected <scalar>", mark()
+		}
+	default:
+		return "did not find expected <key> or <section-start>", mark()
+	}
+	if i < len(tokens) {
+		return "did not find expected <line-break>", mark()
+	}
+	return "", ini_mark_t{}
+}
+
+// Skip the rest of the current line.
+func ini_parser_skip_to_line_break(parser *ini_parser_t) bool {
+	if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+		return false
+	}
+	for !is_breakz(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	return true
+}
+
 // The dispatcher for token fetchers.
 func ini_parser_fetch_next_token(parser *ini_parser_t) bool {
 	// Ensure that the buffer is initialized.
@@ -400,25 +543,83 @@ func ini_parser_fetch_next_token(parser *ini_parser_t) bool {
 		return ini_parser_fetch_document_end(parser)
 	}
 
+	// Is it a Java properties line?  It has no sections.
+	if parser.dialect == ini_PROPERTIES_DIALECT {
+		return ini_parser_fetch_property(parser)
+	}
+
+	// Is it a dotenv line?  It has no sections either.
+	if parser.dialect == ini_DOTENV_DIALECT {
+		return ini_parser_fetch_dotenv_variable(parser)
+	}
+
 	// Is it the section start indicator?
 	if parser.mark.column == 0 && parser.buffer[parser.buffer_pos] == '[' {
 		return ini_parser_fetch_section_start(parser)
 	}
-	if parser.buffer[parser.buffer_pos] == ':' {
+
+	// Is it the section inherit or entry indicator?  Both are only
+	// recognized inside a section header, so ':' may delimit values.
+	// Python section names may hold ':'.
+	if parser.section_header && parser.buffer[parser.buffer_pos] == ':' && parser.dialect != ini_PYTHON_DIALECT {
 		return ini_parser_fetch_section_inherit(parser)
 	}
-	if parser.buffer[parser.buffer_pos] == ']' {
+	if parser.section_header && parser.buffer[parser.buffer_pos] == ']' {
 		return ini_parser_fetch_section_entry(parser)
 	}
 
 	// Is it the item value indicator?
-	if parser.buffer[parser.buffer_pos] == '=' {
+	if is_delimiter(parser, parser.buffer, parser.buffer_pos) ||
+		parser.value_allowed && parser.blank_delimiter {
 		return ini_parser_fetch_value(parser)
 	}
 
 	return ini_parser_fetch_key(parser)
 }
 
+// Check if the character at the specified position starts a comment.
+func is_comment(parser *ini_parser_t, b []byte, i int) bool {
+	if parser.comments == nil {
+		return b[i] == '#' || b[i] == ';'
+	}
+	return bytes.IndexByte(parser.comments, b[i]) >= 0
+}
+
+// Check if the character at the specified position starts a comment that
+// ends the scalar s.
+func is_inline_comment(parser *ini_parser_t, s []byte, b []byte, i int) bool {
+	if parser.no_inline_comments || !is_comment(parser, b, i) {
+		return false
+	}
+	if parser.inline_comment_blank {
+		if len(s) == 0 {
+			return parser.blank_before
+		}
+		return is_blank(s, len(s)-1)
+	}
+	return true
+}
+
+// Check if the character at the specified position may appear in a plain
+// section key: an alphabetical character, a digit, '_', '-', any non-ASCII
+// character, or one of the extra section key characters.  Python section
+// keys may hold any character but brackets.
+func is_section_char(parser *ini_parser_t, b []byte, i int) bool {
+	if parser.dialect == ini_PYTHON_DIALECT {
+		return b[i] != '[' && b[i] != ']'
+	}
+	return is_alpha(b, i) || !is_ascii(b, i) || bytes.IndexByte(parser.section_chars, b[i]) >= 0
+}
+
+// Check if the character at the specified position is a key-value
+// delimiter.
+func is_delimiter(parser *ini_parser_t, b []byte, i int) bool {
+	if parser.delimiters == nil {
+		return b[i] == '='
+	}
+	return bytes.IndexByte(parser.delimiters, b[i]) >= 0
+}
+
 // Increase the flow level and resize the simple key list if needed.
 func ini_parser_increase_key_level(parser *ini_parser_t) bool {
 	// Increase the flow level.
@@ -440,12 +641,51 @@ func ini_parser_fetch_section_start(parser *ini_parser_t) bool {
 	start_mark := parser.mark
 	skip(parser)
 	end_mark := parser.mark
+	parser.section_header = true
 	section_start_token := ini_token_t{
 		typ:        ini_SECTION_START_TOKEN,
 		start_mark: start_mark,
 		end_mark:   end_mark,
 	}
 	ini_insert_token(parser, -1, &section_start_token)
+	if parser.dialect == ini_GIT_DIALECT {
+		return ini_parser_fetch_git_section_key(parser)
+	}
+	// Produce the SCALAR(...,plain) token.
+	// Create the SCALAR token and append it to the queue.
+	var scalar_token ini_token_t
+	if !ini_parser_scan_section_key(parser, &scalar_token) {
+		return false
+	}
+	ini_insert_token(parser, -1, &scalar_token)
+	// A double-quoted subsection name may follow, as in [remote "origin"].
+	if scalar_token.style == ini_PLAIN_SCALAR_STYLE && parser.buffer[parser.buffer_pos] == '"' {
+		if !ini_parser_scan_quoted_section_key(parser, &scalar_token) {
+			return false
+		}
+		map_token := ini_token_t{
+			typ:        ini_MAP_TOKEN,
+			start_mark: scalar_token.start_mark,
+			end_mark:   scalar_token.start_mark,
+		}
+		ini_insert_token(parser, -1, &map_token)
+		ini_insert_token(parser, -1, &scalar_token)
+	}
+
+	return true
+}
+
+func ini_parser_fetch_section_inherit(parser *ini_parser_t) bool {
+	// Consume the token.
+	start_mark := parser.mark
+	skip(parser)
+	end_mark := parser.mark
+	section_inherit_token := ini_token_t{
+		typ:        ini_SECTION_INHERIT_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+	}
+	ini_insert_token(parser, -1, &section_inherit_token)
 	// Produce the SCALAR(...,plain) token.
 	// Create the SCALAR token and append it to the queue.
 	var scalar_token ini_token_t
@@ -453,89 +693,641 @@ func ini_parser_fetch_section_start(parser *ini_parser_t) bool {
 		return false
 	}
 	ini_insert_token(parser, -1, &scalar_token)
+	return true
+}
+
+func ini_parser_fetch_section_entry(parser *ini_parser_t) bool {
+	// Consume the token.
+	start_mark := parser.mark
+	skip(parser)
+	end_mark := parser.mark
+	if !is_breakz(parser.buffer, parser.buffer_pos) {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning for the section entry", parser.mark,
+			"must have a line break before the first section key")
+	}
+	parser.section_header = false
+	token := ini_token_t{
+		typ:        ini_SECTION_ENTRY_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+	}
+	ini_insert_token(parser, -1, &token)
+	return true
+}
+
+func ini_parser_scan_section_key(parser *ini_parser_t, token *ini_token_t) bool {
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	// Is it a quoted section key?
+	if parser.buffer[parser.buffer_pos] == '"' && parser.dialect != ini_PYTHON_DIALECT {
+		return ini_parser_scan_quoted_section_key(parser, token)
+	}
+	start_mark := parser.mark
+	var s []byte
+	// Consume the content of the plain scalar.
+	for !is_breakz(parser.buffer, parser.buffer_pos) &&
+		(parser.buffer[parser.buffer_pos] != ':' || parser.dialect == ini_PYTHON_DIALECT) &&
+		parser.buffer[parser.buffer_pos] != '[' && parser.buffer[parser.buffer_pos] != ']' &&
+		(parser.buffer[parser.buffer_pos] != '"' || len(s) == 0 || !is_blank(s, len(s)-1) ||
+			is_section_char(parser, parser.buffer, parser.buffer_pos)) {
+		if !is_section_char(parser, parser.buffer, parser.buffer_pos) && !is_blank(parser.buffer, parser.buffer_pos) {
+			return ini_parser_set_scanner_error(parser,
+				"while scanning for the section key", parser.mark,
+				"found character("+string(parser.buffer[parser.buffer_pos:parser.buffer_pos+width(parser.buffer[parser.buffer_pos])])+") that cannot start for any section key")
+		}
+		// Copy the character.
+		s = read(parser, s)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	end_mark := parser.mark
+	// Trim blank characters.
+	s = bytes.Trim(s, " \t")
+	if bytes.IndexAny(s, " \t") >= 0 && !is_section_char(parser, []byte{' '}, 0) {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning for the section key", start_mark,
+			"found blank character inside the section key")
+	}
+
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+		value:      s,
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Scan a double-quoted section key.  Any character but a line break is
+// allowed, '\"' and '\\' escape a quote and a backslash.
+func ini_parser_scan_quoted_section_key(parser *ini_parser_t, token *ini_token_t) bool {
+	start_mark := parser.mark
+	skip(parser)
+	var s []byte
+	for {
+		if parser.unread < 2 && !ini_parser_update_buffer(parser, 2) {
+			return false
+		}
+		if is_breakz(parser.buffer, parser.buffer_pos) {
+			return ini_parser_set_scanner_error(parser,
+				"while scanning a quoted section key", start_mark,
+				"found unexpected end of line")
+		}
+		if parser.buffer[parser.buffer_pos] == '"' {
+			skip(parser)
+			break
+		}
+		if parser.buffer[parser.buffer_pos] == '\\' &&
+			(parser.buffer[parser.buffer_pos+1] == '"' || parser.buffer[parser.buffer_pos+1] == '\\') {
+			skip(parser)
+		}
+		s = read(parser, s)
+	}
+	end_mark := parser.mark
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	if parser.dialect == ini_GIT_DIALECT && parser.buffer[parser.buffer_pos] != ']' {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning a quoted section key", start_mark,
+			"did not find expected ']'")
+	}
+	if parser.buffer[parser.buffer_pos] != ':' && parser.buffer[parser.buffer_pos] != ']' {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning a quoted section key", start_mark,
+			"did not find expected ':' or ']'")
+	}
 
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+		value:      s,
+		style:      ini_DOUBLE_QUOTED_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Scan a git section header, up to the ']' indicator.  A section name holds
+// alphabetical characters, digits, '-' and '.', and may be followed by the
+// double-quoted name of a subsection, as in [remote "origin"].  Section names
+// are case-insensitive, so they are lowercased, while subsection names are
+// case-sensitive.  In the deprecated [section.subsection] form, the
+// subsection follows the first '.' and is lowercased too.
+//
+// Tokens:
+//
+//      SCALAR('remote', plain)
+//      MAP
+//      SCALAR('origin', double-quoted)
+//
+func ini_parser_fetch_git_section_key(parser *ini_parser_t) bool {
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	start_mark := parser.mark
+	var s []byte
+	for is_alpha(parser.buffer, parser.buffer_pos) || parser.buffer[parser.buffer_pos] == '.' {
+		s = read(parser, s)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	end_mark := parser.mark
+	name, subsection := s, []byte(nil)
+	if i := bytes.IndexByte(s, '.'); i >= 0 {
+		name, subsection = s[:i], s[i+1:]
+	}
+	if len(name) == 0 || subsection != nil && len(subsection) == 0 {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning for the section key", parser.mark,
+			"found character("+string(parser.buffer[parser.buffer_pos:parser.buffer_pos+width(parser.buffer[parser.buffer_pos])])+") that cannot start for any section key")
+	}
+	token := ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+		value:      bytes.ToLower(name),
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	ini_insert_token(parser, -1, &token)
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	token.value = nil
+	if subsection != nil {
+		token.value = bytes.ToLower(subsection)
+	} else if parser.buffer[parser.buffer_pos] == '"' {
+		if !ini_parser_scan_quoted_section_key(parser, &token) {
+			return false
+		}
+	}
+	if token.value != nil {
+		map_token := ini_token_t{
+			typ:        ini_MAP_TOKEN,
+			start_mark: token.start_mark,
+			end_mark:   token.start_mark,
+		}
+		ini_insert_token(parser, -1, &map_token)
+		ini_insert_token(parser, -1, &token)
+	}
+	if parser.buffer[parser.buffer_pos] != ']' {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning for the section key", start_mark,
+			"did not find expected ']'")
+	}
+	return true
+}
+
+// Scan a git key.  A key starts with an alphabetical character, holds
+// alphabetical characters, digits and '-', and is case-insensitive, so it
+// is lowercased.
+func ini_parser_scan_git_key(parser *ini_parser_t, token *ini_token_t) bool {
+	start_mark := parser.mark
+	var s []byte
+	for !is_blankz(parser.buffer, parser.buffer_pos) && !is_delimiter(parser, parser.buffer, parser.buffer_pos) &&
+		!is_comment(parser, parser.buffer, parser.buffer_pos) {
+		if !is_alpha(parser.buffer, parser.buffer_pos) || len(s) == 0 && is_digit(parser.buffer, parser.buffer_pos) {
+			return ini_parser_set_scanner_error(parser,
+				"while scanning for the key", parser.mark,
+				"found character("+string(parser.buffer[parser.buffer_pos:parser.buffer_pos+width(parser.buffer[parser.buffer_pos])])+") that cannot start for any key")
+		}
+		s = read(parser, s)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      bytes.ToLower(s),
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Produce the KEY, SCALAR, VALUE and SCALAR tokens of a MySQL directive,
+// such as "!include /etc/mysql/extra.cnf".  The name of the directive,
+// with its '!', is the key, and the rest of the line is the value.
+func ini_parser_fetch_mysql_directive(parser *ini_parser_t) bool {
+	start_mark := parser.mark
+	var name []byte
+	for {
+		name = read(parser, name)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+		if !is_alpha(parser.buffer, parser.buffer_pos) {
+			break
+		}
+	}
+	name_end_mark := parser.mark
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	value_start_mark := parser.mark
+	var value []byte
+	for !is_breakz(parser.buffer, parser.buffer_pos) {
+		value = read(parser, value)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	value = bytes.TrimRight(value, " \t")
+	if len(value) == 0 {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning a directive", start_mark,
+			"did not find expected file name")
+	}
+	tokens := []ini_token_t{
+		{typ: ini_KEY_TOKEN, start_mark: start_mark, end_mark: start_mark},
+		{typ: ini_SCALAR_TOKEN, start_mark: start_mark, end_mark: name_end_mark, value: name, style: ini_PLAIN_SCALAR_STYLE},
+		{typ: ini_VALUE_TOKEN, start_mark: value_start_mark, end_mark: value_start_mark},
+		{typ: ini_SCALAR_TOKEN, start_mark: value_start_mark, end_mark: parser.mark, value: value, style: ini_PLAIN_SCALAR_STYLE},
+	}
+	for i := range tokens {
+		ini_insert_token(parser, -1, &tokens[i])
+	}
+	return true
+}
+
+// Produce the KEY, SCALAR, VALUE and SCALAR tokens of a Java properties
+// line.  The key ends at the first unescaped '=', ':' or blank, and blanks
+// and one '=' or ':' separate it from the value.  A key without a value
+// holds an empty value.
+//
+// Tokens:
+//
+//      KEY
+//      SCALAR('key', plain)
+//      VALUE
+//      SCALAR('value', plain)
+//
+func ini_parser_fetch_property(parser *ini_parser_t) bool {
+	var key_token ini_token_t
+	if !ini_parser_scan_property_scalar(parser, &key_token, true) {
+		return false
+	}
+	if !ini_parser_fetch_key_tokens(parser, &key_token) {
+		return false
+	}
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	start_mark := parser.mark
+	var delimiter []byte
+	if parser.buffer[parser.buffer_pos] == '=' || parser.buffer[parser.buffer_pos] == ':' {
+		delimiter = read(parser, delimiter)
+	}
+	token := ini_token_t{
+		typ:        ini_VALUE_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      delimiter,
+	}
+	ini_insert_token(parser, -1, &token)
+	if !ini_parser_scan_property_scalar(parser, &token, false) {
+		return false
+	}
+	ini_insert_token(parser, -1, &token)
+	return true
+}
+
+// Scan the key or the value of a Java properties line.  Leading blanks are
+// skipped, and a value keeps its trailing blanks.  '\t', '\n', '\r', '\f'
+// and '\uXXXX' are escape sequences, a backslash before any other character
+// stands for that character, and a backslash before a line break continues
+// the scalar on the next line, after its leading blanks.
+func ini_parser_scan_property_scalar(parser *ini_parser_t, token *ini_token_t, key bool) bool {
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	start_mark := parser.mark
+	var s []byte
+	for {
+		if parser.unread < 3 && !ini_parser_update_buffer(parser, 3) {
+			return false
+		}
+		if is_breakz(parser.buffer, parser.buffer_pos) {
+			break
+		}
+		if key && (is_blank(parser.buffer, parser.buffer_pos) ||
+			parser.buffer[parser.buffer_pos] == '=' || parser.buffer[parser.buffer_pos] == ':') {
+			break
+		}
+		if parser.buffer[parser.buffer_pos] != '\\' {
+			s = read(parser, s)
+			continue
+		}
+		skip(parser)
+		if is_z(parser.buffer, parser.buffer_pos) {
+			break
+		}
+		if is_break(parser.buffer, parser.buffer_pos) {
+			// It is an escaped line break.
+			skip_line(parser)
+			for {
+				if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+					return false
+				}
+				if !is_blank(parser.buffer, parser.buffer_pos) {
+					break
+				}
+				skip(parser)
+			}
+			continue
+		}
+		switch parser.buffer[parser.buffer_pos] {
+		case 't':
+			s = append(s, '\t')
+		case 'n':
+			s = append(s, '\n')
+		case 'r':
+			s = append(s, '\r')
+		case 'f':
+			s = append(s, '\f')
+		case 'u':
+			value, ok := ini_parser_scan_property_escape(parser, start_mark)
+			if !ok {
+				return false
+			}
+			if value >= 0xD800 && value <= 0xDBFF {
+				// It is the high surrogate of a pair.
+				if parser.unread < 2 && !ini_parser_update_buffer(parser, 2) {
+					return false
+				}
+				if parser.buffer[parser.buffer_pos] != '\\' || parser.buffer[parser.buffer_pos+1] != 'u' {
+					return ini_parser_set_scanner_error(parser, "while scanning a property",
+						start_mark, "found invalid Unicode character escape code")
+				}
+				skip(parser)
+				low, ok := ini_parser_scan_property_escape(parser, start_mark)
+				if !ok {
+					return false
+				}
+				if low < 0xDC00 || low > 0xDFFF {
+					return ini_parser_set_scanner_error(parser, "while scanning a property",
+						start_mark, "found invalid Unicode character escape code")
+				}
+				value = 0x10000 + (value-0xD800)<<10 + (low - 0xDC00)
+			} else if value >= 0xDC00 && value <= 0xDFFF {
+				return ini_parser_set_scanner_error(parser, "while scanning a property",
+					start_mark, "found invalid Unicode character escape code")
+			}
+			s = append(s, string(rune(value))...)
+			continue
+		default:
+			s = read(parser, s)
+			continue
+		}
+		skip(parser)
+	}
+
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      s,
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Scan the 'uXXXX' of a '\uXXXX' escape sequence, and return its value.
+func ini_parser_scan_property_escape(parser *ini_parser_t, start_mark ini_mark_t) (int, bool) {
+	if parser.unread < 5 && !ini_parser_update_buffer(parser, 5) {
+		return 0, false
+	}
+	skip(parser)
+	value := 0
+	for k := 0; k < 4; k++ {
+		if !is_hex(parser.buffer, parser.buffer_pos+k) {
+			return 0, ini_parser_set_scanner_error(parser, "while scanning a property",
+				start_mark, "did not find expected hexdecimal number")
+		}
+		value = (value << 4) + as_hex(parser.buffer, parser.buffer_pos+k)
+	}
+	for k := 0; k < 4; k++ {
+		skip(parser)
+	}
+	return value, true
+}
+
+// Produce the KEY, SCALAR, VALUE and SCALAR tokens of a dotenv line, such
+// as "export NAME=value".  The export keyword is dropped, and the value is
+// plain, single-quoted or double-quoted.  Quoted values may span several
+// lines, and double-quoted ones keep their escape sequences for the
+// composer, which replaces them with their variables.
+func ini_parser_fetch_dotenv_variable(parser *ini_parser_t) bool {
+	var key_token ini_token_t
+	if !ini_parser_scan_dotenv_key(parser, &key_token) {
+		return false
+	}
+	if string(key_token.value) == "export" && is_blank(parser.buffer, parser.buffer_pos) {
+		for is_blank(parser.buffer, parser.buffer_pos) {
+			skip(parser)
+			if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+				return false
+			}
+		}
+		if !ini_parser_scan_dotenv_key(parser, &key_token) {
+			return false
+		}
+	}
+	if !ini_parser_fetch_key_tokens(parser, &key_token) {
+		return false
+	}
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	if parser.buffer[parser.buffer_pos] != '=' {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning a dotenv variable", key_token.start_mark,
+			"did not find expected '='")
+	}
+	return ini_parser_fetch_dotenv_value(parser)
+}
+
+// Scan the name of a dotenv variable, up to a blank or '='.
+func ini_parser_scan_dotenv_key(parser *ini_parser_t, token *ini_token_t) bool {
+	start_mark := parser.mark
+	var s []byte
+	for !is_blankz(parser.buffer, parser.buffer_pos) && parser.buffer[parser.buffer_pos] != '=' {
+		s = read(parser, s)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+	}
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      s,
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
 	return true
 }
 
-func ini_parser_fetch_section_inherit(parser *ini_parser_t) bool {
-	// Consume the token.
+// Produce the VALUE and SCALAR tokens of a dotenv variable.
+func ini_parser_fetch_dotenv_value(parser *ini_parser_t) bool {
 	start_mark := parser.mark
-	skip(parser)
-	end_mark := parser.mark
-	section_inherit_token := ini_token_t{
-		typ:        ini_SECTION_INHERIT_TOKEN,
+	delimiter := read(parser, nil)
+	token := ini_token_t{
+		typ:        ini_VALUE_TOKEN,
 		start_mark: start_mark,
-		end_mark:   end_mark,
+		end_mark:   parser.mark,
+		value:      delimiter,
 	}
-	ini_insert_token(parser, -1, &section_inherit_token)
-	// Produce the SCALAR(...,plain) token.
-	// Create the SCALAR token and append it to the queue.
-	var scalar_token ini_token_t
-	if !ini_parser_scan_section_key(parser, &scalar_token) {
+	ini_insert_token(parser, -1, &token)
+	parser.blank_before = false
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+		parser.blank_before = true
+	}
+	if parser.buffer[parser.buffer_pos] != '\'' && parser.buffer[parser.buffer_pos] != '"' {
+		if !ini_parser_scan_plain_scalar(parser, &token, false) {
+			return false
+		}
+		ini_insert_token(parser, -1, &token)
+		return true
+	}
+	if !ini_parser_scan_dotenv_quoted_scalar(parser, &token) {
 		return false
 	}
-	ini_insert_token(parser, -1, &scalar_token)
-	return true
-}
+	ini_insert_token(parser, -1, &token)
 
-func ini_parser_fetch_section_entry(parser *ini_parser_t) bool {
-	// Consume the token.
-	start_mark := parser.mark
-	skip(parser)
-	end_mark := parser.mark
-	if !is_break(parser.buffer, parser.buffer_pos) {
-		return ini_parser_set_scanner_error(parser,
-			"while scanning for the section entry", parser.mark,
-			"must have a line break before the first section key")
+	// Only a comment may follow the closing quote.
+	for is_blank(parser.buffer, parser.buffer_pos) {
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
 	}
-	token := ini_token_t{
-		typ:        ini_SECTION_ENTRY_TOKEN,
-		start_mark: start_mark,
-		end_mark:   end_mark,
+	if !is_breakz(parser.buffer, parser.buffer_pos) && !is_comment(parser, parser.buffer, parser.buffer_pos) {
+		return ini_parser_set_scanner_error(parser,
+			"while scanning a dotenv value", token.start_mark,
+			"did not find expected comment or line break")
 	}
-	ini_insert_token(parser, -1, &token)
+	// The comment is eaten with the whitespace before the next token.
 	return true
 }
 
-func ini_parser_scan_section_key(parser *ini_parser_t, token *ini_token_t) bool {
+// Scan a quoted dotenv value.  A single-quoted value is literal.  In a
+// double-quoted value, a backslash escapes the next character, and both are
+// kept.
+func ini_parser_scan_dotenv_quoted_scalar(parser *ini_parser_t, token *ini_token_t) bool {
 	start_mark := parser.mark
+	quote := parser.buffer[parser.buffer_pos]
+	skip(parser)
 	var s []byte
-	// Consume the content of the plain scalar.
-	for !is_break(parser.buffer, parser.buffer_pos) && parser.buffer[parser.buffer_pos] != ':' &&
-		parser.buffer[parser.buffer_pos] != '[' && parser.buffer[parser.buffer_pos] != ']' {
-		if !is_alpha(parser.buffer, parser.buffer_pos) {
+	for {
+		if parser.unread < 2 && !ini_parser_update_buffer(parser, 2) {
+			return false
+		}
+		if is_z(parser.buffer, parser.buffer_pos) {
 			return ini_parser_set_scanner_error(parser,
-				"while scanning for the section key", parser.mark,
-				"found character("+string([]byte{parser.buffer[parser.buffer_pos]})+") that cannot start for any section key")
+				"while scanning a quoted dotenv value", start_mark,
+				"found unexpected end of stream")
 		}
-		// Copy the character.
-		s = read(parser, s)
-		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
-			return false
+		if parser.buffer[parser.buffer_pos] == quote {
+			skip(parser)
+			break
+		}
+		if is_break(parser.buffer, parser.buffer_pos) {
+			s = read_line(parser, s)
+			continue
 		}
+		if quote == '"' && parser.buffer[parser.buffer_pos] == '\\' && !is_breakz(parser.buffer, parser.buffer_pos+1) {
+			s = read(parser, s)
+		}
+		s = read(parser, s)
 	}
-	end_mark := parser.mark
-	// Trim blank characters.
-	s = bytes.Trim(s, " ")
 
 	// Create a token.
 	*token = ini_token_t{
 		typ:        ini_SCALAR_TOKEN,
 		start_mark: start_mark,
-		end_mark:   end_mark,
+		end_mark:   parser.mark,
 		value:      s,
+		style:      ini_SINGLE_QUOTED_SCALAR_STYLE,
+	}
+	if quote == '"' {
+		token.style = ini_DOUBLE_QUOTED_SCALAR_STYLE
+	}
+	return true
+}
+
+// Produce the VALUE and SCALAR('true', plain) tokens of a key that stands
+// without a delimiter and a value.
+func ini_parser_fetch_boolean_value(parser *ini_parser_t) bool {
+	parser.value_allowed = false
+	token := ini_token_t{
+		typ:        ini_VALUE_TOKEN,
+		start_mark: parser.mark,
+		end_mark:   parser.mark,
+	}
+	ini_insert_token(parser, -1, &token)
+	token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: parser.mark,
+		end_mark:   parser.mark,
+		value:      []byte("true"),
 		style:      ini_PLAIN_SCALAR_STYLE,
 	}
+	ini_insert_token(parser, -1, &token)
 	return true
 }
 
 func ini_parser_fetch_key(parser *ini_parser_t) bool {
+	parser.key_column = parser.mark.column
 	for is_blank(parser.buffer, parser.buffer_pos) {
 		parser.buffer_pos++
 	}
+	if parser.dialect == ini_MYSQL_DIALECT && parser.buffer[parser.buffer_pos] == '!' {
+		return ini_parser_fetch_mysql_directive(parser)
+	}
 	// Produce the SCALAR(...,plain) token.
 	var key_token ini_token_t
-	if parser.buffer[parser.buffer_pos] == '\'' {
+	if parser.dialect == ini_GIT_DIALECT {
+		if !ini_parser_scan_git_key(parser, &key_token) {
+			return false
+		}
+	} else if parser.buffer[parser.buffer_pos] == '\'' {
 		// key must start with alpha([0-9a-zA-Z_-])
 		if !is_alpha(parser.buffer, parser.buffer_pos+1) && parser.buffer[parser.buffer_pos+1] != '~' {
 			return ini_parser_set_scanner_error(parser,
@@ -550,11 +1342,37 @@ func ini_parser_fetch_key(parser *ini_parser_t) bool {
 			return false
 		}
 	} else {
-		if !ini_parser_scan_plain_scalar(parser, &key_token) {
+		if !ini_parser_scan_plain_scalar(parser, &key_token, true) {
 			return false
 		}
 	}
-	keys := bytes.Split(key_token.value, []byte("."))
+	parser.value_allowed = true
+	if !ini_parser_fetch_key_tokens(parser, &key_token) {
+		return false
+	}
+	// Is it a key without a value?
+	if parser.boolean_keys {
+		for is_blank(parser.buffer, parser.buffer_pos) {
+			skip(parser)
+			if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+				return false
+			}
+		}
+		if is_breakz(parser.buffer, parser.buffer_pos) ||
+			!parser.no_inline_comments && is_comment(parser, parser.buffer, parser.buffer_pos) {
+			return ini_parser_fetch_boolean_value(parser)
+		}
+	}
+	return true
+}
+
+// Produce the KEY and SCALAR tokens of a key, split at dots into the MAP
+// tokens of nested keys unless keys are flat.
+func ini_parser_fetch_key_tokens(parser *ini_parser_t, key_token *ini_token_t) bool {
+	keys := [][]byte{key_token.value}
+	if !parser.flat_keys {
+		keys = bytes.Split(key_token.value, []byte("."))
+	}
 	key_len := len(keys)
 	key_start_mark := key_token.start_mark
 	for i := 0; i < key_len; i++ {
@@ -570,52 +1388,85 @@ func ini_parser_fetch_key(parser *ini_parser_t) bool {
 			end_mark:   key_start_mark,
 		}
 		ini_insert_token(parser, -1, &key_token)
-        key_end_mark := key_start_mark
-        key_end_mark.index = key_start_mark.index + len(keys[i])
-        scalar_token := ini_token_t{
-            typ:        ini_SCALAR_TOKEN,
-            start_mark: key_start_mark,
-            end_mark:   key_end_mark,
-            value:      keys[i],
-            style:      ini_PLAIN_SCALAR_STYLE,
-        }
-        ini_insert_token(parser, -1, &scalar_token)
-        if i < key_len-1 {
-            // map
-            key_start_mark = key_end_mark
-            key_end_mark.index = key_start_mark.index + 1
-            map_token := ini_token_t{
-                typ:        ini_MAP_TOKEN,
-                start_mark: key_start_mark,
-                end_mark:   key_end_mark,
-                value:      []byte("."),
-                style:      ini_PLAIN_SCALAR_STYLE,
-            }
-            ini_insert_token(parser, -1, &map_token)
-        }
-    }
+		key_end_mark := key_start_mark
+		key_end_mark.index = key_start_mark.index + len(keys[i])
+		scalar_token := ini_token_t{
+			typ:        ini_SCALAR_TOKEN,
+			start_mark: key_start_mark,
+			end_mark:   key_end_mark,
+			value:      keys[i],
+			style:      ini_PLAIN_SCALAR_STYLE,
+		}
+		ini_insert_token(parser, -1, &scalar_token)
+		if i < key_len-1 {
+			// map
+			key_start_mark = key_end_mark
+			key_end_mark.index = key_start_mark.index + 1
+			map_token := ini_token_t{
+				typ:        ini_MAP_TOKEN,
+				start_mark: key_start_mark,
+				end_mark:   key_end_mark,
+				value:      []byte("."),
+				style:      ini_PLAIN_SCALAR_STYLE,
+			}
+			ini_insert_token(parser, -1, &map_token)
+		}
+	}
 	return true
 }
 
 // Produce the VALUE(...,plain) token.
 func ini_parser_fetch_value(parser *ini_parser_t) bool {
-	// Consume the token.
+	// Consume the token, unless the key was delimited by blanks.
 	start_mark := parser.mark
-	skip(parser)
+	var delimiter []byte
+	if is_delimiter(parser, parser.buffer, parser.buffer_pos) {
+		delimiter = read(parser, delimiter)
+	}
 	end_mark := parser.mark
+	parser.value_allowed = false
 	token := ini_token_t{
 		typ:        ini_VALUE_TOKEN,
 		start_mark: start_mark,
 		end_mark:   end_mark,
-		value:      []byte("="),
+		value:      delimiter,
 	}
 	ini_insert_token(parser, -1, &token)
 
+	parser.blank_before = len(delimiter) == 0
 	for is_blank(parser.buffer, parser.buffer_pos) {
-		parser.buffer_pos++
+		skip(parser)
+		if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+			return false
+		}
+		parser.blank_before = true
 	}
 	// Produce the SCALAR(...,plain) token.
-	if parser.buffer[parser.buffer_pos] == '\'' {
+	if parser.dialect == ini_GIT_DIALECT {
+		// Is it a git value?
+		if !ini_parser_scan_git_scalar(parser, &token) {
+			return false
+		}
+		ini_insert_token(parser, -1, &token)
+	} else if parser.dialect == ini_SYSTEMD_DIALECT {
+		// Is it a systemd value?
+		if !ini_parser_scan_systemd_scalar(parser, &token) {
+			return false
+		}
+		ini_insert_token(parser, -1, &token)
+	} else if parser.dialect == ini_PYTHON_DIALECT {
+		// Is it a Python value?
+		if !ini_parser_scan_python_scalar(parser, &token) {
+			return false
+		}
+		ini_insert_token(parser, -1, &token)
+	} else if parser.dialect == ini_DESKTOP_DIALECT {
+		// Is it a desktop entry value?  Its quotes are literal.
+		if !ini_parser_scan_plain_scalar(parser, &token, false) {
+			return false
+		}
+		ini_insert_token(parser, -1, &token)
+	} else if parser.buffer[parser.buffer_pos] == '\'' {
 		// Is it a single-quoted scalar?
 		if !ini_parser_scan_scalar(parser, &token, true) {
 			return false
@@ -629,7 +1480,7 @@ func ini_parser_fetch_value(parser *ini_parser_t) bool {
 		ini_insert_token(parser, -1, &token)
 	} else {
 		// Is it a plain scalar?
-		if !ini_parser_scan_plain_scalar(parser, &token) {
+		if !ini_parser_scan_plain_scalar(parser, &token, false) {
 			return false
 		}
 		ini_insert_token(parser, -1, &token)
@@ -837,18 +1688,230 @@ func ini_parser_scan_scalar(parser *ini_parser_t, token *ini_token_t, single boo
 	return true
 }
 
-// Scan a plain scalar.
-func ini_parser_scan_plain_scalar(parser *ini_parser_t, token *ini_token_t) bool {
+// Scan a git value.  Double quotes may open and close anywhere in the value
+// and are dropped.  Outside of them, a comment character ends the value, and
+// blanks are kept as spaces, but for leading and trailing blanks.  '\n',
+// '\t', '\b', '\"' and '\\' are escape sequences, and a backslash before a
+// line break continues the value on the next line.
+func ini_parser_scan_git_scalar(parser *ini_parser_t, token *ini_token_t) bool {
+	start_mark := parser.mark
+	style := ini_PLAIN_SCALAR_STYLE
+	quoted := false
+	spaces := 0
+	var s []byte
+	for {
+		if parser.unread < 3 && !ini_parser_update_buffer(parser, 3) {
+			return false
+		}
+		if is_breakz(parser.buffer, parser.buffer_pos) {
+			if quoted {
+				return ini_parser_set_scanner_error(parser,
+					"while scanning a git value", start_mark,
+					"found unexpected end of line")
+			}
+			break
+		}
+		if !quoted {
+			if is_blank(parser.buffer, parser.buffer_pos) {
+				if len(s) > 0 {
+					spaces++
+				}
+				skip(parser)
+				continue
+			}
+			if is_comment(parser, parser.buffer, parser.buffer_pos) {
+				break
+			}
+		}
+		for ; spaces > 0; spaces-- {
+			s = append(s, ' ')
+		}
+		switch parser.buffer[parser.buffer_pos] {
+		case '"':
+			quoted = !quoted
+			style = ini_DOUBLE_QUOTED_SCALAR_STYLE
+			skip(parser)
+		case '\\':
+			if is_break(parser.buffer, parser.buffer_pos+1) {
+				// It is an escaped line break.
+				skip(parser)
+				skip_line(parser)
+				continue
+			}
+			switch parser.buffer[parser.buffer_pos+1] {
+			case 'n':
+				s = append(s, '\n')
+			case 't':
+				s = append(s, '\t')
+			case 'b':
+				s = append(s, '\b')
+			case '"', '\\':
+				s = append(s, parser.buffer[parser.buffer_pos+1])
+			default:
+				return ini_parser_set_scanner_error(parser,
+					"while scanning a git value", start_mark,
+					"found unknown escape character")
+			}
+			skip(parser)
+			skip(parser)
+		default:
+			s = read(parser, s)
+		}
+	}
+
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      s,
+		style:      style,
+	}
+	return true
+}
+
+// Scan a systemd value.  The value is read verbatim up to the line break,
+// except that a backslash before a line break is replaced by a space and
+// continues the value on the next line.  Comment lines inside a continued
+// value are skipped.
+func ini_parser_scan_systemd_scalar(parser *ini_parser_t, token *ini_token_t) bool {
+	start_mark := parser.mark
+	var s []byte
+	for {
+		if parser.unread < 3 && !ini_parser_update_buffer(parser, 3) {
+			return false
+		}
+		if is_breakz(parser.buffer, parser.buffer_pos) {
+			break
+		}
+		if parser.buffer[parser.buffer_pos] == '\\' && is_break(parser.buffer, parser.buffer_pos+1) {
+			// It is an escaped line break.
+			s = append(s, ' ')
+			skip(parser)
+			skip_line(parser)
+			for {
+				if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+					return false
+				}
+				if !is_comment(parser, parser.buffer, parser.buffer_pos) {
+					break
+				}
+				for !is_breakz(parser.buffer, parser.buffer_pos) {
+					skip(parser)
+					if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+						return false
+					}
+				}
+				if parser.unread < 2 && !ini_parser_update_buffer(parser, 2) {
+					return false
+				}
+				skip_line(parser)
+			}
+			continue
+		}
+		s = read(parser, s)
+	}
+	s = bytes.Trim(s, " \t")
+
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   parser.mark,
+		value:      s,
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Scan a Python value.  The value is read verbatim up to the line break,
+// and continues on the lines after it that are indented deeper than its
+// key, joined with line breaks.  Comment lines are skipped, and empty lines
+// are kept but for the ones that end the value.
+func ini_parser_scan_python_scalar(parser *ini_parser_t, token *ini_token_t) bool {
+	indent := parser.key_column
+	start_mark := parser.mark
+	end_mark := parser.mark
+	var s []byte
+	breaks := 0
+	for {
+		var line []byte
+		for !is_breakz(parser.buffer, parser.buffer_pos) {
+			line = read(parser, line)
+			if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+				return false
+			}
+		}
+		line = bytes.TrimRight(line, " \t")
+		if len(line) > 0 {
+			for ; breaks > 0; breaks-- {
+				s = append(s, '\n')
+			}
+			s = append(s, line...)
+			end_mark = parser.mark
+		}
+
+		// Is the value continued on the next line?
+		for {
+			if is_z(parser.buffer, parser.buffer_pos) {
+				break
+			}
+			if parser.unread < 2 && !ini_parser_update_buffer(parser, 2) {
+				return false
+			}
+			skip_line(parser)
+			breaks++
+			for is_blank(parser.buffer, parser.buffer_pos) {
+				skip(parser)
+				if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+					return false
+				}
+			}
+			if is_comment(parser, parser.buffer, parser.buffer_pos) {
+				breaks--
+				for !is_breakz(parser.buffer, parser.buffer_pos) {
+					skip(parser)
+					if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+						return false
+					}
+				}
+				continue
+			}
+			if !is_breakz(parser.buffer, parser.buffer_pos) {
+				break
+			}
+		}
+		if is_z(parser.buffer, parser.buffer_pos) || parser.mark.column <= indent {
+			break
+		}
+	}
+
+	// Create a token.
+	*token = ini_token_t{
+		typ:        ini_SCALAR_TOKEN,
+		start_mark: start_mark,
+		end_mark:   end_mark,
+		value:      s,
+		style:      ini_PLAIN_SCALAR_STYLE,
+	}
+	return true
+}
+
+// Scan a plain scalar.  A key ends at a delimiter; a value only ends at the
+// default '=' delimiter.
+func ini_parser_scan_plain_scalar(parser *ini_parser_t, token *ini_token_t, key bool) bool {
 	start_mark := parser.mark
 	var s []byte
-	// Consume the content of the plain scalar.
 	// Consume the content of the plain scalar.
 	for !is_breakz(parser.buffer, parser.buffer_pos) {
 		// Check for a comment.
-		if parser.buffer[parser.buffer_pos] == '#' || parser.buffer[parser.buffer_pos] == ';'{
+		if is_inline_comment(parser, s, parser.buffer, parser.buffer_pos) {
+			break
+		}
+		if (key || parser.delimiters == nil) && is_delimiter(parser, parser.buffer, parser.buffer_pos) {
 			break
 		}
-		if parser.buffer[parser.buffer_pos] == '=' {
+		if key && parser.blank_delimiter && is_blank(parser.buffer, parser.buffer_pos) {
 			break
 		}
 		// Copy the character.
@@ -859,7 +1922,7 @@ func ini_parser_scan_plain_scalar(parser *ini_parser_t, token *ini_token_t) bool
 	}
 	end_mark := parser.mark
 	// Trim blank characters.
-	s = bytes.Trim(s, " ")
+	s = bytes.Trim(s, " \t")
 
 	// Create a token.
 	*token = ini_token_t{
@@ -896,14 +1959,27 @@ func ini_parser_scan_to_next_token(parser *ini_parser_t) bool {
 			}
 		}
 
-		// Eat a comment until a line break.
-		if parser.buffer[parser.buffer_pos] == '#' || parser.buffer[parser.buffer_pos] == ';' {
+		// Eat a comment until a line break, and keep it if asked to.
+		if is_comment(parser, parser.buffer, parser.buffer_pos) {
+			comment := ini_comment_t{mark: parser.mark}
+			skip(parser)
+			if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
+				return false
+			}
 			for !is_breakz(parser.buffer, parser.buffer_pos) {
-				skip(parser)
+				if parser.keep_comments {
+					comment.value = read(parser, comment.value)
+				} else {
+					skip(parser)
+				}
 				if parser.unread < 1 && !ini_parser_update_buffer(parser, 1) {
 					return false
 				}
 			}
+			if parser.keep_comments {
+				comment.value = bytes.TrimSpace(comment.value)
+				parser.comment_list = append(parser.comment_list, comment)
+			}
 		}
 
 		// If it is a line break, eat it.
@@ -912,6 +1988,7 @@ func ini_parser_scan_to_next_token(parser *ini_parser_t) bool {
 				return false
 			}
 			skip_line(parser)
+			parser.value_allowed = false
 		} else {
 			break // We have found a token.
 		}
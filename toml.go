@@ -0,0 +1,661 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToTOML converts an INI document into a TOML document.  Sections become
+// tables, dotted keys dotted keys, the keys of the default section the keys
+// above the tables, and the values of repeated keys arrays.  Values keep
+// the type they decode into with Unmarshal, and keys their order.  Comments
+// are kept, and inherited keys are copied into the tables.
+//
+// TOML has no null, so empty values are reported in a *ConvertError.
+func ToTOML(in []byte) ([]byte, error) {
+	return ToTOMLWithOptions(in, ConvertOptions{})
+}
+
+// ToTOMLWithOptions is like ToTOML, but the document is read as described
+// by opts.
+func ToTOMLWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	doc, err := ini_document(in, opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	var w toml_writer
+	var tables []*entry
+	for _, e := range doc.entries {
+		if _, ok := e.value.([]*entry); ok {
+			tables = append(tables, e)
+		} else {
+			w.pair(nil, e)
+		}
+	}
+	for _, e := range tables {
+		if w.buf.Len() > 0 {
+			w.buf.WriteByte('\n')
+		}
+		w.comments(e.comments)
+		w.buf.WriteString("[" + toml_key(e.key) + "]")
+		w.comment(e.comment)
+		for _, child := range e.value.([]*entry) {
+			w.pair(nil, child)
+		}
+	}
+	if len(doc.comments) > 0 && w.buf.Len() > 0 {
+		w.buf.WriteByte('\n')
+	}
+	w.comments(doc.comments)
+	if len(w.errors) > 0 {
+		return nil, &ConvertError{w.errors}
+	}
+	return w.buf.Bytes(), nil
+}
+
+// FromTOML converts a TOML document into an INI document.  It is the
+// reverse of ToTOML: tables become sections, tables nested in them and
+// dotted keys dotted keys, and the keys above the tables the keys of the
+// default section.  Dates and times become strings.  Comments are kept.
+//
+// Arrays of tables, arrays of arrays or inline tables, arrays anywhere but
+// in git and systemd files, keys holding a '.' in the default dialect, and
+// nested tables in the dialects that read dotted keys as plain keys,
+// cannot be expressed in INI and are reported in a *ConvertError.
+func FromTOML(in []byte) ([]byte, error) {
+	return FromTOMLWithOptions(in, ConvertOptions{})
+}
+
+// FromTOMLWithOptions is like FromTOML, but the document is written as
+// described by opts.
+func FromTOMLWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	r := toml_reader{text: strings.TrimPrefix(string(in), "\ufeff"), defined: make(map[*entry]bool)}
+	doc := r.document()
+	return ini_write(doc, opts.Dump, r.errors)
+}
+
+// ----------------------------------------------------------------------------
+// TOML writer
+
+type toml_writer struct {
+	buf    bytes.Buffer
+	errors []string
+}
+
+// pair writes a key and its value.  The keys of a map are dotted keys.
+func (w *toml_writer) pair(path []string, e *entry) {
+	path = append(path[:len(path):len(path)], toml_key(e.key))
+	if entries, ok := e.value.([]*entry); ok && len(entries) > 0 {
+		w.comments(e.comments)
+		for _, child := range entries {
+			w.pair(path, child)
+		}
+		return
+	}
+	w.comments(e.comments)
+	w.buf.WriteString(strings.Join(path, ".") + " = " + w.value(e, e.value))
+	w.comment(e.comment)
+}
+
+// value returns a value in TOML.
+func (w *toml_writer) value(e *entry, value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		w.errors = append(w.errors, e.problem("key '%s' has no value, which TOML cannot express", e.key))
+		return `""`
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		switch {
+		case math.IsInf(value, 1):
+			return "inf"
+		case math.IsInf(value, -1):
+			return "-inf"
+		case math.IsNaN(value):
+			return "nan"
+		}
+		s := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case int, int64:
+		return fmt.Sprint(value)
+	case uint64:
+		if value > math.MaxInt64 {
+			w.errors = append(w.errors, e.problem("key '%s' holds %d, which TOML integers cannot hold", e.key, value))
+		}
+		return fmt.Sprint(value)
+	case string:
+		return toml_string(value)
+	case []byte:
+		return toml_string(string(value))
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			values = append(values, w.value(e, v))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case []*entry:
+		pairs := make([]string, 0, len(value))
+		for _, child := range value {
+			pairs = append(pairs, toml_key(child.key)+" = "+w.value(child, child.value))
+		}
+		if len(pairs) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }"
+	}
+	return toml_string(fmt.Sprint(value))
+}
+
+// comment ends a line, with a comment if there is one.
+func (w *toml_writer) comment(comment string) {
+	if comment != "" {
+		w.buf.WriteString(" # " + comment)
+	}
+	w.buf.WriteByte('\n')
+}
+
+// comments writes comment lines.
+func (w *toml_writer) comments(comments []string) {
+	for _, comment := range comments {
+		if comment == "" {
+			w.buf.WriteString("#\n")
+		} else {
+			w.buf.WriteString("# " + comment + "\n")
+		}
+	}
+}
+
+// toml_key returns a key in TOML: bare when it only holds ASCII letters,
+// digits, '_' and '-', and quoted otherwise.
+func toml_key(key string) string {
+	bare := key != ""
+	for i := 0; bare && i < len(key); i++ {
+		bare = key[i] < utf8.RuneSelf && is_alpha([]byte(key), i)
+	}
+	if bare {
+		return key
+	}
+	return toml_string(key)
+}
+
+// toml_string returns a string as a TOML basic string.
+func toml_string(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buf, `\u%04X`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// ----------------------------------------------------------------------------
+// TOML reader
+
+// A toml_reader reads the tables, keys, values and comments of a TOML
+// document.
+type toml_reader struct {
+	text     string
+	pos      int
+	line     int      // The current line, from 0.
+	comments []string // The comment lines above the next key or table.
+	errors   []string
+	defined  map[*entry]bool // The tables that headers or dotted keys define.
+}
+
+// toml_error is raised to give up on the rest of a line.
+type toml_error struct{}
+
+// fail records a problem of the current line, and gives up on the line.
+func (r *toml_reader) fail(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf("line %d: %s", r.line+1, fmt.Sprintf(format, args...)))
+	panic(toml_error{})
+}
+
+// document reads the document.
+func (r *toml_reader) document() *document {
+	root := &entry{value: []*entry{}}
+	table := root
+	for r.pos < len(r.text) {
+		table = r.statement(root, table)
+	}
+	return &document{entries: root.value.([]*entry), comments: r.comments}
+}
+
+// statement reads a line: a table header, a key and its value, a comment
+// or nothing.  It returns the current table.
+func (r *toml_reader) statement(root, table *entry) (next *entry) {
+	next = table
+	line := r.line
+	defer func() {
+		if v := recover(); v != nil {
+			if _, ok := v.(toml_error); !ok {
+				panic(v)
+			}
+			// Skip the rest of the line.
+			for r.pos < len(r.text) && r.peek() != '\n' {
+				r.pos++
+			}
+			r.advance()
+		}
+	}()
+	r.blanks()
+	switch {
+	case r.pos >= len(r.text):
+		return table
+	case r.peek() == '\n' || strings.HasPrefix(r.text[r.pos:], "\r\n"):
+		r.newline()
+		return table
+	case r.peek() == '#':
+		r.comments = append(r.comments, r.comment())
+		r.newline()
+		return table
+	case strings.HasPrefix(r.text[r.pos:], "[["):
+		r.fail("arrays of tables cannot be expressed in INI")
+	case r.peek() == '[':
+		r.pos++
+		r.blanks()
+		path := r.key()
+		if r.peek() != ']' {
+			r.fail("did not find expected ']'")
+		}
+		r.pos++
+		e := r.table(root, path, line)
+		if r.defined[e] {
+			r.fail("found the table '%s' twice", strings.Join(path, "."))
+		}
+		r.defined[e] = true
+		e.comments = append(e.comments, r.take()...)
+		e.comment = r.end()
+		return e
+	}
+	path := r.key()
+	if r.peek() != '=' {
+		r.fail("did not find expected '='")
+	}
+	r.pos++
+	r.blanks()
+	// The tables of dotted keys are defined by them.
+	parent := table
+	for _, key := range path[:len(path)-1] {
+		parent = r.table(parent, []string{key}, line)
+		r.defined[parent] = true
+	}
+	key := path[len(path)-1]
+	entries := parent.value.([]*entry)
+	if entry_find(entries, key) != nil {
+		r.fail("found the key '%s' twice", key)
+	}
+	e := &entry{key: key, line: line + 1, comments: r.take()}
+	e.value = r.value()
+	e.comment = r.end()
+	parent.value = append(entries, e)
+	return table
+}
+
+// table returns the table at the path below parent, and adds the tables
+// that are missing.
+func (r *toml_reader) table(parent *entry, path []string, line int) *entry {
+	for _, key := range path {
+		entries := parent.value.([]*entry)
+		e := entry_find(entries, key)
+		if e == nil {
+			e = &entry{key: key, value: []*entry{}, line: line + 1}
+			parent.value = append(entries, e)
+		} else if _, ok := e.value.([]*entry); !ok {
+			r.fail("the key '%s' is not a table", key)
+		}
+		parent = e
+	}
+	return parent
+}
+
+// take returns the comments collected since the last key or table.
+func (r *toml_reader) take() []string {
+	comments := r.comments
+	r.comments = nil
+	return comments
+}
+
+// end reads the end of a line, and returns its comment.
+func (r *toml_reader) end() (comment string) {
+	r.blanks()
+	if r.peek() == '#' {
+		comment = r.comment()
+	}
+	if r.pos < len(r.text) {
+		if r.peek() != '\n' && !strings.HasPrefix(r.text[r.pos:], "\r\n") {
+			r.fail("found unexpected text at the end of the line")
+		}
+		r.newline()
+	}
+	return comment
+}
+
+// comment reads a comment, without its '#'.
+func (r *toml_reader) comment() string {
+	start := r.pos + 1
+	for r.pos < len(r.text) && r.peek() != '\n' {
+		r.pos++
+	}
+	return strings.TrimSpace(r.text[start:r.pos])
+}
+
+func (r *toml_reader) peek() byte {
+	if r.pos < len(r.text) {
+		return r.text[r.pos]
+	}
+	return 0
+}
+
+func (r *toml_reader) advance() {
+	if r.peek() == '\n' {
+		r.line++
+	}
+	r.pos++
+}
+
+func (r *toml_reader) newline() {
+	if r.peek() == '\r' {
+		r.pos++
+	}
+	r.advance()
+}
+
+// blanks skips spaces and tabs.
+func (r *toml_reader) blanks() {
+	for r.peek() == ' ' || r.peek() == '\t' {
+		r.pos++
+	}
+}
+
+// gaps skips blanks, line breaks and comments inside an array.
+func (r *toml_reader) gaps() {
+	for {
+		r.blanks()
+		switch {
+		case r.peek() == '#':
+			r.comment()
+		case r.peek() == '\n' || strings.HasPrefix(r.text[r.pos:], "\r\n"):
+			r.newline()
+		default:
+			return
+		}
+	}
+}
+
+// key reads a dotted key, and the blanks after it.
+func (r *toml_reader) key() (path []string) {
+	for {
+		var key string
+		switch c := r.peek(); {
+		case c == '"' || c == '\'':
+			key = r.string(false)
+		default:
+			start := r.pos
+			for r.pos < len(r.text) && is_alpha([]byte(r.text), r.pos) && r.text[r.pos] < utf8.RuneSelf {
+				r.pos++
+			}
+			if start == r.pos {
+				r.fail("did not find expected key")
+			}
+			key = r.text[start:r.pos]
+		}
+		path = append(path, key)
+		r.blanks()
+		if r.peek() != '.' {
+			return path
+		}
+		r.pos++
+		r.blanks()
+	}
+}
+
+var (
+	tomlDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)([Zz]|[-+]\d{2}:\d{2})?`)
+	tomlNumber   = regexp.MustCompile(`^[-+0-9a-zA-Z_.]+`)
+)
+
+// value reads a value.
+func (r *toml_reader) value() interface{} {
+	switch c := r.peek(); {
+	case c == '"' || c == '\'':
+		return r.string(true)
+	case c == '[':
+		r.pos++
+		values := []interface{}{}
+		for {
+			r.gaps()
+			if r.peek() == ']' {
+				r.pos++
+				return values
+			}
+			values = append(values, r.value())
+			r.gaps()
+			if r.peek() == ',' {
+				r.pos++
+			} else if r.peek() != ']' {
+				r.fail("did not find expected ',' or ']'")
+			}
+		}
+	case c == '{':
+		r.pos++
+		table := &entry{value: []*entry{}}
+		for {
+			r.blanks()
+			if r.peek() == '}' {
+				r.pos++
+				return table.value
+			}
+			path := r.key()
+			if r.peek() != '=' {
+				r.fail("did not find expected '='")
+			}
+			r.pos++
+			r.blanks()
+			parent := r.table(table, path[:len(path)-1], r.line)
+			key := path[len(path)-1]
+			entries := parent.value.([]*entry)
+			if entry_find(entries, key) != nil {
+				r.fail("found the key '%s' twice", key)
+			}
+			parent.value = append(entries, &entry{key: key, value: r.value()})
+			r.blanks()
+			if r.peek() == ',' {
+				r.pos++
+			} else if r.peek() != '}' {
+				r.fail("did not find expected ',' or '}'")
+			}
+		}
+	case strings.HasPrefix(r.text[r.pos:], "true"):
+		r.pos += 4
+		return true
+	case strings.HasPrefix(r.text[r.pos:], "false"):
+		r.pos += 5
+		return false
+	}
+	if s := tomlDateTime.FindString(r.text[r.pos:]); s != "" {
+		r.pos += len(s)
+		return s
+	}
+	s := tomlNumber.FindString(r.text[r.pos:])
+	r.pos += len(s)
+	switch s {
+	case "inf", "+inf":
+		return math.Inf(1)
+	case "-inf":
+		return math.Inf(-1)
+	case "nan", "+nan", "-nan":
+		return math.NaN()
+	}
+	if !toml_underscores(s) {
+		r.fail("found the invalid value '%s'", s)
+	}
+	// Integers have no leading zeros, unless they are hexadecimal, octal
+	// or binary.
+	plain := strings.Replace(s, "_", "", -1)
+	digits := strings.TrimLeft(plain, "+-")
+	prefixed := len(digits) > 1 && digits[0] == '0' && strings.IndexByte("xob", digits[1]) >= 0
+	if prefixed || digits == "0" || digits != "" && digits[0] != '0' {
+		i, err := strconv.ParseInt(plain, 0, 64)
+		if err == nil {
+			if i == int64(int(i)) {
+				return int(i)
+			}
+			return i
+		}
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			r.fail("found the integer '%s', which does not fit in 64 bits", s)
+		}
+	}
+	if f, err := strconv.ParseFloat(plain, 64); err == nil && !prefixed && strings.IndexAny(plain, "xXpP") < 0 {
+		return f
+	}
+	if s == "" {
+		r.fail("did not find expected value")
+	}
+	r.fail("found the invalid value '%s'", s)
+	return nil
+}
+
+// toml_underscores reports whether every underscore of a number is
+// between two digits.
+func toml_underscores(s string) bool {
+	b := []byte(s)
+	for i := range b {
+		if b[i] == '_' && (i == 0 || i == len(b)-1 || !is_hex(b, i-1) || !is_hex(b, i+1)) {
+			return false
+		}
+	}
+	return true
+}
+
+// string reads a basic or literal string, or a multi-line one if it may
+// be.
+func (r *toml_reader) string(multi bool) string {
+	quote := r.text[r.pos : r.pos+1]
+	if multi && strings.HasPrefix(r.text[r.pos:], quote+quote+quote) {
+		r.pos += 3
+		// A line break right after the delimiter is trimmed.
+		if r.peek() == '\n' || strings.HasPrefix(r.text[r.pos:], "\r\n") {
+			r.newline()
+		}
+		var buf []byte
+		for {
+			if r.pos >= len(r.text) {
+				r.fail("found a multi-line string that does not end")
+			}
+			if strings.HasPrefix(r.text[r.pos:], quote+quote+quote) {
+				// Up to two quotes of the string may stand before the
+				// delimiter.
+				n := 3
+				for n < 5 && r.pos+n < len(r.text) && r.text[r.pos+n] == quote[0] {
+					n++
+				}
+				buf = append(buf, strings.Repeat(quote, n-3)...)
+				r.pos += n
+				return string(buf)
+			}
+			if quote == `"` && r.peek() == '\\' {
+				// A backslash at the end of a line trims the blanks and
+				// the line breaks after it.
+				rest := strings.TrimLeft(r.text[r.pos+1:], " \t")
+				if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+					r.pos++
+					for strings.IndexByte(" \t\r\n", r.peek()) >= 0 && r.pos < len(r.text) {
+						r.advance()
+					}
+					continue
+				}
+				buf = r.escape(buf)
+				continue
+			}
+			if r.peek() == '\r' && strings.HasPrefix(r.text[r.pos:], "\r\n") {
+				r.pos++
+			}
+			buf = append(buf, r.peek())
+			r.advance()
+		}
+	}
+	r.pos++
+	var buf []byte
+	for {
+		switch c := r.peek(); {
+		case r.pos >= len(r.text) || c == '\n':
+			r.fail("found a string that does not end on its line")
+		case c == quote[0]:
+			r.pos++
+			return string(buf)
+		case c == '\\' && quote == `"`:
+			buf = r.escape(buf)
+		default:
+			buf = append(buf, c)
+			r.pos++
+		}
+	}
+}
+
+// escape reads an escape sequence of a basic string into buf.
+func (r *toml_reader) escape(buf []byte) []byte {
+	r.pos++
+	c := r.peek()
+	r.pos++
+	switch c {
+	case 'b':
+		return append(buf, '\b')
+	case 't':
+		return append(buf, '\t')
+	case 'n':
+		return append(buf, '\n')
+	case 'f':
+		return append(buf, '\f')
+	case 'r':
+		return append(buf, '\r')
+	case 'e':
+		return append(buf, 0x1b)
+	case '"', '\\':
+		return append(buf, c)
+	case 'u', 'U':
+		width := 4
+		if c == 'U' {
+			width = 8
+		}
+		if r.pos+width <= len(r.text) {
+			if code, err := strconv.ParseUint(r.text[r.pos:r.pos+width], 16, 32); err == nil && utf8.ValidRune(rune(code)) {
+				r.pos += width
+				return append(buf, string(rune(code))...)
+			}
+		}
+	}
+	r.fail("found an invalid escape sequence")
+	return nil
+}
@@ -0,0 +1,169 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var toTOMLTests = []struct {
+	options ini.ConvertOptions
+	data    string
+	toml    string
+}{
+	{
+		ini.ConvertOptions{},
+		"",
+		"",
+	}, {
+		ini.ConvertOptions{},
+		"# service\nname = app\n\n[server]\nhost = localhost ; the host\nport = 8080\nratio = 0.5\ndebug = off\ndb.user = root\npath = C:\\dir\n",
+		`# service
+name = "app"
+
+[server]
+host = "localhost" # the host
+port = 8080
+ratio = 0.5
+debug = false
+db.user = "root"
+path = "C:\\dir"
+name = "app"
+`,
+	}, {
+		ini.ConvertOptions{Load: ini.LoadOptions{Dialect: ini.DialectGit}},
+		"[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n[core]\n\tbare = false\n",
+		`[remote]
+origin.fetch = ["a", "b"]
+
+[core]
+bare = false
+`,
+	},
+}
+
+func (s *S) TestToTOML(c *C) {
+	for _, item := range toTOMLTests {
+		out, err := ini.ToTOMLWithOptions([]byte(item.data), item.options)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(string(out), Equals, item.toml, Commentf("data: %q", item.data))
+	}
+}
+
+func (s *S) TestToTOMLErrors(c *C) {
+	_, err := ini.ToTOML([]byte("[a]\nempty =\n"))
+	c.Assert(err, ErrorMatches, "ini: cannot convert:\n  line 2: key 'empty' has no value, which TOML cannot express")
+}
+
+var fromTOMLTests = []struct {
+	options ini.ConvertOptions
+	toml    string
+	data    string
+}{
+	{
+		ini.ConvertOptions{},
+		"",
+		"",
+	}, {
+		ini.ConvertOptions{},
+		`# service
+name = "my app"  # the name
+when = 1979-05-27T07:32:00Z
+
+[server]
+host = 'localhost'
+port = 8_080
+ratio = inf
+"quoted key" = """
+multi \
+line"""
+tls = { enabled = true, cert = "/etc/cert.pem" }
+
+[server.db]
+user = "root"
+`,
+		`# service
+# the name
+name = my app
+when = 1979-05-27T07:32:00Z
+
+[server]
+host = localhost
+port = 8080
+ratio = .inf
+quoted key = multi line
+tls.enabled = true
+tls.cert = /etc/cert.pem
+db.user = root
+`,
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectGit}},
+		"[remote.origin]\nfetch = [\n  \"a\",\n  \"b\",\n]\n",
+		"[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n",
+	}, {
+		ini.ConvertOptions{},
+		"[a.b]\nk = 1\n",
+		"[a]\nb.k = 1\n",
+	},
+}
+
+func (s *S) TestFromTOML(c *C) {
+	for _, item := range fromTOMLTests {
+		out, err := ini.FromTOMLWithOptions([]byte(item.toml), item.options)
+		c.Assert(err, IsNil, Commentf("toml: %q", item.toml))
+		c.Assert(string(out), Equals, item.data, Commentf("toml: %q", item.toml))
+	}
+}
+
+var fromTOMLErrorTests = []struct {
+	options ini.ConvertOptions
+	toml    string
+	error   string
+}{
+	{
+		ini.ConvertOptions{},
+		"[[servers]]\nhost = \"a\"\n",
+		"ini: cannot convert:\n  line 1: arrays of tables cannot be expressed in INI",
+	}, {
+		ini.ConvertOptions{},
+		"a = 1\na = 2\nb = \"open\n[c]\nd = [1, 2]\n",
+		"ini: cannot convert:\n" +
+			"  line 2: found the key 'a' twice\n" +
+			"  line 3: found a string that does not end on its line\n" +
+			"  line 5: key 'd' holds a list, which the keys of this dialect cannot hold",
+	}, {
+		ini.ConvertOptions{},
+		"[a]\nb.c = 1\n[a.b]\n[a]\nx = 1__0\ny = _1\nz = 99999999999999999999\n",
+		"ini: cannot convert:\n" +
+			"  line 3: found the table 'a.b' twice\n" +
+			"  line 4: found the table 'a' twice\n" +
+			"  line 5: found the invalid value '1__0'\n" +
+			"  line 6: found the invalid value '_1'\n" +
+			"  line 7: found the integer '99999999999999999999', which does not fit in 64 bits",
+	}, {
+		ini.ConvertOptions{},
+		"[s]\n\"a.b\" = 1\n",
+		"ini: cannot convert:\n  line 2: key 'a.b' holds a '.', which this dialect reads as nested keys",
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectPHP}},
+		"[a.b]\nk = 1\n",
+		"ini: cannot convert:\n  line 1: key 'b' holds a map, which this dialect reads back as flat dotted keys",
+	},
+}
+
+func (s *S) TestFromTOMLErrors(c *C) {
+	for _, item := range fromTOMLErrorTests {
+		_, err := ini.FromTOMLWithOptions([]byte(item.toml), item.options)
+		c.Assert(err, NotNil, Commentf("toml: %q", item.toml))
+		c.Assert(err.Error(), Equals, item.error, Commentf("toml: %q", item.toml))
+	}
+}
+
+func (s *S) TestFromTOMLNestedTable(c *C) {
+	// The dotted keys of a nested table read back as the same table.
+	out, err := ini.FromTOML([]byte("[a.b]\nk = 1\n"))
+	c.Assert(err, IsNil)
+	var value map[string]map[string]map[string]int
+	c.Assert(ini.Unmarshal(out, &value), IsNil)
+	c.Assert(value, DeepEquals, map[string]map[string]map[string]int{"a": {"b": {"k": 1}}})
+}
@@ -1,5 +1,10 @@
 package ini
 
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
 // Set the writer error and return false.
 func ini_emitter_set_writer_error(emitter *ini_emitter_t, problem string) bool {
 	emitter.error = ini_WRITER_ERROR
@@ -18,9 +23,93 @@ func ini_emitter_flush(emitter *ini_emitter_t) bool {
 		return true
 	}
 
-	if err := emitter.write_handler(emitter, emitter.buffer[:emitter.buffer_pos]); err != nil {
+	// If the output encoding is UTF-8, we don't need to recode the buffer.
+	if emitter.encoding == ini_UTF8_ENCODING || emitter.encoding == ini_ANY_ENCODING {
+		if err := emitter.write_handler(emitter, emitter.buffer[:emitter.buffer_pos]); err != nil {
+			return ini_emitter_set_writer_error(emitter, "write error: "+err.Error())
+		}
+		emitter.buffer_pos = 0
+		return true
+	}
+
+	// Recode the buffer into the raw buffer.
+	if emitter.encoding == ini_LATIN1_ENCODING {
+		return ini_emitter_flush_latin1(emitter)
+	}
+	var low, high int
+	if emitter.encoding == ini_UTF16LE_ENCODING {
+		low, high = 0, 1
+	} else {
+		low, high = 1, 0
+	}
+
+	pos := 0
+	for pos < emitter.buffer_pos {
+		// See the "readerc.go" code for more details on UTF-8 encoding.  Note
+		// that we assume that the buffer contains a valid UTF-8 sequence.
+
+		// Read the next UTF-8 character.
+		octet := emitter.buffer[pos]
+
+		var w int
+		var value rune
+		switch {
+		case octet&0x80 == 0x00:
+			w, value = 1, rune(octet&0x7F)
+		case octet&0xE0 == 0xC0:
+			w, value = 2, rune(octet&0x1F)
+		case octet&0xF0 == 0xE0:
+			w, value = 3, rune(octet&0x0F)
+		case octet&0xF8 == 0xF0:
+			w, value = 4, rune(octet&0x07)
+		}
+		for k := 1; k < w; k++ {
+			octet = emitter.buffer[pos+k]
+			value = (value << 6) + (rune(octet) & 0x3F)
+		}
+		pos += w
+
+		// Write the character.
+		if value < 0x10000 {
+			var b [2]byte
+			b[high] = byte(value >> 8)
+			b[low] = byte(value & 0xFF)
+			emitter.raw_buffer = append(emitter.raw_buffer, b[0], b[1])
+		} else {
+			// Write the character using a surrogate pair (check "readerc.go").
+			var b [4]byte
+			value -= 0x10000
+			b[high] = byte(0xD8 + (value >> 18))
+			b[low] = byte((value >> 10) & 0xFF)
+			b[high+2] = byte(0xDC + ((value >> 8) & 0x03))
+			b[low+2] = byte(value & 0xFF)
+			emitter.raw_buffer = append(emitter.raw_buffer, b[0], b[1], b[2], b[3])
+		}
+	}
+
+	// Write the raw buffer.
+	if err := emitter.write_handler(emitter, emitter.raw_buffer); err != nil {
+		return ini_emitter_set_writer_error(emitter, "write error: "+err.Error())
+	}
+	emitter.buffer_pos = 0
+	emitter.raw_buffer = emitter.raw_buffer[:0]
+	return true
+}
+
+// Flush the output buffer in the ISO-8859-1 encoding.
+func ini_emitter_flush_latin1(emitter *ini_emitter_t) bool {
+	for pos := 0; pos < emitter.buffer_pos; {
+		value, w := utf8.DecodeRune(emitter.buffer[pos:emitter.buffer_pos])
+		if value > 0xFF {
+			return ini_emitter_set_writer_error(emitter, "cannot write the character "+strconv.QuoteRune(value)+" in ISO-8859-1")
+		}
+		emitter.raw_buffer = append(emitter.raw_buffer, byte(value))
+		pos += w
+	}
+	if err := emitter.write_handler(emitter, emitter.raw_buffer); err != nil {
 		return ini_emitter_set_writer_error(emitter, "write error: "+err.Error())
 	}
 	emitter.buffer_pos = 0
+	emitter.raw_buffer = emitter.raw_buffer[:0]
 	return true
 }
@@ -0,0 +1,807 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToYAML converts an INI document into a YAML document.  Sections become
+// maps, dotted keys nested maps, the keys of the default section the keys
+// of the outer map, and the values of repeated keys lists.  Values keep the
+// type they decode into with Unmarshal, and keys their order.  Comments are
+// kept.
+func ToYAML(in []byte) ([]byte, error) {
+	return ToYAMLWithOptions(in, ConvertOptions{})
+}
+
+// ToYAMLWithOptions is like ToYAML, but the document is read and written
+// as described by opts.
+func ToYAMLWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	doc, err := ini_document(in, opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	w := yaml_writer{doc: doc}
+	if opts.Anchors {
+		w.anchors = make(map[string]string)
+		used := make(map[string]bool)
+		for _, e := range doc.entries {
+			if _, ok := doc.own(e); ok && w.anchors[e.base] == "" {
+				w.anchors[e.base] = yaml_anchor(e.base, used)
+			}
+		}
+	}
+	w.entries(doc.entries, "", true)
+	if len(doc.comments) > 0 && w.buf.Len() > 0 {
+		w.buf.WriteByte('\n')
+	}
+	w.comments(doc.comments, "")
+	return w.buf.Bytes(), nil
+}
+
+// FromYAML converts a YAML document into an INI document.  It is the
+// reverse of ToYAML: the maps of the outer map become sections, maps nested
+// in them dotted keys, and the other keys of the outer map the keys of the
+// default section.  A section that merges an anchored section with "<<"
+// inherits it in the default dialect, and holds a copy of its keys in the
+// others.  Comments are kept.
+//
+// FromYAML reads block and flow maps and lists, plain, quoted and block
+// scalars, anchors, aliases and merge keys.  Lists of lists or maps, lists
+// anywhere but in git and systemd files, keys holding a '.' in the default
+// dialect, and nested maps in the dialects that read dotted keys as plain
+// keys, cannot be expressed in INI and are reported in a *ConvertError.
+func FromYAML(in []byte) ([]byte, error) {
+	return FromYAMLWithOptions(in, ConvertOptions{})
+}
+
+// FromYAMLWithOptions is like FromYAML, but the document is written as
+// described by opts.
+func FromYAMLWithOptions(in []byte, opts ConvertOptions) ([]byte, error) {
+	r := yaml_reader{anchors: make(map[string]interface{}), sections: make(map[string]string)}
+	doc := r.document(in)
+	return ini_write(doc, opts.Dump, r.errors)
+}
+
+// ----------------------------------------------------------------------------
+// YAML writer
+
+type yaml_writer struct {
+	buf     bytes.Buffer
+	doc     *document
+	anchors map[string]string // The anchors of the inherited sections.
+}
+
+// entries writes the keys of a map.
+func (w *yaml_writer) entries(entries []*entry, indent string, top bool) {
+	for _, e := range entries {
+		if _, ok := e.value.([]*entry); ok && top && w.buf.Len() > 0 {
+			w.buf.WriteByte('\n')
+		}
+		w.comments(e.comments, indent)
+		w.buf.WriteString(indent + yaml_string(e.key) + ":")
+		switch value := e.value.(type) {
+		case []*entry:
+			var base string
+			if top {
+				if anchor := w.anchors[e.key]; anchor != "" {
+					w.buf.WriteString(" &" + anchor)
+				}
+				if own, ok := w.doc.own(e); ok && w.anchors[e.base] != "" {
+					value, base = own, w.anchors[e.base]
+				}
+			}
+			if len(value) == 0 && base == "" {
+				w.buf.WriteString(" {}")
+			}
+			w.comment(e.comment)
+			if base != "" {
+				w.buf.WriteString(indent + "  <<: *" + base + "\n")
+			}
+			w.entries(value, indent+"  ", false)
+		case []interface{}:
+			if len(value) == 0 {
+				w.buf.WriteString(" []")
+			}
+			w.comment(e.comment)
+			for _, v := range value {
+				w.buf.WriteString(indent + "  - " + yaml_scalar(v) + "\n")
+			}
+		default:
+			w.buf.WriteString(" " + yaml_scalar(value))
+			w.comment(e.comment)
+		}
+	}
+}
+
+// comment ends a line, with a comment if there is one.
+func (w *yaml_writer) comment(comment string) {
+	if comment != "" {
+		w.buf.WriteString(" # " + comment)
+	}
+	w.buf.WriteByte('\n')
+}
+
+// comments writes comment lines.
+func (w *yaml_writer) comments(comments []string, indent string) {
+	for _, comment := range comments {
+		if comment == "" {
+			w.buf.WriteString(indent + "#\n")
+		} else {
+			w.buf.WriteString(indent + "# " + comment + "\n")
+		}
+	}
+}
+
+// yaml_anchor returns an anchor for a section name that no other section
+// uses.
+func yaml_anchor(name string, used map[string]bool) string {
+	anchor := strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (is_alpha([]byte{byte(r)}, 0) || r == '.') {
+			return r
+		}
+		return '_'
+	}, name)
+	if anchor == "" {
+		anchor = "section"
+	}
+	for i := 2; used[anchor]; i++ {
+		anchor = strings.TrimRight(anchor, "0123456789") + strconv.Itoa(i)
+	}
+	used[anchor] = true
+	return anchor
+}
+
+// yaml_scalar returns a scalar in YAML.
+func yaml_scalar(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		switch {
+		case math.IsInf(value, 1):
+			return ".inf"
+		case math.IsInf(value, -1):
+			return "-.inf"
+		case math.IsNaN(value):
+			return ".nan"
+		}
+		s := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case string:
+		return yaml_string(value)
+	case []byte:
+		return "!!binary " + base64.StdEncoding.EncodeToString(value)
+	case int, int64, uint64:
+		return fmt.Sprint(value)
+	}
+	return yaml_string(fmt.Sprint(value))
+}
+
+// yaml_string returns a string in YAML: plain when it reads back as the
+// same string, and double-quoted otherwise.
+func yaml_string(s string) string {
+	plain := s != "" && s[len(s)-1] != ' '
+	for i, r := range s {
+		if !plain {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '/':
+		case r >= '0' && r <= '9', r == '.', r == '-', r == '+', r == '@', r == ' ':
+			plain = i > 0
+		default:
+			plain = r >= utf8.RuneSelf && unicode.IsPrint(r) && r != '\ufeff'
+		}
+	}
+	if plain {
+		if tag, _ := resolve("", s); tag == ini_STR_TAG {
+			return s
+		}
+	}
+	return strconv.Quote(s)
+}
+
+// ----------------------------------------------------------------------------
+// YAML reader
+
+// A yaml_reader reads the block and flow maps and lists, the scalars, the
+// anchors and the comments of a YAML document, line by line.
+type yaml_reader struct {
+	lines    []string
+	pos      int                    // The current line.
+	anchors  map[string]interface{} // The values of the anchors.
+	sections map[string]string      // The sections by their anchors.
+	comments []string               // The comment lines above the next key.
+	errors   []string
+}
+
+// fail records a problem of a line.
+func (r *yaml_reader) fail(line int, format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf("line %d: %s", line+1, fmt.Sprintf(format, args...)))
+}
+
+// document reads a document.  Its outer value must be a map.
+func (r *yaml_reader) document(in []byte) *document {
+	text := strings.TrimPrefix(string(in), "\ufeff")
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	r.lines = strings.Split(text, "\n")
+	doc := &document{}
+	// Directives and the document start.
+	for ; r.pos < len(r.lines); r.pos++ {
+		text, comment := yaml_split_comment(r.lines[r.pos])
+		if text == "" {
+			if comment != nil {
+				r.comments = append(r.comments, *comment)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "%") {
+			continue
+		}
+		if text == "---" {
+			r.pos++
+		} else if strings.HasPrefix(text, "--- ") {
+			r.fail(r.pos, "a document that is not a map cannot be expressed in INI")
+			return doc
+		}
+		break
+	}
+	if r.next() {
+		indent := yaml_indent(r.lines[r.pos])
+		text, _ := yaml_split_comment(r.lines[r.pos])
+		if _, _, ok := yaml_key(text); !ok {
+			r.fail(r.pos, "a document that is not a map cannot be expressed in INI")
+			return doc
+		}
+		doc.entries, _ = r.mapping(indent, 0)
+		if r.next() {
+			r.fail(r.pos, "did not find expected key")
+		}
+	}
+	doc.comments = r.comments
+	return doc
+}
+
+// next skips to the next line with content, and collects the comments on
+// the way.  It reports false at the end of the document.
+func (r *yaml_reader) next() bool {
+	for ; r.pos < len(r.lines); r.pos++ {
+		text, comment := yaml_split_comment(r.lines[r.pos])
+		switch {
+		case text == "..." || text == "---" || strings.HasPrefix(text, "--- "):
+			if text == "..." {
+				r.end()
+			} else {
+				r.fail(r.pos, "several documents cannot be expressed in INI")
+			}
+			r.pos = len(r.lines)
+			return false
+		case text != "":
+			return true
+		case comment != nil:
+			r.comments = append(r.comments, *comment)
+		}
+	}
+	return false
+}
+
+// end checks that only comments and document end markers follow the end
+// of the document.
+func (r *yaml_reader) end() {
+	for pos := r.pos + 1; pos < len(r.lines); pos++ {
+		if text, _ := yaml_split_comment(r.lines[pos]); text != "" && text != "..." {
+			r.fail(pos, "several documents cannot be expressed in INI")
+			return
+		}
+	}
+}
+
+// take returns the comments collected since the last key.
+func (r *yaml_reader) take() []string {
+	comments := r.comments
+	r.comments = nil
+	return comments
+}
+
+// skip skips the lines indented deeper than indent.
+func (r *yaml_reader) skip(indent int) {
+	for r.pos++; r.pos < len(r.lines); r.pos++ {
+		if text, _ := yaml_split_comment(r.lines[r.pos]); text != "" && yaml_indent(r.lines[r.pos]) <= indent {
+			return
+		}
+	}
+}
+
+// mapping reads the keys of a block map indented by indent, at a depth
+// of nesting in the document.  It returns the section inherited by a
+// section that merges an anchored section.
+func (r *yaml_reader) mapping(indent, depth int) (entries []*entry, base string) {
+	type merge struct {
+		at      int
+		entries []*entry
+	}
+	var merges []merge
+	for r.next() {
+		line := r.lines[r.pos]
+		if yaml_indent(line) < indent {
+			break
+		}
+		if strings.ContainsRune(line[:yaml_indent(line)+1], '\t') {
+			r.fail(r.pos, "found a tab that indents a key")
+			r.skip(yaml_indent(line))
+			continue
+		}
+		if yaml_indent(line) > indent {
+			r.fail(r.pos, "found a key indented deeper than the keys before it")
+			r.skip(yaml_indent(line))
+			continue
+		}
+		text, comment := yaml_split_comment(line)
+		key, rest, ok := yaml_key(text[indent:])
+		if !ok {
+			r.fail(r.pos, "did not find expected key")
+			r.skip(indent)
+			continue
+		}
+		e := &entry{key: key, line: r.pos + 1, comments: r.take()}
+		if comment != nil {
+			e.comment = *comment
+		}
+		if key == "<<" {
+			r.pos++
+			var names []string
+			if strings.HasPrefix(rest, "[") && strings.HasSuffix(rest, "]") {
+				names = strings.Split(rest[1:len(rest)-1], ",")
+			} else {
+				names = []string{rest}
+			}
+			for _, name := range names {
+				name = strings.TrimSpace(name)
+				value, ok := r.anchors[strings.TrimPrefix(name, "*")]
+				merged, isMap := value.([]*entry)
+				if !strings.HasPrefix(name, "*") || !ok || !isMap {
+					r.fail(e.line-1, "merge key holds %s, which is not an alias of a map", name)
+					continue
+				}
+				if section := r.sections[name[1:]]; depth == 1 && base == "" && section != "" {
+					base = section
+				}
+				merges = append(merges, merge{len(entries), entry_copy(merged).([]*entry)})
+			}
+			continue
+		}
+		if entry_find(entries, key) != nil {
+			r.fail(r.pos, "found the key '%s' twice", key)
+		}
+		e.value, e.base = r.value(rest, indent, depth, e)
+		entries = entry_set(entries, e)
+	}
+	// The keys of a map win over the keys that it merges, wherever they
+	// are, and the first merged map wins over the next ones.
+	for i := len(merges) - 1; i >= 0; i-- {
+		var added []*entry
+		for _, e := range merges[i].entries {
+			if entry_find(entries, e.key) == nil && entry_find(added, e.key) == nil {
+				added = append(added, e)
+			}
+		}
+		entries = append(entries[:merges[i].at], append(added, entries[merges[i].at:]...)...)
+		for j := 0; j < i; j++ {
+			if merges[j].at >= merges[i].at {
+				merges[j].at += len(added)
+			}
+		}
+	}
+	return entries, base
+}
+
+// value reads the value of the key of e, whose text after the ':' on its
+// line is rest.  It returns the section that a section inherits.
+func (r *yaml_reader) value(rest string, indent, depth int, e *entry) (value interface{}, base string) {
+	line := r.pos
+	r.pos++
+	var anchor, tag string
+	for {
+		if strings.HasPrefix(rest, "&") && anchor == "" {
+			anchor, rest = yaml_token(rest[1:])
+		} else if strings.HasPrefix(rest, "!") && tag == "" {
+			tag, rest = yaml_token(rest)
+		} else {
+			break
+		}
+	}
+	switch {
+	case rest == "":
+		// A nested block, or null.
+		if !r.next() {
+			break
+		}
+		next := yaml_indent(r.lines[r.pos])
+		text, _ := yaml_split_comment(r.lines[r.pos])
+		isList := text[next:] == "-" || strings.HasPrefix(text[next:], "- ")
+		if next > indent && !isList {
+			value, base = r.mapping(next, depth+1)
+		} else if next > indent || next == indent && isList {
+			value = r.sequence(next)
+		}
+	case rest[0] == '*':
+		name, more := yaml_token(rest[1:])
+		var ok bool
+		if value, ok = r.anchors[name]; !ok {
+			r.fail(line, "found an unknown anchor '%s'", name)
+		} else if more != "" {
+			r.fail(line, "found unexpected text after the alias '%s'", name)
+		}
+		value = entry_copy(value)
+	case rest[0] == '|' || rest[0] == '>':
+		value = r.block_scalar(rest, indent, line)
+	case rest[0] == '[' || rest[0] == '{':
+		var more string
+		var problem string
+		value, more, problem = yaml_flow(rest)
+		if problem == "" && strings.TrimSpace(more) != "" {
+			problem = "found unexpected text after a flow collection"
+		}
+		if problem != "" {
+			r.fail(line, "%s", problem)
+		}
+	default:
+		value = r.scalar(rest, tag, line)
+	}
+	if anchor != "" {
+		r.anchors[anchor] = value
+		if _, ok := value.([]*entry); ok && depth == 0 {
+			r.sections[anchor] = e.key
+		}
+	}
+	return value, base
+}
+
+// sequence reads the values of a block list indented by indent.
+func (r *yaml_reader) sequence(indent int) []interface{} {
+	values := []interface{}{}
+	for r.next() {
+		line := r.lines[r.pos]
+		text, _ := yaml_split_comment(line)
+		if yaml_indent(line) != indent || text[indent:] != "-" && !strings.HasPrefix(text[indent:], "- ") {
+			break
+		}
+		rest := strings.TrimSpace(text[indent+1:])
+		if _, _, ok := yaml_key(rest); ok || rest == "" || rest[0] == '|' || rest[0] == '>' {
+			r.fail(r.pos, "a list holds a map or a block, which INI cannot express")
+			r.skip(indent)
+			continue
+		}
+		value, _ := r.value(rest, indent, -1, &entry{})
+		values = append(values, value)
+	}
+	return values
+}
+
+// scalar reads a plain or quoted scalar, with its tag.
+func (r *yaml_reader) scalar(text, tag string, line int) interface{} {
+	var value string
+	quoted := text[0] == '"' || text[0] == '\''
+	if quoted {
+		var more string
+		var ok bool
+		value, more, ok = yaml_quoted(text)
+		if !ok {
+			r.fail(line, "found a quoted scalar that does not end on its line")
+			return nil
+		}
+		if strings.TrimSpace(more) != "" {
+			r.fail(line, "found unexpected text after a quoted scalar")
+		}
+	} else {
+		value = text
+		switch {
+		case strings.Contains(value, ": ") || strings.HasSuffix(value, ":"):
+			r.fail(line, "mapping values are not allowed in this context")
+			return nil
+		case strings.ContainsAny(value[:1], "@`"):
+			r.fail(line, "found character that cannot start any token")
+			return nil
+		}
+		if r.next() && yaml_indent(r.lines[r.pos]) > yaml_indent(r.lines[line]) {
+			r.fail(r.pos, "a plain scalar continues on this line, which is not supported")
+			r.skip(yaml_indent(r.lines[line]))
+		}
+	}
+	switch tag {
+	case "":
+		if quoted {
+			return value
+		}
+	case "!!str", "!!binary":
+		return value
+	case "!!int", "!!float", "!!bool", "!!null":
+	default:
+		r.fail(line, "the tag %s cannot be expressed in INI", tag)
+		return value
+	}
+	_, resolved := resolve("", value)
+	return resolved
+}
+
+// block_scalar reads a literal or folded block scalar, whose header is
+// text, below a key indented by indent.
+func (r *yaml_reader) block_scalar(text string, indent, line int) interface{} {
+	folded := text[0] == '>'
+	chomp := byte(0)
+	content := -1
+	for _, c := range []byte(text[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		case c >= '1' && c <= '9':
+			content = indent + int(c-'0')
+		default:
+			r.fail(line, "found an unexpected character in a block scalar header")
+			return nil
+		}
+	}
+	var lines []string
+	for ; r.pos < len(r.lines); r.pos++ {
+		s := r.lines[r.pos]
+		if strings.TrimSpace(s) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if content < 0 {
+			content = yaml_indent(s)
+		}
+		if content <= indent || yaml_indent(s) < content {
+			break
+		}
+		lines = append(lines, s[content:])
+	}
+	// The lines of a trailing break or more, for chomping.
+	trailing := 0
+	for trailing < len(lines) && lines[len(lines)-1-trailing] == "" {
+		trailing++
+	}
+	lines = lines[:len(lines)-trailing]
+	var value string
+	for i, s := range lines {
+		switch {
+		case i == 0:
+		case folded && s != "" && lines[i-1] != "" && s[0] != ' ' && lines[i-1][0] != ' ':
+			value += " "
+		case folded && s != "" && lines[i-1] == "":
+		default:
+			value += "\n"
+		}
+		value += s
+	}
+	if len(lines) > 0 {
+		switch chomp {
+		case 0:
+			value += "\n"
+		case '+':
+			value += strings.Repeat("\n", trailing+1)
+		}
+	}
+	return value
+}
+
+// yaml_indent returns the number of spaces that indent a line.
+func yaml_indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// yaml_split_comment splits a line into its text, without trailing blanks,
+// and its comment, if it has one.
+func yaml_split_comment(line string) (text string, comment *string) {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" \t:[{,-", line[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			s := strings.TrimSpace(line[i+1:])
+			return strings.TrimRight(line[:i], " \t"), &s
+		}
+	}
+	return strings.TrimRight(line, " \t"), nil
+}
+
+// yaml_key splits the text of a line of a block map into its key and the
+// text after the ':'.
+func yaml_key(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '-' && (len(text) == 1 || text[1] == ' ') || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		key, rest, ok = yaml_quoted(text)
+		if !ok || !(rest == ":" || strings.HasPrefix(rest, ": ")) {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimRight(text[:i], " "), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// yaml_token splits text at its first blank.
+func yaml_token(text string) (token, rest string) {
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+// yaml_quoted reads the single-quoted or double-quoted scalar at the start
+// of text, and returns the text after it.
+func yaml_quoted(text string) (value, rest string, ok bool) {
+	quote := text[0]
+	var buf []byte
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'' && c == '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				buf = append(buf, '\'')
+				i++
+				continue
+			}
+			return string(buf), text[i+1:], true
+		case quote == '"' && c == '"':
+			return string(buf), text[i+1:], true
+		case quote == '"' && c == '\\' && i+1 < len(text):
+			i++
+			switch text[i] {
+			case '0':
+				buf = append(buf, 0)
+			case 'a':
+				buf = append(buf, '\a')
+			case 'b':
+				buf = append(buf, '\b')
+			case 't', '\t':
+				buf = append(buf, '\t')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'v':
+				buf = append(buf, '\v')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'r':
+				buf = append(buf, '\r')
+			case 'e':
+				buf = append(buf, 0x1b)
+			case 'N':
+				buf = append(buf, "\u0085"...)
+			case '_':
+				buf = append(buf, "\u00a0"...)
+			case 'L':
+				buf = append(buf, "\u2028"...)
+			case 'P':
+				buf = append(buf, "\u2029"...)
+			case 'x', 'u', 'U':
+				width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[i]]
+				if i+width >= len(text) {
+					return "", "", false
+				}
+				code, err := strconv.ParseUint(text[i+1:i+1+width], 16, 32)
+				if err != nil {
+					return "", "", false
+				}
+				buf = append(buf, string(rune(code))...)
+				i += width
+			default:
+				// ' ', '"', '/' and '\\' stand for themselves.
+				buf = append(buf, text[i])
+			}
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return "", "", false
+}
+
+// yaml_flow reads the flow list or map at the start of text, and returns
+// the text after it, or a problem.
+func yaml_flow(text string) (value interface{}, rest string, problem string) {
+	end := byte(']')
+	if text[0] == '{' {
+		end = '}'
+	}
+	var values []interface{}
+	var entries []*entry
+	rest = strings.TrimSpace(text[1:])
+	for {
+		if rest == "" {
+			return nil, "", "found a flow collection that does not end on its line"
+		}
+		if rest[0] == end {
+			break
+		}
+		var key string
+		if end == '}' {
+			if rest[0] == '"' || rest[0] == '\'' {
+				var ok bool
+				if key, rest, ok = yaml_quoted(rest); !ok {
+					return nil, "", "found a quoted scalar that does not end on its line"
+				}
+			} else {
+				i := strings.IndexAny(rest, ":,}")
+				if i < 0 {
+					i = len(rest)
+				}
+				key, rest = strings.TrimSpace(rest[:i]), rest[i:]
+			}
+			rest = strings.TrimSpace(rest)
+			if !strings.HasPrefix(rest, ":") {
+				return nil, "", "did not find expected ':' in a flow map"
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+		var item interface{}
+		switch {
+		case rest == "":
+			return nil, "", "found a flow collection that does not end on its line"
+		case rest[0] == '[' || rest[0] == '{':
+			if item, rest, problem = yaml_flow(rest); problem != "" {
+				return nil, "", problem
+			}
+		case rest[0] == '"' || rest[0] == '\'':
+			var ok bool
+			if item, rest, ok = yaml_quoted(rest); !ok {
+				return nil, "", "found a quoted scalar that does not end on its line"
+			}
+		default:
+			i := strings.IndexAny(rest, ",]}")
+			if i < 0 {
+				i = len(rest)
+			}
+			_, item = resolve("", strings.TrimSpace(rest[:i]))
+			rest = rest[i:]
+		}
+		if end == '}' {
+			entries = append(entries, &entry{key: key, value: item})
+		} else {
+			values = append(values, item)
+		}
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if rest == "" || rest[0] != end {
+			return nil, "", "did not find expected ',' or '" + string(end) + "'"
+		}
+	}
+	if end == '}' {
+		if entries == nil {
+			entries = []*entry{}
+		}
+		return entries, rest[1:], ""
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+	return values, rest[1:], ""
+}
@@ -0,0 +1,217 @@
+package ini_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"go-ini"
+)
+
+var toYAMLTests = []struct {
+	options ini.ConvertOptions
+	data    string
+	yaml    string
+}{
+	{
+		ini.ConvertOptions{},
+		"",
+		"",
+	}, {
+		ini.ConvertOptions{},
+		"# service\n[server]\nhost = localhost ; the host\nport = 8080\nratio = 0.5\ndebug = off\nempty =\nname = \"yes\"\ndb.user = root\n# end\n",
+		`# service
+server:
+  host: localhost # the host
+  port: 8080
+  ratio: 0.5
+  debug: false
+  empty: null
+  name: true
+  db:
+    user: root
+
+# end
+`,
+	}, {
+		ini.ConvertOptions{},
+		"[base]\nhost = localhost\nport = 8080\n[prod : base]\nhost = example.com\n",
+		`base:
+  host: localhost
+  port: 8080
+
+prod:
+  host: example.com
+  port: 8080
+`,
+	}, {
+		ini.ConvertOptions{Anchors: true},
+		"[base]\nhost = localhost\nport = 8080\n[prod : base]\n# prod host\nhost = example.com\n",
+		`base: &base
+  host: localhost
+  port: 8080
+
+prod:
+  <<: *base
+  # prod host
+  host: example.com
+`,
+	}, {
+		ini.ConvertOptions{Load: ini.LoadOptions{Dialect: ini.DialectGit}},
+		"[remote \"origin\"]\n\turl = git@host:a b\n\tfetch = a\n\tfetch = b\n",
+		`remote:
+  origin:
+    url: "git@host:a b"
+    fetch:
+      - a
+      - b
+`,
+	},
+}
+
+func (s *S) TestToYAML(c *C) {
+	for _, item := range toYAMLTests {
+		out, err := ini.ToYAMLWithOptions([]byte(item.data), item.options)
+		c.Assert(err, IsNil, Commentf("data: %q", item.data))
+		c.Assert(string(out), Equals, item.yaml, Commentf("data: %q", item.data))
+	}
+}
+
+var fromYAMLTests = []struct {
+	options ini.ConvertOptions
+	yaml    string
+	data    string
+}{
+	{
+		ini.ConvertOptions{},
+		"",
+		"",
+	}, {
+		ini.ConvertOptions{},
+		`%YAML 1.2
+---
+# service
+name: "my app"   # the name
+version: 1.2.0
+server:
+  host: localhost
+  tls: {enabled: yes, cert: /etc/cert.pem}
+  motd: >-
+    hello
+    world
+  'quoted key': 'it''s'
+...
+`,
+		`# service
+# the name
+name = my app
+version = 1.2.0
+
+[server]
+host = localhost
+tls.enabled = true
+tls.cert = /etc/cert.pem
+motd = hello world
+quoted key = it's
+`,
+	}, {
+		ini.ConvertOptions{},
+		`base: &base
+  host: localhost
+  port: 8080
+prod:
+  host: example.com
+  <<: *base
+`,
+		"[base]\nhost = localhost\nport = 8080\n\n[prod:base]\nhost = example.com\n",
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectGit}},
+		`defaults: &defaults
+  retries: 3
+core:
+  <<: *defaults
+  editor: vim
+remote:
+  origin:
+    fetch: [a, b]
+`,
+		"[defaults]\n\tretries = 3\n\n[core]\n\tretries = 3\n\teditor = vim\n\n[remote \"origin\"]\n\tfetch = a\n\tfetch = b\n",
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectPHP}},
+		"base: &base\n  host: localhost\nprod:\n  <<: *base\n  port: 80\n",
+		"[base]\nhost = localhost\n\n[prod]\nhost = localhost\nport = 80\n",
+	},
+}
+
+func (s *S) TestFromYAML(c *C) {
+	for _, item := range fromYAMLTests {
+		out, err := ini.FromYAMLWithOptions([]byte(item.yaml), item.options)
+		c.Assert(err, IsNil, Commentf("yaml: %q", item.yaml))
+		c.Assert(string(out), Equals, item.data, Commentf("yaml: %q", item.yaml))
+	}
+}
+
+var fromYAMLErrorTests = []struct {
+	options ini.ConvertOptions
+	yaml    string
+	error   string
+}{
+	{
+		ini.ConvertOptions{},
+		"- a\n- b\n",
+		"ini: cannot convert:\n  line 1: a document that is not a map cannot be expressed in INI",
+	}, {
+		ini.ConvertOptions{},
+		"a:\n  b: [1, 2]\n  c: !env HOME\n  d: |\n    one\n    two\n",
+		"ini: cannot convert:\n" +
+			"  line 3: the tag !env cannot be expressed in INI\n" +
+			"  line 2: key 'b' holds a list, which the keys of this dialect cannot hold\n" +
+			"  line 4: key 'd' holds a line break or both kinds of quotes, which the values of this dialect cannot hold",
+	}, {
+		ini.ConvertOptions{},
+		"a:\n  - x: 1\n  - [1, 2]\nb: *c\nc: 1\nc: 2\n---\nd: 1\n",
+		"ini: cannot convert:\n" +
+			"  line 2: a list holds a map or a block, which INI cannot express\n" +
+			"  line 4: found an unknown anchor 'c'\n" +
+			"  line 6: found the key 'c' twice\n" +
+			"  line 7: several documents cannot be expressed in INI\n" +
+			"  line 1: key 'a' holds a list, which the keys of this dialect cannot hold",
+	}, {
+		ini.ConvertOptions{},
+		"a: 1\n...\n---\nb: 2\n",
+		"ini: cannot convert:\n  line 3: several documents cannot be expressed in INI",
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectSystemd}},
+		"a: 1\nUnit:\n  After: [a, [b]]\n  Env:\n    A: 1\n",
+		"ini: cannot convert:\n" +
+			"  line 1: key 'a' is not in a section\n" +
+			"  line 3: key 'After' holds a list of lists or maps\n" +
+			"  line 4: key 'Env' holds a map, which the keys of this dialect cannot hold",
+	}, {
+		ini.ConvertOptions{},
+		"s:\n  k: a: b\n  j: @x\n  \"a.b\": 1\n",
+		"ini: cannot convert:\n" +
+			"  line 2: mapping values are not allowed in this context\n" +
+			"  line 3: found character that cannot start any token\n" +
+			"  line 4: key 'a.b' holds a '.', which this dialect reads as nested keys",
+	}, {
+		ini.ConvertOptions{Dump: ini.DumpOptions{Dialect: ini.DialectMySQL}},
+		"mysqld:\n  db:\n    user: root\n",
+		"ini: cannot convert:\n  line 2: key 'db' holds a map, which this dialect reads back as flat dotted keys",
+	},
+}
+
+func (s *S) TestFromYAMLErrors(c *C) {
+	for _, item := range fromYAMLErrorTests {
+		_, err := ini.FromYAMLWithOptions([]byte(item.yaml), item.options)
+		c.Assert(err, NotNil, Commentf("yaml: %q", item.yaml))
+		c.Assert(err.Error(), Equals, item.error, Commentf("yaml: %q", item.yaml))
+	}
+}
+
+func (s *S) TestYAMLRoundTrip(c *C) {
+	data := "# top\nname = app\n\n[base]\nhost = localhost\nport = 8080\n\n[prod:base]\n# prod host\nhost = example.com\n"
+	out, err := ini.ToYAMLWithOptions([]byte(data), ini.ConvertOptions{Anchors: true})
+	c.Assert(err, IsNil)
+	back, err := ini.FromYAML(out)
+	c.Assert(err, IsNil)
+	c.Assert(string(back), Equals, "# top\nname = app\n\n[base]\nhost = localhost\nport = 8080\nname = app\n\n[prod:base]\n# prod host\nhost = example.com\n")
+}